@@ -0,0 +1,29 @@
+package main
+
+import (
+	"15-puzzle/internal/repo"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+func main() {
+	dataFlag := flag.String("data", os.Getenv("DATA_FILE"), "data file, defaults to the DATA_FILE variable")
+	dryRunFlag := flag.Bool("dry-run", false, "report the changes without writing the files")
+	flag.Parse()
+
+	if *dataFlag == "" {
+		exitWithError("data file not set")
+	}
+	report, err := repo.Migrate(*dataFlag, *dryRunFlag)
+	if err != nil {
+		exitWithError("migrate %s: %s", *dataFlag, err)
+	}
+	fmt.Println(report)
+}
+
+func exitWithError(format string, a ...any) {
+	slog.Error(fmt.Sprintf(format, a...))
+	os.Exit(1)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/solver/pdb"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	sizeFlag := flag.String("size", board.Classic.String(), "board size, WxH")
+	partitionFlag := flag.String("partition", "", `tiles groups: sizes of consecutive tiles groups like "6-6-3" or explicit tiles like "1,2,3/4,5,6,7/..."; a well-known default is used when empty`)
+	outFlag := flag.String("out", "", "output file, defaults to pdb-<size>.bin")
+	flag.Parse()
+
+	size, err := board.ParseSize(*sizeFlag)
+	if err != nil {
+		exitWithError("%s", err)
+	}
+	partition := pdb.DefaultPartition(size)
+	if *partitionFlag != "" {
+		if partition, err = pdb.ParsePartition(size, *partitionFlag); err != nil {
+			exitWithError("%s", err)
+		}
+	}
+	out := *outFlag
+	if out == "" {
+		out = "pdb-" + size.String() + ".bin"
+	}
+
+	slog.Info(fmt.Sprintf("building pattern database for %s with partition %v", size, partition))
+	start := time.Now()
+	db, err := pdb.Build(ctx, size, partition)
+	if err != nil {
+		exitWithError("build: %s", err)
+	}
+	if err := db.Save(out); err != nil {
+		exitWithError("save: %s", err)
+	}
+	slog.Info(fmt.Sprintf("pattern database saved to %s in %s", out, time.Since(start).Round(time.Second)))
+}
+
+func exitWithError(format string, a ...any) {
+	slog.Error(fmt.Sprintf(format, a...))
+	os.Exit(1)
+}
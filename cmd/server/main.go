@@ -2,14 +2,18 @@ package main
 
 import (
 	"15-puzzle/internal/repo"
+	"15-puzzle/internal/solver"
+	"15-puzzle/internal/solver/pdb"
 	"15-puzzle/internal/tgbot"
 	"15-puzzle/internal/web-service/handler"
+	"15-puzzle/internal/web-service/plausibility"
 	"15-puzzle/internal/web-service/server"
 	"context"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"time"
 )
 
 func main() {
@@ -24,13 +28,63 @@ func main() {
 	}
 	bot.Start()
 
-	r, err := repo.NewFileRepo(ctx, requireEnv("DATA_FILE"))
+	rankings, err := repo.ParseRankings(envOrDefault("RANKINGS", repo.DefaultRankings))
+	if err != nil {
+		exitWithError("rankings: %s", err)
+	}
+
+	loc, err := time.LoadLocation(envOrDefault("TIME_ZONE", "UTC"))
+	if err != nil {
+		exitWithError("time zone: %s", err)
+	}
+
+	policy, err := repo.ParseSync(envOrDefault("JOURNAL_SYNC", repo.SyncAlways.String()))
+	if err != nil {
+		exitWithError("journal sync: %s", err)
+	}
+
+	var opts []solver.Option
+	if files, ok := os.LookupEnv("PDB_FILE"); ok {
+		h, closeDBs, err := pdb.LoadHeuristic(files)
+		if err != nil {
+			exitWithError("pattern database: %s", err)
+		}
+		defer func() {
+			if err := closeDBs(); err != nil {
+				slog.Error(fmt.Sprintf("pattern database close: %s", err))
+			}
+		}()
+		opts = append(opts, solver.WithHeuristic(h))
+	}
+
+	r, err := openRepo(ctx, envOrDefault("STORE", "journal"), requireEnv("DATA_FILE"), policy, rankings)
 	if err != nil {
 		exitWithError("repo init: %s", err)
 	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			slog.Error(fmt.Sprintf("repo close: %s", err))
+		}
+	}()
 
 	server.StartServer(ctx,
-		handler.NewHandler(r, token, requireEnv("ACCESS_CODE"), os.Getenv("CONTEXT_ROOT"), os.Getenv("STATIC_DIR"), requireEnv("PROJECT_LINK")))
+		handler.NewHandler(r, token, requireEnv("ACCESS_CODE"), os.Getenv("CONTEXT_ROOT"), os.Getenv("STATIC_DIR"), requireEnv("PROJECT_LINK"), loc, plausibility.DefaultLimits(), opts...))
+}
+
+// repository is the storage backend the server closes on exit.
+type repository interface {
+	handler.Repository
+	Close() error
+}
+
+func openRepo(ctx context.Context, store, file string, policy repo.Sync, rankings []repo.Ranking) (repository, error) {
+	switch store {
+	case "journal":
+		return repo.NewFileRepo(ctx, file, policy, rankings...)
+	case "btree":
+		return repo.NewKVRepo(ctx, file, policy, rankings...)
+	}
+	return nil, fmt.Errorf("unknown store %q", store)
 }
 
 func requireEnv(env string) string {
@@ -41,6 +95,13 @@ func requireEnv(env string) string {
 	return v
 }
 
+func envOrDefault(env, value string) string {
+	if v, ok := os.LookupEnv(env); ok {
+		return v
+	}
+	return value
+}
+
 func exitWithError(format string, a ...any) {
 	slog.Error(fmt.Sprintf(format, a...))
 	os.Exit(1)
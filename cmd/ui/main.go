@@ -1,13 +1,64 @@
 package main
 
 import (
+	"15-puzzle/internal/board"
 	"15-puzzle/internal/puzzle"
+	"15-puzzle/internal/solver"
+	"15-puzzle/internal/solver/pdb"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 )
 
 func main() {
-	if err := puzzle.Init(func(p *puzzle.Controller) { p.SetActive(true) }); err != nil {
+	replayFlag := flag.String("replay", "", "file with a game recording to play back")
+	gamepadFlag := flag.Bool("gamepad", false, "play with a gamepad along with the mouse and the keyboard")
+	keysFlag := flag.String("keys", "", "key bindings over the default ones, e.g. \"I=up,Space=new\"")
+	pdbFlag := flag.String("pdb", "", "comma separated pattern database files the hints are searched with")
+	flag.Parse()
+
+	var replay *board.Recording
+	if *replayFlag != "" {
+		b, err := os.ReadFile(*replayFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read replay: %v", err)
+			os.Exit(1)
+		}
+		r, err := board.ParseRecording(strings.TrimSpace(string(b)))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read replay: %v", err)
+			os.Exit(1)
+		}
+		replay = &r
+	}
+
+	keys := puzzle.DefaultKeyBindings()
+	if *keysFlag != "" {
+		if err := keys.Bind(*keysFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "read keys: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	var heuristic solver.Heuristic
+	if *pdbFlag != "" {
+		h, closeDBs, err := pdb.LoadHeuristic(*pdbFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read pattern database: %v", err)
+			os.Exit(1)
+		}
+		defer closeDBs()
+		heuristic = h
+	}
+
+	if err := puzzle.Init(func(p *puzzle.Controller) {
+		p.SetActive(true)
+		p.Replay = replay
+		p.KeyBindings = keys
+		p.Gamepad = *gamepadFlag
+		p.Heuristic = heuristic
+	}); err != nil {
 		fmt.Fprintf(os.Stderr, "start failed: %v", err)
 		os.Exit(1)
 	}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"syscall/js"
 )
 
@@ -20,14 +21,36 @@ func main() {
 		p.InfoRequest = func() {
 			js.Global().Call("apiRequest", js.ValueOf(http.MethodGet), js.ValueOf("api/info"))
 		}
-		p.UserStatsRequest = func() {
-			js.Global().Call("apiRequest", js.ValueOf(http.MethodGet), js.ValueOf("api/stats"))
+		p.UserStatsRequest = func(lb model.Leaderboard) {
+			js.Global().Call("apiRequest", js.ValueOf(http.MethodGet), js.ValueOf("api/stats?"+leaderboardQuery(lb)))
 		}
-		p.OnGameStart = func() {
-			js.Global().Call("apiRequest", js.ValueOf(http.MethodPut), js.ValueOf("api/start"))
+		p.DailyRequest = func(lb model.Leaderboard) {
+			js.Global().Call("apiRequest", js.ValueOf(http.MethodGet), js.ValueOf("api/daily?"+leaderboardQuery(lb)))
 		}
-		p.OnGameSolve = func(moves int) {
-			js.Global().Call("apiRequest", js.ValueOf(http.MethodPut), js.ValueOf("api/solve?moves="+strconv.Itoa(moves)))
+		p.RankRequest = func(lb model.Leaderboard, w model.Window) {
+			js.Global().Call("apiRequest", js.ValueOf(http.MethodGet), js.ValueOf("api/rating?"+leaderboardQuery(lb)+"&window="+w.String()))
+		}
+		p.ReplayRequest = func(lb model.Leaderboard, rank int) {
+			js.Global().Call("apiRequest", js.ValueOf(http.MethodGet), js.ValueOf("api/replay?"+leaderboardQuery(lb)+"&rank="+strconv.Itoa(rank)))
+		}
+		p.OnGameStart = func(lb model.Leaderboard, daily bool, respond func(model.ApiResponse)) {
+			appData := js.Global().Get("Telegram").Get("WebApp").Get("initData").String()
+			go func() {
+				_, result, err := apiRequest(http.MethodPut, "api/start?"+leaderboardQuery(lb)+"&daily="+strconv.FormatBool(daily), "", appData, nil)
+				if err != nil {
+					errStr := err.Error()
+					result = model.ApiResponse{Err: &errStr}
+				}
+				respond(result)
+			}()
+		}
+		p.OnGameSolve = func(lb model.Leaderboard, solve model.Solve) {
+			body, err := json.Marshal(solve)
+			if err != nil {
+				p.Debug("solve json marshal: %s", err)
+				return
+			}
+			js.Global().Call("apiRequest", js.ValueOf(http.MethodPut), js.ValueOf("api/solve?"+leaderboardQuery(lb)), js.ValueOf(""), js.ValueOf(string(body)))
 		}
 		p.MonitoringRequest = func(code string) {
 			js.Global().Call("apiRequest", js.ValueOf(http.MethodGet), js.ValueOf("api/monitoring"), js.ValueOf(code))
@@ -48,6 +71,10 @@ func main() {
 	}
 }
 
+func leaderboardQuery(lb model.Leaderboard) string {
+	return "size=" + lb.Size.String() + "&tier=" + lb.Tier.String() + "&metric=" + lb.Metric.String()
+}
+
 func HTTPRequestFunc(responseHandler func(model.ApiResponse)) js.Func {
 	return js.FuncOf(func(this js.Value, args []js.Value) any {
 		// Get the URL as argument
@@ -55,6 +82,10 @@ func HTTPRequestFunc(responseHandler func(model.ApiResponse)) js.Func {
 		reqUrl := args[1].String()
 		code := args[2].String()
 		appData := args[3].String()
+		var body io.Reader
+		if len(args) > 4 && args[4].String() != "" {
+			body = strings.NewReader(args[4].String())
+		}
 		handler := js.FuncOf(func(this js.Value, args []js.Value) any {
 			resolve := args[0]
 			reject := args[1]
@@ -65,60 +96,22 @@ func HTTPRequestFunc(responseHandler func(model.ApiResponse)) js.Func {
 				errStr := err.Error()
 				responseHandler(model.ApiResponse{Err: &errStr})
 			}
-			var result model.ApiResponse
 			go func() {
-				// The HTTP request
-				req, err := http.NewRequest(reqMethod, reqUrl, nil)
-				if err != nil {
-					onErr(fmt.Errorf("http new request: %s", err))
-					return
-				}
-				req.Header.Add(handler.WebAppInitDataHeader, appData)
-				if code != "" {
-					req.Header.Add(handler.WebAppExtraCodeHeader, code)
-				}
-				res, err := http.DefaultClient.Do(req)
+				data, result, err := apiRequest(reqMethod, reqUrl, code, appData, body)
 				if err != nil {
-					onErr(fmt.Errorf("http do request: %s", err))
+					onErr(err)
 					return
 				}
-				defer res.Body.Close()
-
-				data := make([]byte, 0)
-
-				// Func to return a Promise because HTTP requests are blocking in Go
-				returnPromise := func() {
-					arrayConstructor := js.Global().Get("Uint8Array")
-					dataJS := arrayConstructor.New(len(data))
-					js.CopyBytesToJS(dataJS, data)
-					responseConstructor := js.Global().Get("Response")
-					response := responseConstructor.New(dataJS)
-					resolve.Invoke(response)
+				// Return a Promise because HTTP requests are blocking in Go
+				arrayConstructor := js.Global().Get("Uint8Array")
+				dataJS := arrayConstructor.New(len(data))
+				js.CopyBytesToJS(dataJS, data)
+				responseConstructor := js.Global().Get("Response")
+				response := responseConstructor.New(dataJS)
+				if data != nil {
+					responseHandler(result)
 				}
-
-				switch res.StatusCode {
-				case http.StatusForbidden:
-					returnPromise()
-					return
-				case http.StatusOK:
-				default:
-					onErr(fmt.Errorf("http code: %d", res.StatusCode))
-					return
-				}
-
-				// Read the response body
-				data, err = io.ReadAll(res.Body)
-				if err != nil {
-					onErr(fmt.Errorf("http read response: %s", err))
-					return
-				}
-				if err := json.Unmarshal(data, &result); err != nil {
-					onErr(fmt.Errorf("http response to json: %s", err))
-					return
-				}
-
-				responseHandler(result)
-				returnPromise()
+				resolve.Invoke(response)
 			}()
 			return nil
 		})
@@ -126,3 +119,42 @@ func HTTPRequestFunc(responseHandler func(model.ApiResponse)) js.Func {
 		return promiseConstructor.New(handler)
 	})
 }
+
+// apiRequest sends the request to the API, a forbidden request responds no data.
+func apiRequest(method, url, code, appData string, body io.Reader) ([]byte, model.ApiResponse, error) {
+	var result model.ApiResponse
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, result, fmt.Errorf("http new request: %s", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Add(handler.WebAppInitDataHeader, appData)
+	if code != "" {
+		req.Header.Add(handler.WebAppExtraCodeHeader, code)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, result, fmt.Errorf("http do request: %s", err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusForbidden:
+		return nil, result, nil
+	case http.StatusOK:
+	default:
+		return nil, result, fmt.Errorf("http code: %d", res.StatusCode)
+	}
+
+	// Read the response body
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, result, fmt.Errorf("http read response: %s", err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, result, fmt.Errorf("http response to json: %s", err)
+	}
+	return data, result, nil
+}
@@ -0,0 +1,337 @@
+package board
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinSize  = 3
+	MaxSize  = 8
+	MaxTiles = MaxSize * MaxSize
+)
+
+// Classic is the 15 puzzle board size.
+var Classic = Size{W: 4, H: 4}
+
+// Size is the board dimension in tiles.
+type Size struct {
+	W, H int
+}
+
+// ParseSize reads a size in the String format, e.g. "4x4".
+func ParseSize(s string) (Size, error) {
+	w, h, ok := strings.Cut(s, "x")
+	if !ok {
+		return Size{}, fmt.Errorf("parse size %q: expected WxH", s)
+	}
+	var sz Size
+	var err error
+	if sz.W, err = strconv.Atoi(w); err != nil {
+		return Size{}, fmt.Errorf("parse size %q: %s", s, err)
+	}
+	if sz.H, err = strconv.Atoi(h); err != nil {
+		return Size{}, fmt.Errorf("parse size %q: %s", s, err)
+	}
+	if err := sz.Validate(); err != nil {
+		return Size{}, fmt.Errorf("parse size %q: %s", s, err)
+	}
+	return sz, nil
+}
+
+func (s Size) String() string {
+	return strconv.Itoa(s.W) + "x" + strconv.Itoa(s.H)
+}
+
+func (s Size) Validate() error {
+	if s.W < MinSize || s.W > MaxSize || s.H < MinSize || s.H > MaxSize {
+		return fmt.Errorf("size %s out of range %dx%d..%dx%d", s, MinSize, MinSize, MaxSize, MaxSize)
+	}
+	return nil
+}
+
+func (s Size) Tiles() int {
+	return s.W * s.H
+}
+
+func (s Size) Col(pos int) int {
+	return pos % s.W
+}
+
+func (s Size) Row(pos int) int {
+	return pos / s.W
+}
+
+func (s Size) Pos(col, row int) int {
+	return row*s.W + col
+}
+
+// Direction is the way a tile slides into the blank cell.
+type Direction byte
+
+const (
+	Up Direction = iota
+	Down
+	Left
+	Right
+)
+
+var Directions = [...]Direction{Up, Down, Left, Right}
+
+func (d Direction) Opposite() Direction {
+	switch d {
+	case Up:
+		return Down
+	case Down:
+		return Up
+	case Left:
+		return Right
+	default:
+		return Left
+	}
+}
+
+// Board is a puzzle position without any presentation state.
+// Cells are indexed by position in row-major order, a cell value is the tile number and zero is the blank.
+// Cells are held in a fixed array, so a Board value can be copied and compared.
+type Board struct {
+	size  Size
+	cells [MaxTiles]byte
+	blank int
+}
+
+// Solved returns the goal position: tiles in ascending order with the blank in the bottom right corner.
+func Solved(s Size) Board {
+	b := Board{size: s}
+	for i := range s.Tiles() - 1 {
+		b.cells[i] = byte(i + 1)
+	}
+	b.blank = s.Tiles() - 1
+	return b
+}
+
+// Shuffled returns a random solvable position.
+func Shuffled(s Size) Board {
+	b := Solved(s)
+	rand.Shuffle(s.Tiles(), func(i, j int) { b.swap(i, j) })
+	if !b.IsSolvable() {
+		b.swap(b.Pos(1), b.Pos(2))
+	}
+	return b
+}
+
+// New makes a board of cells which must hold every tile of the size exactly once.
+func New(s Size, cells []byte) (Board, error) {
+	if err := s.Validate(); err != nil {
+		return Board{}, err
+	}
+	if len(cells) != s.Tiles() {
+		return Board{}, fmt.Errorf("expected %d cells for size %s, got %d", s.Tiles(), s, len(cells))
+	}
+	b := Board{size: s, blank: -1}
+	copy(b.cells[:], cells)
+	var seen [MaxTiles]bool
+	for i, c := range cells {
+		if int(c) >= s.Tiles() {
+			return Board{}, fmt.Errorf("tile %d out of range at position %d", c, i)
+		}
+		if seen[c] {
+			if c == 0 {
+				return Board{}, errors.New("only one blank position allowed")
+			}
+			return Board{}, fmt.Errorf("tile %d duplicated at position %d", c, i)
+		}
+		seen[c] = true
+		if c == 0 {
+			b.blank = i
+		}
+	}
+	if b.blank < 0 {
+		return Board{}, errors.New("blank position required to be set")
+	}
+	return b, nil
+}
+
+// Parse reads a board in the String format: rows separated by '/', tiles by ','.
+func Parse(s string) (Board, error) {
+	rows := strings.Split(s, "/")
+	size := Size{H: len(rows)}
+	cells := make([]byte, 0, MaxTiles)
+	for r, row := range rows {
+		cols := strings.Split(row, ",")
+		if r == 0 {
+			size.W = len(cols)
+		} else if len(cols) != size.W {
+			return Board{}, fmt.Errorf("parse board %q: expected %d tiles in row %d, got %d", s, size.W, r+1, len(cols))
+		}
+		for _, v := range cols {
+			n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 8)
+			if err != nil {
+				return Board{}, fmt.Errorf("parse board %q: %s", s, err)
+			}
+			cells = append(cells, byte(n))
+		}
+	}
+	b, err := New(size, cells)
+	if err != nil {
+		return Board{}, fmt.Errorf("parse board %q: %s", s, err)
+	}
+	return b, nil
+}
+
+func (b Board) String() string {
+	var sb strings.Builder
+	for i, c := range b.Cells() {
+		switch {
+		case i == 0:
+		case i%b.size.W == 0:
+			sb.WriteByte('/')
+		default:
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.Itoa(int(c)))
+	}
+	return sb.String()
+}
+
+func (b Board) Size() Size {
+	return b.size
+}
+
+func (b Board) Cells() []byte {
+	return b.cells[:b.size.Tiles()]
+}
+
+// At returns the tile number at position pos.
+func (b Board) At(pos int) byte {
+	return b.cells[pos]
+}
+
+// Pos returns the position of the tile.
+func (b Board) Pos(tile byte) int {
+	if tile == 0 {
+		return b.blank
+	}
+	for i, c := range b.Cells() {
+		if c == tile {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b Board) Blank() int {
+	return b.blank
+}
+
+// Source returns the position of the tile which would slide in the direction d.
+func (b Board) Source(d Direction) (int, bool) {
+	col, row := b.size.Col(b.blank), b.size.Row(b.blank)
+	switch {
+	case d == Up && row < b.size.H-1:
+		return b.blank + b.size.W, true
+	case d == Down && row > 0:
+		return b.blank - b.size.W, true
+	case d == Left && col < b.size.W-1:
+		return b.blank + 1, true
+	case d == Right && col > 0:
+		return b.blank - 1, true
+	}
+	return -1, false
+}
+
+// Direction returns the way the tile at position pos would slide, it is only possible for tiles adjacent to the blank.
+func (b Board) Direction(pos int) (Direction, bool) {
+	for _, d := range Directions {
+		if p, ok := b.Source(d); ok && p == pos {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Line returns the way the tiles between the position pos and the blank would slide, and the number of them.
+// It is only possible for tiles in the row or the column of the blank.
+func (b Board) Line(pos int) (Direction, int, bool) {
+	if pos < 0 || pos >= b.size.Tiles() || pos == b.blank {
+		return 0, 0, false
+	}
+	col, row := b.size.Col(pos), b.size.Row(pos)
+	blankCol, blankRow := b.size.Col(b.blank), b.size.Row(b.blank)
+	switch {
+	case row == blankRow && col < blankCol:
+		return Right, blankCol - col, true
+	case row == blankRow:
+		return Left, col - blankCol, true
+	case col == blankCol && row < blankRow:
+		return Down, blankRow - row, true
+	case col == blankCol:
+		return Up, row - blankRow, true
+	}
+	return 0, 0, false
+}
+
+// Moves returns all the legal directions for the position.
+func (b Board) Moves() []Direction {
+	moves := make([]Direction, 0, len(Directions))
+	for _, d := range Directions {
+		if _, ok := b.Source(d); ok {
+			moves = append(moves, d)
+		}
+	}
+	return moves
+}
+
+// Move slides a tile in the direction d into the blank cell.
+func (b *Board) Move(d Direction) error {
+	pos, ok := b.Source(d)
+	if !ok {
+		return fmt.Errorf("illegal move %d with blank at %d", d, b.blank)
+	}
+	b.swap(pos, b.blank)
+	return nil
+}
+
+func (b Board) IsSolved() bool {
+	return b == Solved(b.size)
+}
+
+func (b Board) IsSolvable() bool {
+	solvable, _ := IsSolvable(b.size, b.Cells())
+	return solvable
+}
+
+// IsSolvable tells whether the cells layout can be brought to the solved position.
+// With an odd board width the number of inversions must be even, with an even width
+// the number of inversions plus the blank's row counted from the bottom must be even.
+func IsSolvable(s Size, cells []byte) (bool, error) {
+	b, err := New(s, cells)
+	if err != nil {
+		return false, err
+	}
+	invCount := 0
+	for i := range len(cells) - 1 {
+		for j := i + 1; j < len(cells); j++ {
+			if cells[j] > 0 && cells[i] > 0 && cells[i] > cells[j] {
+				invCount += 1
+			}
+		}
+	}
+	if s.W%2 == 1 {
+		return invCount%2 == 0, nil
+	}
+	return (invCount+s.H-1-s.Row(b.blank))%2 == 0, nil
+}
+
+func (b *Board) swap(i, j int) {
+	b.cells[i], b.cells[j] = b.cells[j], b.cells[i]
+	switch {
+	case b.cells[i] == 0:
+		b.blank = i
+	case b.cells[j] == 0:
+		b.blank = j
+	}
+}
@@ -0,0 +1,187 @@
+package board_test
+
+import (
+	"15-puzzle/internal/board"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSolvable(t *testing.T) {
+	table := []struct {
+		puzzle   []byte
+		solvable bool
+	}{
+		{[]byte{
+			13, 2, 10, 3,
+			1, 12, 8, 4,
+			5, 0, 9, 6,
+			15, 14, 11, 7},
+			true},
+		{[]byte{
+			6, 13, 7, 10,
+			8, 9, 11, 0,
+			15, 2, 12, 5,
+			14, 3, 1, 4},
+			true},
+		{[]byte{
+			12, 1, 10, 2,
+			7, 11, 4, 14,
+			5, 0, 9, 15,
+			8, 13, 6, 3},
+			true},
+		{[]byte{ // not solvable permutation
+			3, 9, 1, 15,
+			14, 11, 4, 6,
+			13, 0, 10, 12,
+			2, 7, 8, 5},
+			false},
+		{[]byte{ // same as before but tiles 1 and 2 are switched
+			3, 9, 2, 15,
+			14, 11, 4, 6,
+			13, 0, 10, 12,
+			1, 7, 8, 5},
+			true},
+	}
+	for i := range table {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			solvable, err := board.IsSolvable(board.Classic, table[i].puzzle)
+			assert.NoError(t, err)
+			assert.Equal(t, table[i].solvable, solvable)
+		})
+	}
+	_, err := board.IsSolvable(board.Classic, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 11, 13, 14, 15, 16})
+	assert.Error(t, err)
+	_, err = board.IsSolvable(board.Classic, []byte{0, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 0})
+	assert.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	b, err := board.Parse("13,2,10,3/1,12,8,4/5,0,9,6/15,14,11,7")
+	assert.NoError(t, err)
+	assert.Equal(t, 9, b.Blank())
+	assert.Equal(t, byte(13), b.At(0))
+	assert.Equal(t, 12, b.Pos(15))
+	assert.Equal(t, "13,2,10,3/1,12,8,4/5,0,9,6/15,14,11,7", b.String())
+
+	b, err = board.Parse("1,2,3/4,5,6/7,8,0/9,10,11")
+	assert.NoError(t, err)
+	assert.Equal(t, board.Size{W: 3, H: 4}, b.Size())
+	assert.True(t, b.IsSolvable())
+
+	_, err = board.Parse("1,2,3,4/5,6,7,8/9,10,11")
+	assert.Error(t, err)
+	_, err = board.Parse("1,2/3,0")
+	assert.Error(t, err)
+	_, err = board.Parse("1,2,3,4/5,6,7,8/9,10,11,12/13,14,15,x")
+	assert.Error(t, err)
+	_, err = board.Parse("1,2,3,4/5,6,7,8/9,10,11,12/13,14,15,16")
+	assert.Error(t, err)
+}
+
+func TestMove(t *testing.T) {
+	b := board.Solved(board.Classic)
+	assert.True(t, b.IsSolved())
+	assert.ElementsMatch(t, []board.Direction{board.Down, board.Right}, b.Moves())
+	assert.Error(t, b.Move(board.Up))
+	assert.Error(t, b.Move(board.Left))
+
+	assert.NoError(t, b.Move(board.Right))
+	assert.False(t, b.IsSolved())
+	assert.Equal(t, 14, b.Blank())
+	assert.Equal(t, byte(15), b.At(15))
+
+	d, ok := b.Direction(10)
+	assert.True(t, ok)
+	assert.Equal(t, board.Down, d)
+	_, ok = b.Direction(0)
+	assert.False(t, ok)
+
+	assert.NoError(t, b.Move(board.Down))
+	assert.NoError(t, b.Move(board.Up))
+	assert.NoError(t, b.Move(board.Left))
+	assert.True(t, b.IsSolved())
+}
+
+func TestIsSolvableSizes(t *testing.T) {
+	table := []struct {
+		board    string
+		solvable bool
+	}{
+		{"1,2,3/4,5,6/7,8,0", true},
+		{"1,2,3/4,5,6/8,7,0", false},
+		{"8,1,3/4,0,2/7,6,5", true},
+		{"1,2,3,4/5,6,7,8/9,10,11,0", true},
+		{"1,2,3,4/5,6,7,8/9,10,0,11", true},
+		{"1,2,3,4/5,6,7,8/0,9,10,11", true},
+		{"1,2,3,4/5,6,7,0/9,10,11,8", true},
+		{"1,2,3,4/5,6,7,8/9,11,10,0", false},
+		{"1,2,3/4,5,6/7,8,9/10,11,0", true},
+		{"1,2,3/4,5,6/7,8,9/11,10,0", false},
+		{"1,2,3,4,5/6,7,8,9,10/11,12,13,14,15/16,17,18,19,20/21,22,23,0,24", true},
+		{"1,2,3,4,5/6,7,8,9,10/11,12,13,14,15/16,17,18,19,20/21,22,24,23,0", false},
+	}
+	for i := range table {
+		t.Run(table[i].board, func(t *testing.T) {
+			b, err := board.Parse(table[i].board)
+			assert.NoError(t, err)
+			assert.Equal(t, table[i].solvable, b.IsSolvable())
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	s, err := board.ParseSize("4x4")
+	assert.NoError(t, err)
+	assert.Equal(t, board.Classic, s)
+	s, err = board.ParseSize("8x3")
+	assert.NoError(t, err)
+	assert.Equal(t, board.Size{W: 8, H: 3}, s)
+	assert.Equal(t, "8x3", s.String())
+
+	for _, v := range []string{"", "4", "4x", "x4", "2x4", "4x9", "axb"} {
+		_, err := board.ParseSize(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestShuffled(t *testing.T) {
+	for _, s := range []board.Size{{W: 3, H: 3}, board.Classic, {W: 5, H: 5}, {W: 3, H: 5}, {W: 8, H: 8}} {
+		for range 100 {
+			b := board.Shuffled(s)
+			assert.Equal(t, s, b.Size())
+			assert.True(t, b.IsSolvable(), b.String())
+			assert.Equal(t, byte(0), b.At(b.Blank()), b.String())
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	b, _ := board.Parse("1,2,3,4/5,6,0,7/8,9,10,11/12,13,14,15")
+	for _, tc := range []struct {
+		pos int
+		d   board.Direction
+		n   int
+	}{
+		{4, board.Right, 2},
+		{5, board.Right, 1},
+		{7, board.Left, 1},
+		{2, board.Down, 1},
+		{14, board.Up, 2},
+	} {
+		d, n, ok := b.Line(tc.pos)
+		assert.True(t, ok, tc.pos)
+		assert.Equal(t, tc.d, d, tc.pos)
+		assert.Equal(t, tc.n, n, tc.pos)
+	}
+	for _, pos := range []int{0, 6, 11, -1, 16} {
+		_, _, ok := b.Line(pos)
+		assert.False(t, ok, pos)
+	}
+	moves, _ := board.ParseMoves("LLLUURDDD")
+	assert.Equal(t, 4, board.MultiTile.Count(moves))
+	assert.Equal(t, 9, board.SingleTile.Count(moves))
+	assert.Equal(t, board.MultiTile, board.SingleTile.Next())
+	assert.Equal(t, board.SingleTile, board.MultiTile.Next())
+}
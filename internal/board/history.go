@@ -0,0 +1,129 @@
+package board
+
+import "time"
+
+// History is the record of a game from its start position.
+// Undo and redo are slides themselves: they are appended to the log like any other move,
+// so the log replayed from the start always brings the current position and its length is the moves count.
+// The moves undone are kept for redo until a new move is made.
+// A move slides a line of tiles at once, it is undone and redone as a whole.
+type History struct {
+	start   Board
+	created time.Time
+	paused  time.Time // zero unless the clock is paused
+	log     []Direction
+	times   []time.Duration
+	path    []line
+	head    int
+}
+
+// line is a move of n tiles sliding in the same direction.
+type line struct {
+	d Direction
+	n int
+}
+
+func NewHistory(start Board) History {
+	return History{start: start, created: time.Now()}
+}
+
+// Start returns the position the game was started with.
+func (h History) Start() Board {
+	return h.start
+}
+
+// Log returns every slide made in the game including undo and redo ones.
+func (h History) Log() []Direction {
+	return h.log
+}
+
+// Len returns the number of slides made in the game.
+func (h History) Len() int {
+	return len(h.log)
+}
+
+// Push records a new move of n tiles sliding in the direction d, the moves available for redo are dropped.
+func (h *History) Push(d Direction, n int) {
+	h.path = append(h.path[:h.head], line{d: d, n: n})
+	h.head++
+	h.record(d, n)
+}
+
+func (h History) CanUndo() bool {
+	return h.head > 0
+}
+
+func (h History) CanRedo() bool {
+	return h.head < len(h.path)
+}
+
+// Undo records the move reverting the last one and returns its direction and the number of tiles.
+func (h *History) Undo() (Direction, int, bool) {
+	if !h.CanUndo() {
+		return 0, 0, false
+	}
+	h.head--
+	l := h.path[h.head]
+	h.record(l.d.Opposite(), l.n)
+	return l.d.Opposite(), l.n, true
+}
+
+// Redo records the last undone move again and returns its direction and the number of tiles.
+func (h *History) Redo() (Direction, int, bool) {
+	if !h.CanRedo() {
+		return 0, 0, false
+	}
+	l := h.path[h.head]
+	h.head++
+	h.record(l.d, l.n)
+	return l.d, l.n, true
+}
+
+// Recording returns the log with the time of every slide since the history was created.
+func (h History) Recording() Recording {
+	return Recording{Start: h.start, Moves: h.log, Times: h.times}
+}
+
+// Board replays the log from the start position.
+func (h History) Board() (Board, error) {
+	return h.Recording().Board()
+}
+
+// Pause stops the clock of the game until Resume, the time paused is not recorded.
+func (h *History) Pause() {
+	if h.paused.IsZero() {
+		h.paused = time.Now()
+	}
+}
+
+func (h *History) Resume() {
+	if !h.paused.IsZero() {
+		h.created = h.created.Add(time.Since(h.paused))
+		h.paused = time.Time{}
+	}
+}
+
+// Elapsed returns the time played since the first move, zero before it.
+func (h History) Elapsed() time.Duration {
+	if len(h.times) == 0 {
+		return 0
+	}
+	return h.clock() - h.times[0]
+}
+
+// clock returns the time since the history was created excluding pauses.
+func (h History) clock() time.Duration {
+	if !h.paused.IsZero() {
+		return h.paused.Sub(h.created)
+	}
+	return time.Since(h.created)
+}
+
+// record logs the slides of a move, all of them at the same time.
+func (h *History) record(d Direction, n int) {
+	t := h.clock()
+	for range n {
+		h.log = append(h.log, d)
+		h.times = append(h.times, t)
+	}
+}
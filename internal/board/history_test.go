@@ -0,0 +1,130 @@
+package board_test
+
+import (
+	"15-puzzle/internal/board"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	start, _ := board.Parse("1,2,3/4,5,6/7,8,0")
+	h := board.NewHistory(start)
+	assert.False(t, h.CanUndo())
+	assert.False(t, h.CanRedo())
+	_, _, ok := h.Undo()
+	assert.False(t, ok)
+
+	h.Push(board.Down, 1)
+	h.Push(board.Right, 1)
+	b, err := h.Board()
+	assert.NoError(t, err)
+	assert.Equal(t, "1,2,3/4,0,5/7,8,6", b.String())
+
+	d, _, ok := h.Undo()
+	assert.True(t, ok)
+	assert.Equal(t, board.Left, d)
+	assert.True(t, h.CanRedo())
+	b, _ = h.Board()
+	assert.Equal(t, "1,2,3/4,5,0/7,8,6", b.String())
+
+	d, _, ok = h.Redo()
+	assert.True(t, ok)
+	assert.Equal(t, board.Right, d)
+	assert.False(t, h.CanRedo())
+
+	_, _, _ = h.Undo()
+	_, _, _ = h.Undo()
+	assert.False(t, h.CanUndo())
+	b, _ = h.Board()
+	assert.Equal(t, start, b)
+	assert.Equal(t, 6, h.Len(), "undo and redo are counted as moves")
+
+	h.Push(board.Right, 1)
+	assert.False(t, h.CanRedo(), "a new move drops the moves undone")
+	assert.Equal(t, []board.Direction{board.Down, board.Right, board.Left, board.Right, board.Left, board.Up, board.Right}, h.Log())
+	b, _ = h.Board()
+	assert.Equal(t, "1,2,3/4,5,6/7,0,8", b.String())
+}
+
+func TestHistoryLines(t *testing.T) {
+	start, _ := board.Parse("1,2,3,4/5,6,7,8/9,10,11,12/13,14,15,0")
+	h := board.NewHistory(start)
+	h.Push(board.Right, 3)
+	h.Push(board.Down, 2)
+	b, _ := h.Board()
+	assert.Equal(t, "1,2,3,4/0,6,7,8/5,10,11,12/9,13,14,15", b.String())
+
+	d, n, ok := h.Undo()
+	assert.True(t, ok)
+	assert.Equal(t, board.Up, d)
+	assert.Equal(t, 2, n, "a line is undone as a whole")
+	d, n, _ = h.Redo()
+	assert.Equal(t, board.Down, d)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 9, h.Len())
+	assert.Equal(t, 4, board.MultiTile.Count(h.Log()))
+	assert.Equal(t, 9, board.SingleTile.Count(h.Log()))
+	times := h.Recording().Times
+	assert.Equal(t, times[0], times[2], "slides of a line are made at once")
+}
+
+func TestHistoryPause(t *testing.T) {
+	start, _ := board.Parse("1,2,3/4,5,6/7,8,0")
+	h := board.NewHistory(start)
+	assert.Zero(t, h.Elapsed(), "the clock starts with the first move")
+	h.Push(board.Down, 1)
+	h.Pause()
+	elapsed := h.Elapsed()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, elapsed, h.Elapsed(), "the clock should stop on pause")
+	h.Resume()
+	h.Push(board.Up, 1)
+	assert.Less(t, h.Recording().Duration(), 20*time.Millisecond, "the time paused should not be recorded")
+}
+
+func TestRecording(t *testing.T) {
+	start, _ := board.Parse("1,2,3/4,5,6/7,0,8")
+	r := board.Recording{
+		Start: start,
+		Moves: []board.Direction{board.Right, board.Left, board.Left},
+		Times: []time.Duration{850 * time.Millisecond, 1150*time.Millisecond + 999*time.Microsecond, 3 * time.Second},
+	}
+	assert.Equal(t, "1,2,3/4,5,6/7,0,8 RLL 850,300,1850", r.String())
+	parsed, err := board.ParseRecording(r.String())
+	assert.NoError(t, err)
+	assert.Equal(t, start, parsed.Start)
+	assert.Equal(t, r.Moves, parsed.Moves)
+	assert.Equal(t, []time.Duration{850 * time.Millisecond, 1150 * time.Millisecond, 3 * time.Second}, parsed.Times)
+	assert.Equal(t, 2150*time.Millisecond, parsed.Duration())
+	b, err := parsed.Board()
+	assert.NoError(t, err)
+	assert.True(t, b.IsSolved())
+
+	empty, err := board.ParseRecording("1,2,3/4,5,6/7,0,8  ")
+	assert.NoError(t, err)
+	assert.Empty(t, empty.Moves)
+
+	for _, v := range []string{
+		"1,2,3/4,5,6/7,0,8 RLL 850,300",
+		"1,2,3/4,5,6/7,0,8 RLX 850,300,1",
+		"1,2,3/4,5,6/7,0,8 RRR 850,300,1",
+		"1,2,3/4,5,6/7,0,8 RLL 850,-300,1",
+		"1,2,3/4,5,6/7,0,7 RLL 850,300,1",
+		"1,2,3/4,5,6/7,0,8 RLL",
+	} {
+		_, err := board.ParseRecording(v)
+		assert.Error(t, err, v)
+	}
+
+	h := board.NewHistory(start)
+	h.Push(board.Right, 1)
+	_, _, _ = h.Undo()
+	h.Push(board.Left, 1)
+	rec := h.Recording()
+	assert.Equal(t, []board.Direction{board.Right, board.Left, board.Left}, rec.Moves)
+	assert.Len(t, rec.Times, 3)
+	assert.True(t, slices.IsSorted(rec.Times))
+}
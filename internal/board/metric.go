@@ -0,0 +1,65 @@
+package board
+
+import "fmt"
+
+// Metric is the way the moves of a game are counted.
+// The zero metric is SingleTile, the only one before multi-tile slides were introduced.
+type Metric int
+
+const (
+	SingleTile Metric = iota // every tile slide is a move
+	MultiTile                // slides in the same direction in a row are one move, e.g. "LLLU" is two moves
+)
+
+var metricNames = map[Metric]string{SingleTile: "stm", MultiTile: "mtm"}
+
+func ParseMetric(s string) (Metric, error) {
+	for m, name := range metricNames {
+		if name == s {
+			return m, nil
+		}
+	}
+	return SingleTile, fmt.Errorf("parse metric %q: unknown", s)
+}
+
+func (m Metric) String() string {
+	if name, ok := metricNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("metric(%d)", int(m))
+}
+
+// Count returns the number of moves in the metric.
+func (m Metric) Count(moves []Direction) int {
+	if m != MultiTile {
+		return len(moves)
+	}
+	n := 0
+	for i := range moves {
+		if i == 0 || moves[i] != moves[i-1] {
+			n++
+		}
+	}
+	return n
+}
+
+// Next returns the other metric.
+func (m Metric) Next() Metric {
+	if m == MultiTile {
+		return SingleTile
+	}
+	return MultiTile
+}
+
+func (m Metric) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Metric) UnmarshalText(text []byte) error {
+	v, err := ParseMetric(string(text))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
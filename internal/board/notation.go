@@ -0,0 +1,134 @@
+package board
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// notation letters are the directions tiles slide, the blank moves the opposite way.
+const notation = "UDLR"
+
+func (d Direction) String() string {
+	if int(d) >= len(notation) {
+		return "?"
+	}
+	return notation[d : d+1]
+}
+
+// FormatMoves writes moves in slide notation, e.g. "ULLDR".
+func FormatMoves(moves []Direction) string {
+	var sb strings.Builder
+	for _, d := range moves {
+		sb.WriteString(d.String())
+	}
+	return sb.String()
+}
+
+// ParseMoves reads moves in the FormatMoves notation.
+func ParseMoves(s string) ([]Direction, error) {
+	moves := make([]Direction, 0, len(s))
+	for i := range len(s) {
+		d := strings.IndexByte(notation, s[i])
+		if d < 0 {
+			return nil, fmt.Errorf("parse moves: unexpected %q at %d", s[i], i)
+		}
+		moves = append(moves, Direction(d))
+	}
+	return moves, nil
+}
+
+// Recording is a game replayable from the start position.
+// Times are the moments of every move since the start.
+type Recording struct {
+	Start Board
+	Moves []Direction
+	Times []time.Duration
+}
+
+// String writes the recording as space separated start position, moves and milliseconds between moves,
+// e.g. "1,2,3/4,5,6/7,0,8 LR 850,300".
+func (r Recording) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.Start.String())
+	sb.WriteByte(' ')
+	sb.WriteString(FormatMoves(r.Moves))
+	sb.WriteByte(' ')
+	var prev time.Duration
+	for i, t := range r.Times {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatInt((t - prev).Milliseconds(), 10))
+		prev = t.Truncate(time.Millisecond)
+	}
+	return sb.String()
+}
+
+// ParseRecording reads a recording in the String format, every move must be legal.
+func ParseRecording(s string) (Recording, error) {
+	fields := strings.Split(s, " ")
+	if len(fields) != 3 {
+		return Recording{}, fmt.Errorf("parse recording: expected 3 fields, got %d", len(fields))
+	}
+	var r Recording
+	var err error
+	if r.Start, err = Parse(fields[0]); err != nil {
+		return Recording{}, fmt.Errorf("parse recording: %s", err)
+	}
+	if r.Moves, err = ParseMoves(fields[1]); err != nil {
+		return Recording{}, fmt.Errorf("parse recording: %s", err)
+	}
+	var t time.Duration
+	for _, v := range strings.Split(fields[2], ",") {
+		if v == "" && len(r.Moves) == 0 {
+			break
+		}
+		ms, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return Recording{}, fmt.Errorf("parse recording: %s", err)
+		}
+		t += time.Duration(ms) * time.Millisecond
+		r.Times = append(r.Times, t)
+	}
+	if len(r.Times) != len(r.Moves) {
+		return Recording{}, fmt.Errorf("parse recording: %d moves with %d times", len(r.Moves), len(r.Times))
+	}
+	if _, err := r.Board(); err != nil {
+		return Recording{}, fmt.Errorf("parse recording: %s", err)
+	}
+	return r, nil
+}
+
+func (r Recording) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Recording) UnmarshalText(text []byte) error {
+	v, err := ParseRecording(string(text))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// Duration returns the time from the first move to the last one.
+func (r Recording) Duration() time.Duration {
+	if len(r.Times) == 0 {
+		return 0
+	}
+	return r.Times[len(r.Times)-1] - r.Times[0]
+}
+
+// Board replays the moves from the start position.
+func (r Recording) Board() (Board, error) {
+	b := r.Start
+	for i, d := range r.Moves {
+		if err := b.Move(d); err != nil {
+			return b, fmt.Errorf("move %d: %s", i+1, err)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,123 @@
+package board
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxWalk limits the moves of a walk scramble.
+const MaxWalk = 1000
+
+// Scramble identifies a start position generated from the seed.
+// The generator is defined here and never changes, so a seed brings the same position for every client and server version.
+// A scramble of a walk is the solved position shuffled by that many random moves, otherwise it is a random position.
+type Scramble struct {
+	Size Size
+	Seed uint64
+	Walk int
+}
+
+// NewScramble returns a scramble of a random seed.
+func NewScramble(s Size) Scramble {
+	return Scramble{Size: s, Seed: rand.Uint64()}
+}
+
+// Daily returns the scramble shared by all players for the day, days change at midnight UTC.
+func Daily(s Size, day time.Time) Scramble {
+	h := fnv.New64a()
+	h.Write([]byte(DayOf(day)))
+	return Scramble{Size: s, Seed: h.Sum64()}
+}
+
+// DayOf returns the UTC date of t, e.g. "2024-12-31".
+func DayOf(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// ParseScramble reads a scramble in the String format, e.g. "4x4:9e3779b97f4a7c15" or "4x4:9e3779b97f4a7c15/30" of a walk.
+func ParseScramble(s string) (Scramble, error) {
+	size, seed, ok := strings.Cut(s, ":")
+	if !ok {
+		return Scramble{}, fmt.Errorf("parse scramble %q: expected size:seed", s)
+	}
+	seed, walk, hasWalk := strings.Cut(seed, "/")
+	var sc Scramble
+	var err error
+	if sc.Size, err = ParseSize(size); err != nil {
+		return Scramble{}, fmt.Errorf("parse scramble %q: %s", s, err)
+	}
+	if sc.Seed, err = strconv.ParseUint(seed, 16, 64); err != nil {
+		return Scramble{}, fmt.Errorf("parse scramble %q: %s", s, err)
+	}
+	if hasWalk {
+		if sc.Walk, err = strconv.Atoi(walk); err != nil {
+			return Scramble{}, fmt.Errorf("parse scramble %q: %s", s, err)
+		}
+		if sc.Walk < 1 || sc.Walk > MaxWalk {
+			return Scramble{}, fmt.Errorf("parse scramble %q: walk out of range 1..%d", s, MaxWalk)
+		}
+	}
+	return sc, nil
+}
+
+func (sc Scramble) String() string {
+	s := sc.Size.String() + ":" + strconv.FormatUint(sc.Seed, 16)
+	if sc.Walk > 0 {
+		s += "/" + strconv.Itoa(sc.Walk)
+	}
+	return s
+}
+
+// Board returns the solvable position of the scramble.
+// A random position is a Fisher-Yates shuffle of the solved position, the tiles 1 and 2 are swapped when the shuffle is not solvable.
+// A walk never slides the tile just moved back.
+func (sc Scramble) Board() Board {
+	b := Solved(sc.Size)
+	r := splitMix(sc.Seed)
+	if sc.Walk > 0 {
+		var last Direction
+		for i := range sc.Walk {
+			moves := b.Moves()
+			if i > 0 {
+				moves = slices.DeleteFunc(moves, func(d Direction) bool { return d == last.Opposite() })
+			}
+			last = moves[r.intN(len(moves))]
+			_ = b.Move(last)
+		}
+		return b
+	}
+	for i := sc.Size.Tiles() - 1; i > 0; i-- {
+		b.swap(i, r.intN(i+1))
+	}
+	if !b.IsSolvable() {
+		b.swap(b.Pos(1), b.Pos(2))
+	}
+	return b
+}
+
+// splitMix is the SplitMix64 generator, it is kept here to never depend on a library generator changes.
+type splitMix uint64
+
+func (s *splitMix) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := uint64(*s)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// intN returns an unbiased value in [0, n).
+func (s *splitMix) intN(n int) int {
+	limit := math.MaxUint64 - math.MaxUint64%uint64(n)
+	for {
+		if v := s.next(); v < limit {
+			return int(v % uint64(n))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package board_test
+
+import (
+	"15-puzzle/internal/board"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScramble(t *testing.T) {
+	// positions are fixed forever, a change here breaks daily challenges and stored games
+	assert.Equal(t, "3,12,11,7/8,14,15,2/13,6,0,10/4,9,5,1", board.Scramble{Size: board.Classic, Seed: 1}.Board().String())
+	assert.Equal(t, "13,1,0/12,4,7/5,3,9/6,10,14/11,2,8", board.Scramble{Size: board.Size{W: 3, H: 5}, Seed: 0xdeadbeef}.Board().String())
+
+	daily := board.Daily(board.Classic, time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC))
+	assert.Equal(t, "4x4:3b52d169edfe732a", daily.String())
+	assert.Equal(t, daily, board.Daily(board.Classic, time.Date(2025, 1, 1, 1, 0, 0, 0, time.FixedZone("CET", 3600*2))))
+	assert.NotEqual(t, daily, board.Daily(board.Classic, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	for range 100 {
+		sc := board.NewScramble(board.Size{W: 5, H: 4})
+		assert.True(t, sc.Board().IsSolvable())
+		assert.Equal(t, sc.Board(), sc.Board())
+		parsed, err := board.ParseScramble(sc.String())
+		assert.NoError(t, err)
+		assert.Equal(t, sc, parsed)
+	}
+
+	walk := board.Scramble{Size: board.Size{W: 3, H: 3}, Seed: 7, Walk: 20}
+	assert.Equal(t, "3x3:7/20", walk.String())
+	assert.Equal(t, "4,8,6/2,0,1/7,3,5", walk.Board().String())
+	parsed, err := board.ParseScramble(walk.String())
+	assert.NoError(t, err)
+	assert.Equal(t, walk, parsed)
+
+	for _, v := range []string{"4x4", "4x4:", "4x4:xyz", "9x9:1", "4x4:-1", "4x4:1/", "4x4:1/0", "4x4:1/x", "4x4:1/100000"} {
+		_, err := board.ParseScramble(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestTier(t *testing.T) {
+	for _, tier := range board.Tiers {
+		parsed, err := board.ParseTier(tier.String())
+		assert.NoError(t, err)
+		assert.Equal(t, tier, parsed)
+	}
+	_, err := board.ParseTier("trivial")
+	assert.Error(t, err)
+
+	assert.Equal(t, board.Medium, board.Easy.Next())
+	assert.Equal(t, board.Easy, board.Expert.Next())
+	lo, hi := board.Hard.Range()
+	assert.True(t, lo > 0 && lo <= hi)
+}
@@ -0,0 +1,73 @@
+package board
+
+import "fmt"
+
+// Tier is the difficulty of scrambles given by the range of their optimal solution length.
+// The zero tier is Expert: a random position like every game played before tiers were introduced.
+type Tier int
+
+const (
+	Expert Tier = iota
+	Easy
+	Medium
+	Hard
+)
+
+// Tiers are ordered from the easiest one.
+var Tiers = []Tier{Easy, Medium, Hard, Expert}
+
+var tierNames = map[Tier]string{Easy: "easy", Medium: "medium", Hard: "hard", Expert: "expert"}
+
+func ParseTier(s string) (Tier, error) {
+	for t, name := range tierNames {
+		if name == s {
+			return t, nil
+		}
+	}
+	return Expert, fmt.Errorf("parse tier %q: unknown", s)
+}
+
+func (t Tier) String() string {
+	if name, ok := tierNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("tier(%d)", int(t))
+}
+
+// Range returns the bounds of the optimal solution length of the tier scrambles, both inclusive.
+// Expert scrambles are random positions, the length is not bound.
+func (t Tier) Range() (lo, hi int) {
+	switch t {
+	case Easy:
+		return 10, 17
+	case Medium:
+		return 18, 25
+	case Hard:
+		return 26, 33
+	default:
+		return 0, 0
+	}
+}
+
+// Next returns the tier following t in Tiers, the easiest one follows the last.
+func (t Tier) Next() Tier {
+	for i := range Tiers {
+		if Tiers[i] == t {
+			return Tiers[(i+1)%len(Tiers)]
+		}
+	}
+	return Tiers[0]
+}
+
+func (t Tier) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Tier) UnmarshalText(text []byte) error {
+	v, err := ParseTier(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
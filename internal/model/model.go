@@ -1,10 +1,21 @@
 package model
 
 import (
+	"15-puzzle/internal/board"
 	"encoding/json"
+	"errors"
 	"time"
 )
 
+// Errors of the repository, a backend wraps them with the ids it failed on.
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrGameNotFound     = errors.New("game not found")
+	ErrGameNotStarted   = errors.New("game not started")
+	ErrOtherLeaderboard = errors.New("game of other leaderboard")
+	ErrNotQuarantined   = errors.New("game not quarantined")
+)
+
 type JSONTimestamp time.Time
 
 func (t JSONTimestamp) MarshalJSON() ([]byte, error) {
@@ -20,37 +31,192 @@ func (t *JSONTimestamp) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// JSONDuration is written as whole milliseconds.
+type JSONDuration time.Duration
+
+func (d JSONDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Milliseconds())
+}
+
+func (d *JSONDuration) UnmarshalJSON(data []byte) error {
+	var v int64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*d = JSONDuration(time.Duration(v) * time.Millisecond)
+	return nil
+}
+
+// Data is the content of the data file, a file of a former schema version is migrated before it is read.
 type Data struct {
-	Users map[int]User `json:"users"`
+	Version int                     `json:"version"`
+	Boards  map[string]map[int]User `json:"boards"`          // users by leaderboard, e.g. "4x4" or "4x4/easy/mtm"
+	Daily   map[string]map[int]User `json:"daily,omitempty"` // daily challenge users by day and leaderboard, e.g. "2024-12-31/4x4"
+	Games   []Game                  `json:"games,omitempty"` // every game in order of start, the id of a game is its position from 1
+	Seq     uint64                  `json:"seq,omitempty"`   // the sequence number of the last journal entry the data file keeps
+}
+
+// Leaderboard is the ranking a game is counted in, games of other sizes, tiers or move metrics are never compared.
+type Leaderboard struct {
+	Size   board.Size
+	Tier   board.Tier
+	Metric board.Metric
+}
+
+// String is the key of the leaderboard data, the expert tier and the single-tile metric are omitted
+// as the only ones known before tiers and metrics were introduced, e.g. "4x4" or "4x4/easy/mtm".
+func (l Leaderboard) String() string {
+	s := l.Size.String()
+	if l.Tier != board.Expert {
+		s += "/" + l.Tier.String()
+	}
+	if l.Metric != board.SingleTile {
+		s += "/" + l.Metric.String()
+	}
+	return s
+}
+
+// Rating is the order of the players of a leaderboard by a ranking.
+type Rating struct {
+	Ranking string
+	UserIDs []int
+}
+
+// Rank is the position of a player in the rating of a ranking from 1, -1 when the player is not rated.
+type Rank struct {
+	Ranking  string
+	Position int
 }
 
 type User struct {
-	UserID        int            `json:"user_id"`
-	GamesStarted  int            `json:"games_started"`
-	GamesSolved   int            `json:"games_solved"`
-	LastStartTime *JSONTimestamp `json:"last_start_ts,omitempty"`
-	BestResult    *float32       `json:"best_result,omitempty"`
-	BestSolveTime *JSONTimestamp `json:"best_solve_ts,omitempty"`
-	Monitoring    *Monitoring    `json:"-"`
+	UserID        int              `json:"user_id"`
+	GamesStarted  int              `json:"games_started"`
+	GamesSolved   int              `json:"games_solved"`
+	GamesHinted   int              `json:"games_hinted,omitempty"`   // solved games which used hints, not ranked
+	GamesRejected int              `json:"games_rejected,omitempty"` // solves which failed the server verification
+	LastStartTime *JSONTimestamp   `json:"last_start_ts,omitempty"`
+	BestResult    *float32         `json:"best_result,omitempty"` // seconds per move of the best time game
+	BestTime      *JSONDuration    `json:"best_time,omitempty"`
+	BestMoves     *int             `json:"best_moves,omitempty"`    // in the metric of the leaderboard
+	BestSolveTime *JSONTimestamp   `json:"best_solve_ts,omitempty"` // when the best time was achieved
+	BestGame      *board.Recording `json:"best_game,omitempty"`     // the game of the best time
+	Monitoring    *Monitoring      `json:"-"`
+}
+
+// Solve is a game result reported by the client.
+// Optimal is the single-tile solution length of the scramble, zero when unknown.
+// Duration is the time measured by the client from the first move to the last one, pauses excluded,
+// the server bounds it by its own start and solve timestamps before the solve is registered.
+type Solve struct {
+	Session   string          `json:"session,omitempty"` // the token of the game responded to its start
+	Scramble  string          `json:"scramble,omitempty"`
+	Tier      board.Tier      `json:"tier,omitempty"`
+	Metric    board.Metric    `json:"metric,omitempty"`
+	Optimal   int             `json:"optimal,omitempty"`
+	Duration  JSONDuration    `json:"duration,omitempty"`
+	Recording board.Recording `json:"recording"`
+	Hints     int             `json:"hints,omitempty"`
+}
+
+// GameStatus is the stage of a game, a started game which is not finished in AbandonAfter is abandoned.
+type GameStatus string
+
+const (
+	GameStarted     GameStatus = "started"
+	GameSolved      GameStatus = "solved"
+	GameRejected    GameStatus = "rejected"    // the solve failed the server verification
+	GameQuarantined GameStatus = "quarantined" // the solve is not counted until it is reviewed
+	GameAbandoned   GameStatus = "abandoned"
+)
+
+const AbandonAfter = 24 * time.Hour
+
+// Game is a single game of a user, a game solved without a registered start has no start time.
+type Game struct {
+	ID        int              `json:"id"`
+	UserID    int              `json:"user_id"`
+	Size      string           `json:"size"`
+	Tier      board.Tier       `json:"tier,omitempty"`
+	Metric    board.Metric     `json:"metric,omitempty"`
+	Scramble  string           `json:"scramble,omitempty"` // size and seed of the start position, unknown for legacy games
+	Status    GameStatus       `json:"status"`
+	Start     *JSONTimestamp   `json:"start_ts,omitempty"`
+	Finish    *JSONTimestamp   `json:"finish_ts,omitempty"`
+	Optimal   int              `json:"optimal,omitempty"`
+	Moves     int              `json:"moves,omitempty"` // in the metric of the game
+	Duration  JSONDuration     `json:"duration,omitempty"`
+	Hints     int              `json:"hints,omitempty"`
+	Suspicion *Suspicion       `json:"suspicion,omitempty"`
+	Recording *board.Recording `json:"recording,omitempty"` // of the quarantined game for its review
+}
+
+// QuarantineScore is the suspicion score of solves which are quarantined.
+const QuarantineScore = 1
+
+// Suspicion is how implausible a solve is for a human player, with the reasons of the score.
+type Suspicion struct {
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+func (s Suspicion) Quarantined() bool {
+	return s.Score >= QuarantineScore
+}
+
+// StatusAt returns the status of the game at the time, the started games turn abandoned.
+func (g Game) StatusAt(now time.Time) GameStatus {
+	if g.Status == GameStarted && g.Start != nil && now.Sub(time.Time(*g.Start)) > AbandonAfter {
+		return GameAbandoned
+	}
+	return g.Status
 }
 
 type ApiResponse struct {
 	Stats      *Stats      `json:"stats,omitempty"`
 	Monitoring *Monitoring `json:"monitoring,omitempty"`
 	Info       *Info       `json:"info,omitempty"`
+	Daily      *Daily      `json:"daily,omitempty"`
+	Game       *Game       `json:"game,omitempty"`    // the started game
+	Session    *string     `json:"session,omitempty"` // the token of the started game for its solve
+	Games      []Game      `json:"games,omitempty"`   // the games of the user
+	Replay     *Replay     `json:"replay,omitempty"`
 	Err        *string     `json:"error,omitempty"`
 }
 
+// Replay is the best game of the player at the rank of the leaderboard's main ranking.
+type Replay struct {
+	Rank      int             `json:"rank"`
+	UserID    int             `json:"user_id"`
+	Recording board.Recording `json:"recording"`
+}
+
 type Stats struct {
-	Rank         int `json:"rank"`
-	GamesStarted int `json:"games_started"`
-	GamesSolved  int `json:"games_solved"`
+	Size         string         `json:"size"`
+	Tier         board.Tier     `json:"tier,omitempty"`
+	Metric       board.Metric   `json:"metric,omitempty"`
+	Daily        string         `json:"daily,omitempty"`  // the day of the daily challenge the stats are of
+	Window       Window         `json:"window,omitempty"` // the period the stats and ranks are of, all time when omitted
+	Rank         int            `json:"rank"`             // in the main ranking
+	Ranks        map[string]int `json:"ranks,omitempty"`  // in every ranking
+	GamesStarted int            `json:"games_started"`
+	GamesSolved  int            `json:"games_solved"`
+	BestTime     JSONDuration   `json:"best_time,omitempty"`
+	BestMoves    int            `json:"best_moves,omitempty"`
 }
 
 type Monitoring struct {
-	Users        int `json:"users,omitempty"`
-	GamesStarted int `json:"games_started,omitempty"`
-	GamesSolved  int `json:"games_solved,omitempty"`
+	Users            int `json:"users,omitempty"`
+	GamesStarted     int `json:"games_started,omitempty"`
+	GamesSolved      int `json:"games_solved,omitempty"`
+	GamesRejected    int `json:"games_rejected,omitempty"`
+	GamesAbandoned   int `json:"games_abandoned,omitempty"`
+	GamesQuarantined int `json:"games_quarantined,omitempty"`
+}
+
+// Daily is the challenge of the day, the same for all players.
+type Daily struct {
+	Date     string `json:"date"`
+	Scramble string `json:"scramble"`
 }
 
 type Info struct {
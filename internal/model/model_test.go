@@ -0,0 +1,18 @@
+package model_test
+
+import (
+	"15-puzzle/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameStatus(t *testing.T) {
+	start := model.JSONTimestamp(time.Date(2025, time.March, 2, 12, 0, 0, 0, time.UTC))
+	g := model.Game{Status: model.GameStarted, Start: &start}
+	assert.Equal(t, model.GameStarted, g.StatusAt(time.Time(start).Add(model.AbandonAfter)))
+	assert.Equal(t, model.GameAbandoned, g.StatusAt(time.Time(start).Add(model.AbandonAfter+time.Second)))
+	g.Status = model.GameSolved
+	assert.Equal(t, model.GameSolved, g.StatusAt(time.Time(start).Add(model.AbandonAfter+time.Second)), "finished games are never abandoned")
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window is the period a rating counts the solves of, the zero window is all time.
+type Window int
+
+const (
+	AllTime Window = iota
+	Day
+	Week // ISO week starting on Monday
+	Month
+)
+
+// Windows are ordered from the longest one.
+var Windows = []Window{AllTime, Day, Week, Month}
+
+var windowNames = map[Window]string{AllTime: "all", Day: "day", Week: "week", Month: "month"}
+
+func ParseWindow(s string) (Window, error) {
+	for w, name := range windowNames {
+		if name == s {
+			return w, nil
+		}
+	}
+	return AllTime, fmt.Errorf("parse window %q: unknown", s)
+}
+
+func (w Window) String() string {
+	if name, ok := windowNames[w]; ok {
+		return name
+	}
+	return fmt.Sprintf("window(%d)", int(w))
+}
+
+// Start returns the beginning of the window containing t in the location of t, all time has no beginning.
+func (w Window) Start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch w {
+	case Day:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	case Week:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case Month:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Time{}
+	}
+}
+
+// Next returns the window following w in Windows, the first one follows the last.
+func (w Window) Next() Window {
+	for i := range Windows {
+		if Windows[i] == w {
+			return Windows[(i+1)%len(Windows)]
+		}
+	}
+	return Windows[0]
+}
+
+func (w Window) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+func (w *Window) UnmarshalText(text []byte) error {
+	v, err := ParseWindow(string(text))
+	if err != nil {
+		return err
+	}
+	*w = v
+	return nil
+}
@@ -0,0 +1,32 @@
+package model_test
+
+import (
+	"15-puzzle/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowStart(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	// Sunday late evening in the zone is Sunday afternoon in UTC
+	now := time.Date(2025, time.March, 2, 23, 30, 0, 0, loc)
+	assert.Equal(t, time.Date(2025, time.March, 2, 0, 0, 0, 0, loc), model.Day.Start(now))
+	assert.Equal(t, time.Date(2025, time.February, 24, 0, 0, 0, 0, loc), model.Week.Start(now), "ISO week starts on Monday")
+	assert.Equal(t, time.Date(2025, time.March, 1, 0, 0, 0, 0, loc), model.Month.Start(now))
+	assert.True(t, model.AllTime.Start(now).IsZero())
+	assert.Equal(t, time.Date(2025, time.March, 3, 0, 0, 0, 0, loc), model.Week.Start(now.Add(time.Hour)), "the week rolls over at midnight")
+}
+
+func TestParseWindow(t *testing.T) {
+	for _, w := range model.Windows {
+		parsed, err := model.ParseWindow(w.String())
+		assert.NoError(t, err)
+		assert.Equal(t, w, parsed)
+	}
+	_, err := model.ParseWindow("year")
+	assert.Error(t, err)
+	assert.Equal(t, model.Day, model.AllTime.Next())
+	assert.Equal(t, model.AllTime, model.Month.Next())
+}
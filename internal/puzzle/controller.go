@@ -1,6 +1,9 @@
 package puzzle
 
 import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/solver"
 	"bytes"
 	_ "embed"
 	"encoding/hex"
@@ -63,6 +66,7 @@ const (
 	screenForm
 	screenSplash
 	screenDebug
+	screenReplay
 )
 
 type ticker int
@@ -102,6 +106,19 @@ type Handler interface {
 	Activate()
 }
 
+// dragHandler is a screen handling drags of the pointer from one symbol to another,
+// the last call of a drag is made on release and is expected to act as a tap if the drag means nothing.
+type dragHandler interface {
+	Drag(a Audio, from, to image.Point, t time.Duration, release bool) actionResult
+}
+
+// gesture is a press of the mouse button or a touch.
+type gesture struct {
+	start    time.Time
+	from     image.Point
+	dragging bool
+}
+
 type Controller struct {
 	screen  image.Rectangle
 	bgColor color.Color
@@ -111,17 +128,29 @@ type Controller struct {
 	activeState  atomic.Bool
 	activeScreen screen
 	screens      map[screen]Handler
+	replayed     atomic.Pointer[board.Recording] // responded to the replay request, played back by the next update
 
 	debugFn func(string)
 
-	btnPressed        time.Time
-	touchTapped       map[ebiten.TouchID]time.Time
-	OnGameStart       func()
-	OnGameSolve       func(int)
+	btnPressed        gesture
+	touchTapped       map[ebiten.TouchID]*gesture
+	OnGameStart       func(lb model.Leaderboard, daily bool, respond func(model.ApiResponse)) // the server chooses the scramble of the game, scrambled locally when nil
+	OnGameSolve       func(model.Leaderboard, model.Solve)
 	InfoRequest       func()
-	UserStatsRequest  func()
+	UserStatsRequest  func(model.Leaderboard)
+	DailyRequest      func(model.Leaderboard)
+	RankRequest       func(model.Leaderboard, model.Window)
+	ReplayRequest     func(lb model.Leaderboard, rank int) // the best game of the player at the rank
 	MonitoringRequest func(string)
 	UrlOpener         func(string)
+	Replay            *board.Recording // played back at start instead of the splash screen
+	KeyBindings       KeyBindings
+	Gamepad           bool // gamepads are read along with the mouse, touches and keys
+	GamepadBindings   GamepadBindings
+	Heuristic         solver.Heuristic // searches the hints, LinearConflict when nil
+
+	gamepadIDs []ebiten.GamepadID
+	sticks     map[ebiten.GamepadID]KeyAction // the move of every tilted stick
 
 	audioCtx *audio.Context
 	player   *audio.Player
@@ -138,10 +167,28 @@ func Init(init ...func(*Controller)) error {
 	for i := range init {
 		init[i](c)
 	}
-	c.screens[screenGame] = newGame(c.OnGameStart, c.OnGameSolve, c.UserStatsRequest)
+	var onStart func(model.Leaderboard, bool, func(*model.Game, *string))
+	if c.OnGameStart != nil {
+		onStart = func(lb model.Leaderboard, daily bool, started func(*model.Game, *string)) {
+			c.OnGameStart(lb, daily, func(u model.ApiResponse) {
+				started(u.Game, u.Session)
+				c.ApiResponseHandler(u)
+			})
+		}
+	}
+	g := newGame(onStart, c.OnGameSolve, c.UserStatsRequest, c.DailyRequest, c.RankRequest)
+	g.heuristic = c.Heuristic
+	g.requestReplay = c.ReplayRequest
+	c.screens[screenGame] = g
 	c.screens[screenForm] = newStats(c.MonitoringRequest)
 	c.screens[screenSplash] = newSplash(c.UrlOpener)
 	c.screens[screenDebug], c.debugFn = newDebugOverlay()
+	replay := newReplay()
+	c.screens[screenReplay] = replay
+	if c.Replay != nil {
+		replay.Load(*c.Replay)
+		c.activeScreen = screenReplay
+	}
 
 	c.SetLangCode(langCodeEn)
 
@@ -169,16 +216,21 @@ func newController() (*Controller, error) {
 		font:         tfs,
 		activeScreen: screenSplash,
 		screens:      make(map[screen]Handler),
-		touchTapped:  make(map[ebiten.TouchID]time.Time),
+		touchTapped:  make(map[ebiten.TouchID]*gesture),
+		sticks:       make(map[ebiten.GamepadID]KeyAction),
 		player:       player,
 		audioCtx:     audioCtx,
 		activeState:  atomic.Bool{},
 	}
-	p.OnGameStart = func() {}
-	p.OnGameSolve = func(int) {}
+	p.OnGameSolve = func(model.Leaderboard, model.Solve) {}
 	p.InfoRequest = func() {}
-	p.UserStatsRequest = func() {}
+	p.UserStatsRequest = func(model.Leaderboard) {}
+	p.DailyRequest = func(model.Leaderboard) {}
+	p.RankRequest = func(model.Leaderboard, model.Window) {}
+	p.ReplayRequest = func(model.Leaderboard, int) {}
 	p.MonitoringRequest = func(string) {}
+	p.KeyBindings = DefaultKeyBindings()
+	p.GamepadBindings = DefaultGamepadBindings()
 	p.UrlOpener = func(url string) {
 		if err := openURL(url); err != nil {
 			p.Debug("url open error: %v", err)
@@ -212,6 +264,11 @@ func (c *Controller) tick() {
 
 func (c *Controller) SetActive(active bool) {
 	c.activeState.Store(active)
+	for _, h := range c.screens {
+		if i, ok := h.(interface{ SetActive(bool) }); ok {
+			i.SetActive(active)
+		}
+	}
 }
 
 func (c *Controller) TickEvent(t ticker) {
@@ -224,31 +281,57 @@ func (c *Controller) Update() error {
 	}
 	switch {
 	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButton0):
-		c.btnPressed = time.Now()
+		c.btnPressed = gesture{start: time.Now(), from: c.symbolAt(ebiten.CursorPosition())}
 	case inpututil.IsMouseButtonJustReleased(ebiten.MouseButton0):
 		x, y := ebiten.CursorPosition()
-		c.interact(x, y, time.Since(c.btnPressed))
-		c.btnPressed = time.Time{}
+		c.release(&c.btnPressed, x, y)
+		c.btnPressed = gesture{}
+	case ebiten.IsMouseButtonPressed(ebiten.MouseButton0) && !c.btnPressed.start.IsZero():
+		x, y := ebiten.CursorPosition()
+		c.move(&c.btnPressed, x, y)
 	}
 
 	for _, id := range inpututil.AppendJustPressedTouchIDs(make([]ebiten.TouchID, 0)) {
-		c.touchTapped[id] = time.Now()
+		c.touchTapped[id] = &gesture{start: time.Now(), from: c.symbolAt(ebiten.TouchPosition(id))}
 	}
 
 	for id, v := range c.touchTapped {
-		if v.IsZero() || v.Add(time.Second*5).Before(time.Now()) {
+		if v.start.IsZero() || v.start.Add(time.Second*5).Before(time.Now()) {
 			delete(c.touchTapped, id)
 		}
 	}
 
+	for _, id := range ebiten.AppendTouchIDs(make([]ebiten.TouchID, 0)) {
+		if v, ok := c.touchTapped[id]; ok {
+			x, y := ebiten.TouchPosition(id)
+			c.move(v, x, y)
+		}
+	}
+
 	for _, id := range inpututil.AppendJustReleasedTouchIDs(make([]ebiten.TouchID, 0)) {
 		if v, ok := c.touchTapped[id]; ok {
 			x, y := inpututil.TouchPositionInPreviousTick(id)
-			c.interact(x, y, time.Since(v))
+			c.release(v, x, y)
 		}
 		delete(c.touchTapped, id)
 	}
 
+	c.screens[screenGame].(*game).serve()
+	if rec := c.replayed.Swap(nil); rec != nil {
+		c.screens[screenReplay].(*replay).Load(*rec)
+		c.switchScreen(screenReplay)
+	}
+
+	for _, k := range inpututil.AppendJustPressedKeys(make([]ebiten.Key, 0)) {
+		if action, ok := c.KeyBindings[k]; ok {
+			c.press(action)
+		}
+	}
+
+	if c.Gamepad {
+		c.updateGamepads()
+	}
+
 	return nil
 }
 
@@ -275,11 +358,50 @@ func (c *Controller) PlaySound() {
 	c.player.Play()
 }
 
-func (c *Controller) interact(x, y int, t time.Duration) {
+// symbolAt returns the column and the row of the symbol at the screen point.
+func (c *Controller) symbolAt(x, y int) image.Point {
 	col := int(math.Floor(float64(x-c.screen.Min.X) / (float64(puzzleSymW) * (float64(c.screen.Bounds().Dx()) / float64(c.scr.Bounds().Dx())))))
 	row := int(math.Floor(float64(y-c.screen.Min.Y) / (float64(puzzleSymH) * (float64(c.screen.Bounds().Dy()) / float64(c.scr.Bounds().Dy())))))
+	return image.Point{col, row}
+}
+
+func (c *Controller) interact(x, y int, t time.Duration) {
+	p := c.symbolAt(x, y)
+	c.apply(c.screens[c.activeScreen].Interact(c, p.X, p.Y, t))
+}
+
+// move turns the gesture into a drag once the pointer leaves the symbol it was pressed at, if the active screen handles drags.
+func (c *Controller) move(g *gesture, x, y int) {
+	h, ok := c.screens[c.activeScreen].(dragHandler)
+	if !ok {
+		return
+	}
+	to := c.symbolAt(x, y)
+	if g.dragging = g.dragging || to != g.from; g.dragging {
+		c.apply(h.Drag(c, g.from, to, time.Since(g.start), false))
+	}
+}
+
+// release ends the gesture as a drag or a tap.
+func (c *Controller) release(g *gesture, x, y int) {
+	if h, ok := c.screens[c.activeScreen].(dragHandler); ok && g.dragging {
+		c.apply(h.Drag(c, g.from, c.symbolAt(x, y), time.Since(g.start), true))
+		return
+	}
+	c.interact(x, y, time.Since(g.start))
+}
+
+// press passes the key action to the active screen if it handles keys.
+func (c *Controller) press(action KeyAction) {
+	if h, ok := c.screens[c.activeScreen].(interface {
+		Press(Audio, KeyAction) actionResult
+	}); ok {
+		c.apply(h.Press(c, action))
+	}
+}
 
-	switch c.screens[c.activeScreen].Interact(c, col, row, t) {
+func (c *Controller) apply(result actionResult) {
+	switch result {
 	case resultSwitchDebug:
 		c.screens[screenDebug].Activate()
 		fallthrough
@@ -293,7 +415,7 @@ func (c *Controller) interact(x, y int, t time.Duration) {
 }
 
 func (c *Controller) switchScreen(s screen) {
-	c.btnPressed = time.Time{}
+	c.btnPressed = gesture{}
 	c.activeScreen = s
 	c.screens[c.activeScreen].Activate()
 }
@@ -0,0 +1,72 @@
+package puzzle
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// stickThreshold is how far the stick is tilted to make a move, the stick returns to the center before the next one.
+const stickThreshold = 0.5
+
+// GamepadBindings maps the buttons of gamepads with the standard layout to key actions.
+type GamepadBindings map[ebiten.StandardGamepadButton]KeyAction
+
+// DefaultGamepadBindings are the D-pad for moves, A or Start for a new game, Y to mute,
+// X or the left bumper to undo, B or the right bumper to redo and Back to switch the screen.
+func DefaultGamepadBindings() GamepadBindings {
+	return GamepadBindings{
+		ebiten.StandardGamepadButtonLeftTop:       KeyMoveUp,
+		ebiten.StandardGamepadButtonLeftBottom:    KeyMoveDown,
+		ebiten.StandardGamepadButtonLeftLeft:      KeyMoveLeft,
+		ebiten.StandardGamepadButtonLeftRight:     KeyMoveRight,
+		ebiten.StandardGamepadButtonRightBottom:   KeyNewGame,
+		ebiten.StandardGamepadButtonCenterRight:   KeyNewGame,
+		ebiten.StandardGamepadButtonRightTop:      KeyMute,
+		ebiten.StandardGamepadButtonRightLeft:     KeyUndo,
+		ebiten.StandardGamepadButtonFrontTopLeft:  KeyUndo,
+		ebiten.StandardGamepadButtonRightRight:    KeyRedo,
+		ebiten.StandardGamepadButtonFrontTopRight: KeyRedo,
+		ebiten.StandardGamepadButtonCenterLeft:    KeyScreen,
+	}
+}
+
+// updateGamepads passes the pressed buttons and the tilts of the left stick to the active screen as key actions.
+func (c *Controller) updateGamepads() {
+	c.gamepadIDs = ebiten.AppendGamepadIDs(c.gamepadIDs[:0])
+	for _, id := range c.gamepadIDs {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for b, action := range c.GamepadBindings {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, b) {
+				c.press(action)
+			}
+		}
+		action, tilted := stickAction(id)
+		prev, held := c.sticks[id]
+		switch {
+		case !tilted:
+			delete(c.sticks, id)
+		case !held || prev != action:
+			c.sticks[id] = action
+			c.press(action)
+		}
+	}
+}
+
+func stickAction(id ebiten.GamepadID) (KeyAction, bool) {
+	x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+	y := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+	switch {
+	case x*x+y*y < stickThreshold*stickThreshold:
+		return 0, false
+	case x*x >= y*y && x > 0:
+		return KeyMoveRight, true
+	case x*x >= y*y:
+		return KeyMoveLeft, true
+	case y > 0:
+		return KeyMoveDown, true
+	default:
+		return KeyMoveUp, true
+	}
+}
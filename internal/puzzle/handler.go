@@ -8,10 +8,17 @@ func (c *Controller) ApiResponseHandler(u model.ApiResponse) {
 		c.ApiErrorHandler(*u.Err)
 	case u.Info != nil:
 		c.ApiInfoHandler(*u.Info)
+	case u.Daily != nil:
+		c.ApiDailyHandler(*u.Daily)
+		if u.Stats != nil {
+			c.ApiStatsHandler(*u.Stats)
+		}
 	case u.Stats != nil:
 		c.ApiStatsHandler(*u.Stats)
 	case u.Monitoring != nil:
 		c.ApiMonitoringHandler(*u.Monitoring)
+	case u.Replay != nil:
+		c.ApiReplayHandler(*u.Replay)
 	}
 }
 
@@ -23,6 +30,14 @@ func (c *Controller) ApiInfoHandler(s model.Info) {
 	}
 }
 
+func (c *Controller) ApiDailyHandler(d model.Daily) {
+	for _, h := range c.screens {
+		if i, ok := h.(interface{ ApiDailyHandler(model.Daily) }); ok {
+			i.ApiDailyHandler(d)
+		}
+	}
+}
+
 func (c *Controller) ApiStatsHandler(s model.Stats) {
 	for _, h := range c.screens {
 		if i, ok := h.(interface{ ApiStatsHandler(model.Stats) }); ok {
@@ -39,6 +54,11 @@ func (c *Controller) ApiMonitoringHandler(m model.Monitoring) {
 	}
 }
 
+// ApiReplayHandler plays the game back on the replay screen.
+func (c *Controller) ApiReplayHandler(r model.Replay) {
+	c.replayed.Store(&r.Recording)
+}
+
 func (c *Controller) ApiErrorHandler(e string) {
 	c.Debug("api error: %s", e)
 }
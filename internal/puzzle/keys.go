@@ -0,0 +1,108 @@
+package puzzle
+
+import (
+	"15-puzzle/internal/board"
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// KeyAction is what a key does on the game screen, moves are named by the direction the tile slides into the blank.
+type KeyAction int
+
+const (
+	KeyMoveUp KeyAction = iota
+	KeyMoveDown
+	KeyMoveLeft
+	KeyMoveRight
+	KeyNewGame
+	KeyMute
+	KeyUndo
+	KeyRedo
+	KeyScreen // switches between the game and the statistics screens
+	KeyReplay // plays back the best game of the leader of the leaderboard
+)
+
+var keyActionNames = map[KeyAction]string{
+	KeyMoveUp:    "up",
+	KeyMoveDown:  "down",
+	KeyMoveLeft:  "left",
+	KeyMoveRight: "right",
+	KeyNewGame:   "new",
+	KeyMute:      "mute",
+	KeyUndo:      "undo",
+	KeyRedo:      "redo",
+	KeyScreen:    "screen",
+	KeyReplay:    "replay",
+}
+
+func (a KeyAction) String() string {
+	if name, ok := keyActionNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("action(%d)", int(a))
+}
+
+var keyMoves = map[KeyAction]board.Direction{KeyMoveUp: board.Up, KeyMoveDown: board.Down, KeyMoveLeft: board.Left, KeyMoveRight: board.Right}
+
+// KeyBindings maps keys to their actions, several keys may share an action.
+type KeyBindings map[ebiten.Key]KeyAction
+
+// DefaultKeyBindings are the arrows, WASD and vim keys for moves,
+// N or Enter for a new game, M to mute, U or Backspace to undo, R to redo, Escape to switch the screen
+// and P to play back the leader's game.
+func DefaultKeyBindings() KeyBindings {
+	return KeyBindings{
+		ebiten.KeyArrowUp:    KeyMoveUp,
+		ebiten.KeyW:          KeyMoveUp,
+		ebiten.KeyK:          KeyMoveUp,
+		ebiten.KeyArrowDown:  KeyMoveDown,
+		ebiten.KeyS:          KeyMoveDown,
+		ebiten.KeyJ:          KeyMoveDown,
+		ebiten.KeyArrowLeft:  KeyMoveLeft,
+		ebiten.KeyA:          KeyMoveLeft,
+		ebiten.KeyH:          KeyMoveLeft,
+		ebiten.KeyArrowRight: KeyMoveRight,
+		ebiten.KeyD:          KeyMoveRight,
+		ebiten.KeyL:          KeyMoveRight,
+		ebiten.KeyN:          KeyNewGame,
+		ebiten.KeyEnter:      KeyNewGame,
+		ebiten.KeyM:          KeyMute,
+		ebiten.KeyU:          KeyUndo,
+		ebiten.KeyBackspace:  KeyUndo,
+		ebiten.KeyR:          KeyRedo,
+		ebiten.KeyEscape:     KeyScreen,
+		ebiten.KeyP:          KeyReplay,
+	}
+}
+
+// Bind parses comma separated key=action pairs, e.g. "I=up,Space=new", and binds the keys over the existing bindings.
+// Key names are the ones of ebiten.Key, action names are up, down, left, right, new, mute, undo, redo, screen and replay.
+func (kb KeyBindings) Bind(s string) error {
+	for _, pair := range strings.Split(s, ",") {
+		name, action, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fmt.Errorf("parse key binding %q: no action", pair)
+		}
+		var k ebiten.Key
+		if err := k.UnmarshalText([]byte(name)); err != nil {
+			return fmt.Errorf("parse key binding %q: %s", pair, err)
+		}
+		a, err := parseKeyAction(action)
+		if err != nil {
+			return fmt.Errorf("parse key binding %q: %s", pair, err)
+		}
+		kb[k] = a
+	}
+	return nil
+}
+
+func parseKeyAction(s string) (KeyAction, error) {
+	for a, name := range keyActionNames {
+		if name == s {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown action %q", s)
+}
@@ -1,5 +1,10 @@
 package puzzle
 
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/model"
+)
+
 type langCode string
 
 const (
@@ -51,6 +56,34 @@ func l10nMoves(lc langCode) string {
 	}
 }
 
+func l10nMetric(lc langCode, m board.Metric) string {
+	switch lc {
+	case langCodeRu:
+		if m == board.MultiTile {
+			return "Рядами"
+		}
+		return "По одной"
+	case langCodeEn:
+		fallthrough
+	default:
+		if m == board.MultiTile {
+			return "Lines"
+		}
+		return "Single tiles"
+	}
+}
+
+func l10nDaily(lc langCode) string {
+	switch lc {
+	case langCodeRu:
+		return "День"
+	case langCodeEn:
+		fallthrough
+	default:
+		return "Daily"
+	}
+}
+
 func l10nRank(lc langCode) string {
 	switch lc {
 	case langCodeRu:
@@ -62,6 +95,33 @@ func l10nRank(lc langCode) string {
 	}
 }
 
+// l10nWindow names the rank of the window, the all time one is just the rank.
+func l10nWindow(lc langCode, w model.Window) string {
+	switch lc {
+	case langCodeRu:
+		switch w {
+		case model.Day:
+			return "День"
+		case model.Week:
+			return "Неделя"
+		case model.Month:
+			return "Месяц"
+		}
+	case langCodeEn:
+		fallthrough
+	default:
+		switch w {
+		case model.Day:
+			return "Today"
+		case model.Week:
+			return "Week"
+		case model.Month:
+			return "Month"
+		}
+	}
+	return l10nRank(lc)
+}
+
 func l10nWins(lc langCode) string {
 	switch lc {
 	case langCodeRu:
@@ -72,3 +132,14 @@ func l10nWins(lc langCode) string {
 		return "Wins"
 	}
 }
+
+func l10nOptimal(lc langCode) string {
+	switch lc {
+	case langCodeRu:
+		return "опт"
+	case langCodeEn:
+		fallthrough
+	default:
+		return "opt"
+	}
+}
@@ -1,36 +1,106 @@
 package puzzle
 
 import (
+	"15-puzzle/internal/board"
 	"15-puzzle/internal/model"
-	"errors"
+	"15-puzzle/internal/solver"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"math/rand/v2"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 const (
-	tiles     = 16
-	fieldSymX = 29
-	fieldSymY = 12
+	fieldSymX            = 29
+	fieldSymY            = 12
+	sizeSelectorTemplate = ` %d x %d `
+	tierTemplate         = `[%-4s]`
+	hintTemplate         = `[?%d]`
+	hintsPerGame         = 3
+	hintNodeLimit        = 100000
+	hintTimeout          = time.Second * 10
+	undoTemplate         = `[<]`
+	redoTemplate         = `[>]`
+	flickTime            = time.Millisecond * 250
+	metricTemplate       = ` %s `
 )
 
 var (
-	checkbox = map[bool]string{true: "[x]", false: "[ ]"}
-	frames   = [...]byte{'|', '/', '-', '\\'}
+	checkbox         = map[bool]string{true: "[x]", false: "[ ]"}
+	frames           = [...]byte{'|', '/', '-', '\\'}
+	sizeSelectorRect = image.Rect((puzzleSymX-len(sizeSelectorTemplate)+2)/2, puzzleSymY-1, (puzzleSymX+len(sizeSelectorTemplate)-2)/2, puzzleSymY)
+	hintRect         = image.Rect((puzzleSymX-len(hintTemplate)+1)/2, 1, (puzzleSymX+len(hintTemplate)-1)/2, 2)
+	undoRect         = image.Rect(2, puzzleSymY-1, 2+len(undoTemplate), puzzleSymY)
+	redoRect         = image.Rect(puzzleSymX-2-len(redoTemplate), puzzleSymY-1, puzzleSymX-2, puzzleSymY)
+	tierRect         = image.Rect(puzzleSymX-2-len(fmt.Sprintf(tierTemplate, "")), puzzleSymY-1, puzzleSymX-2, puzzleSymY)
+	metricRow        = 2
+	timerPoint       = image.Point{2, 2}
+	tierMarks        = map[board.Tier]string{board.Easy: "*", board.Medium: "**", board.Hard: "***", board.Expert: "****"}
 )
 
+// prepared is a scramble generated in the background for the next game.
+type prepared struct {
+	scramble board.Scramble
+	tier     board.Tier
+	optimal  int
+}
+
+// dragged are the tiles between the dragged one and the blank, there are none when the board is swiped.
+type dragged struct {
+	from  image.Point
+	d     board.Direction
+	n     int
+	tiles []*tile
+}
+
+// hint is the tile to move next, it is only valid for the board it was computed for.
+type hint struct {
+	board board.Board
+	tile  byte
+}
+
 type game struct {
 	langCode     langCode
-	tiles        [tiles]*tile
-	moves        int
+	board        board.Board
+	scramble     board.Scramble
+	daily        bool
+	tier         board.Tier   // selected for the next games
+	played       board.Tier   // of the current game
+	metric       board.Metric // counts the moves and selects the leaderboard of the games
+	window       model.Window // the period of the rank shown after the game, except of the daily challenge
+	optimal      int          // solution length of the current game scramble, zero when unknown
+	layout       layout
+	tiles        []*tile
+	drag         *dragged
+	history      board.History // every slide counts as a move, including undo and redo
 	solved       bool
 	muted        bool
-	onStart      func()
-	onSolve      func(int)
-	requestStats func()
+	onStart      func(lb model.Leaderboard, daily bool, started func(*model.Game, *string)) // asks the server for the game, nil when scrambled locally
+	onSolve      func(model.Leaderboard, model.Solve)
+	requestStats func(model.Leaderboard)
+	requestDaily func(model.Leaderboard)
+	requestRank  func(model.Leaderboard, model.Window)
+	// requestReplay asks for the best game of the player at the rank of the leaderboard
+	requestReplay func(model.Leaderboard, int)
+
+	dailyInfo atomic.Pointer[model.Daily]
+
+	session  string                       // the token of the current game told by the server, its solve is sent with it
+	starting atomic.Pointer[startRequest] // the game asked from the server, the input waits for it
+	served   atomic.Pointer[servedGame]   // responded to the start request, played by the next update
+
+	next          atomic.Pointer[prepared]
+	cancelPrepare context.CancelFunc
+
+	hints       int
+	heuristic   solver.Heuristic
+	hint        atomic.Pointer[hint]
+	hintPending atomic.Bool
+	cancelHint  context.CancelFunc
 
 	stats     atomic.Value
 	blinkCoef []float64
@@ -39,37 +109,73 @@ type game struct {
 	color [fieldSymX][fieldSymY]color.RGBA
 }
 
-func newGame(onStart func(), onSolve func(int), request func()) *game {
+// startRequest is the game of the leaderboard or of the challenge of the day asked from the server.
+type startRequest struct {
+	lb    model.Leaderboard
+	daily bool
+}
+
+// servedGame is the response to the start request, the game is nil when the request failed.
+type servedGame struct {
+	request *startRequest
+	game    *model.Game
+	session *string
+}
+
+func newGame(onStart func(model.Leaderboard, bool, func(*model.Game, *string)), onSolve func(model.Leaderboard, model.Solve), request func(model.Leaderboard), requestDaily func(model.Leaderboard),
+	requestRank func(model.Leaderboard, model.Window)) *game {
 	p := &game{
 		langCode:     langCodeEn,
-		solved:       true,
 		onStart:      onStart,
 		onSolve:      onSolve,
 		requestStats: request,
+		requestDaily: requestDaily,
+		requestRank:  requestRank,
 		blinkCoef:    []float64{1, .8, .6, .4, .2, 0, 0, .2, .4, .6, .8, 1},
 	}
 
-	for i := range tiles {
-		var pos int
-		if i == 0 {
-			pos = tiles - 1
-		} else {
-			pos = i - 1
-		}
-		p.tiles[i] = NewTile(i, pos)
-	}
-
 	for x := range fieldSymX {
 		for y := range fieldSymY {
 			p.blink[x][y] = rand.IntN(len(frames))
 		}
 	}
 
-	p.requestStats()
+	p.setSize(board.Classic)
 
 	return p
 }
 
+func (g *game) setSize(s board.Size) {
+	g.board = board.Solved(s)
+	g.layout = newLayout(s)
+	g.tiles = make([]*tile, s.Tiles())
+	for i := range g.tiles {
+		g.tiles[i] = NewTile(i, func() int { return g.board.Pos(byte(i)) }, g.layout)
+	}
+	g.history = board.NewHistory(g.board)
+	g.drag = nil
+	g.solved = true
+	g.resetHints()
+	g.refreshStats()
+	g.prepare()
+}
+
+func (g *game) refreshStats() {
+	switch {
+	case g.daily:
+		g.requestDaily(g.leaderboard(board.Expert))
+	case g.window != model.AllTime:
+		g.requestRank(g.leaderboard(g.tier), g.window)
+	default:
+		g.requestStats(g.leaderboard(g.tier))
+	}
+}
+
+// leaderboard is the ranking of the board size and the move metric for the tier.
+func (g *game) leaderboard(t board.Tier) model.Leaderboard {
+	return model.Leaderboard{Size: g.board.Size(), Tier: t, Metric: g.metric}
+}
+
 func (g *game) Tick(t ticker) {
 	if t == ticker10Hz && g.solved {
 		b := g.blinkCoef[0]
@@ -92,19 +198,30 @@ func (g *game) SetLang(lc langCode) {
 	g.langCode = lc
 }
 
+// ApiStatsHandler keeps the stats, the all time ones responded to a game action are followed by the ones of the selected window.
 func (g *game) ApiStatsHandler(s model.Stats) {
 	g.stats.Store(s)
+	if s.Daily == "" && s.Window == model.AllTime && g.window != model.AllTime {
+		g.refreshStats()
+	}
+}
+
+func (g *game) ApiDailyHandler(d model.Daily) {
+	g.dailyInfo.Store(&d)
 }
 
 func (g *game) Draw(s Screen) {
 	drawGameField(s)
 	if g.isSolved() {
-		rating := l10nRank(g.langCode) + ": "
+		rating := g.rankTitle() + ": "
 		wins := l10nWins(g.langCode) + ": "
 		if !g.withStats(func(s model.Stats) {
-			if s.Rank > 0 && s.GamesSolved > 0 {
+			switch {
+			case s.Rank > 0 && s.GamesSolved > 0:
 				rating += fmt.Sprint(s.Rank)
-			} else {
+			case g.window != model.AllTime && !g.daily:
+				rating += "-" // the selected window is shown for the next tap to change it
+			default:
 				rating = ""
 			}
 			wins += fmt.Sprint(s.GamesSolved)
@@ -113,11 +230,18 @@ func (g *game) Draw(s Screen) {
 		}
 		printHeader(s, rating, -1)
 		printHeader(s, wins, 1)
+		if g.optimal > 0 {
+			printHeader(s, fmt.Sprintf(l10nOptimal(g.langCode)+" %d", g.optimal), 0)
+		}
 	} else {
 		printHeader(s, fmt.Sprintf(l10nSilent(g.langCode)+" %s", checkbox[g.muted]), 1)
 		if !g.muted {
-			printHeader(s, fmt.Sprintf(l10nMoves(g.langCode)+": %d", g.moves), -1)
+			printHeader(s, fmt.Sprintf(l10nMoves(g.langCode)+": %d", g.metric.Count(g.history.Log())), -1)
+			clock := formatClock(g.history.Elapsed())
+			s.Fill(image.Rectangle{timerPoint, timerPoint.Add(image.Point{len(clock), 1})}, nil)
+			s.Print(clock, timerPoint, color.White)
 		}
+		printHeader(s, fmt.Sprintf(hintTemplate, hintsPerGame-g.hints), 0)
 	}
 
 	if g.showCongrats() {
@@ -129,50 +253,115 @@ func (g *game) Draw(s Screen) {
 		return
 	}
 
-	boardBottomColor := color.Black
-	// boardBottomColor := color.RGBA{0xFF, 0x00, 0xFF, 0xFF}
+	if g.solved {
+		s.Fill(sizeSelectorRect, nil)
+		s.Print(fmt.Sprintf(sizeSelectorTemplate, g.board.Size().W, g.board.Size().H), sizeSelectorRect.Min, color.White)
+		s.Fill(g.dailyRect(), nil)
+		s.Print(g.dailyTitle(), g.dailyRect().Min, color.White)
+		s.Fill(tierRect, nil)
+		s.Print(fmt.Sprintf(tierTemplate, tierMarks[g.nextTier()]), tierRect.Min, buttonColor(!g.daily))
+		s.Fill(g.metricRect(), nil)
+		s.Print(g.metricTitle(), g.metricRect().Min, color.White)
+	} else {
+		s.Fill(undoRect, nil)
+		s.Print(undoTemplate, undoRect.Min, buttonColor(g.history.CanUndo()))
+		s.Fill(redoRect, nil)
+		s.Print(redoTemplate, redoRect.Min, buttonColor(g.history.CanRedo()))
+	}
 
-	for i := range tiles {
+	var hintTile byte
+	if h := g.hint.Load(); h != nil && h.board == g.board && !g.solved {
+		hintTile = h.tile
+	}
+
+	for i := range g.tiles {
 		var tileBackground color.Color = color.RGBA{0, 0, 0xA0, 0xFF}    // background: 0x0000A0 (lighter) / 0x00006B (darker)
 		var tileForeground color.Color = color.RGBA{0, 0xFF, 0xFF, 0xFF} // text: cyan 0x00FFFF
-		fillRect := image.Rect(g.tiles[i].X(), g.tiles[i].Y(), g.tiles[i].X()+puzzleTileSymW, g.tiles[i].Y()+puzzleTileSymH)
-		if i == 0 {
-			if g.solved {
-				cr, cg, cb, ca := tileForeground.RGBA()
-				tileForeground = color.RGBA{byte(g.blinkCoef[0] * float64(cr)),
-					byte(g.blinkCoef[0] * float64(cg)),
-					byte(g.blinkCoef[0] * float64(cb)),
-					byte(g.blinkCoef[0] * float64(ca))}
-			} else {
-				tileBackground = boardBottomColor
-				fillRect.Max.X--
-			}
+		if i == 0 && g.solved {
+			cr, cg, cb, ca := tileForeground.RGBA()
+			tileForeground = color.RGBA{byte(g.blinkCoef[0] * float64(cr)),
+				byte(g.blinkCoef[0] * float64(cg)),
+				byte(g.blinkCoef[0] * float64(cb)),
+				byte(g.blinkCoef[0] * float64(ca))}
 		}
-		if g.tiles[i].moving {
-			// fillRect contains unshifted yet coordinates, time to fix font gallucinations when animating adjacent tiles
-			fillRect.Max.X--                   // right border: skip last column to justify on move
-			s.Fill(fillRect, boardBottomColor) // partial "board bottom" from both sides
-			fillRect.Max.X++                   // right border: restore
-			fillRect.Min.X--                   // left border: extend to 1 col left to justify on move
-		}
-		s.Fill(fillRect, tileBackground)
-		if i == 0 && g.solved || i > 0 {
-			s.Print(tileTemplate.Format(g.tiles[i].Title()), image.Point{g.tiles[i].X(), g.tiles[i].Y()}, tileForeground)
+		if i > 0 && byte(i) == hintTile {
+			tileBackground = color.RGBA{0xA0, 0xA0, 0, 0xFF}    // background: olive
+			tileForeground = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF} // text: white
 		}
+		g.tiles[i].Draw(s, tileBackground, tileForeground, i == 0 && !g.solved)
 	}
 }
 
+func buttonColor(enabled bool) color.Color {
+	if enabled {
+		return color.White
+	}
+	return color.Gray{0x80}
+}
+
 func (g *game) Interact(a Audio, col, row int, t time.Duration) (result actionResult) {
 	result = resultNone
+	if g.starting.Load() != nil {
+		return
+	}
 	if !g.isSolved() && row == 1 && col < puzzleSymX-2 && col > puzzleSymX-13 {
 		g.muted = !g.muted
 		return
 	}
+	if !g.solved && (image.Point{col, row}).In(hintRect) {
+		g.requestHint()
+		return
+	}
+	if !g.solved && (image.Point{col, row}).In(undoRect) {
+		g.undo(a)
+		return
+	}
+	if !g.solved && (image.Point{col, row}).In(redoRect) {
+		g.redo(a)
+		return
+	}
 	if g.showCongrats() {
-		g.moves = 0
+		g.history = board.NewHistory(g.board)
+		return
+	}
+	if g.isSolved() && !g.daily && row == 1 && col >= 2 && col < 2+utf8.RuneCountInString(g.rankTitle())+4 {
+		g.window = g.window.Next()
+		g.refreshStats()
+		return
+	}
+	if g.isSolved() && !g.daily && row == 1 && col >= puzzleSymX-2-utf8.RuneCountInString(l10nWins(g.langCode))-4 && col < puzzleSymX-2 {
+		g.requestReplay(g.leaderboard(g.tier), 1)
+		return
+	}
+	if g.solved && (image.Point{col, row}).In(g.dailyRect()) {
+		g.daily = !g.daily
+		g.refreshStats()
+		return
+	}
+	if g.solved && !g.daily && (image.Point{col, row}).In(tierRect) {
+		g.tier = g.tier.Next()
+		g.refreshStats()
+		g.prepare()
+		return
+	}
+	if g.solved && (image.Point{col, row}).In(g.metricRect()) {
+		g.metric = g.metric.Next()
+		g.refreshStats()
+		return
+	}
+	if g.solved && (image.Point{col, row}).In(sizeSelectorRect) {
+		s := g.board.Size()
+		if col-sizeSelectorRect.Min.X < sizeSelectorRect.Dx()/2 {
+			s.W = s.W%board.MaxSize + 1
+			s.W = max(s.W, board.MinSize)
+		} else {
+			s.H = s.H%board.MaxSize + 1
+			s.H = max(s.H, board.MinSize)
+		}
+		g.setSize(s)
 		return
 	}
-	for i := range tiles {
+	for i := range g.tiles {
 		if g.tiles[i].CanInteract(col, row) {
 			if g.tiles[i].num == 0 && t > time.Second*3 {
 				return resultSwitchForm
@@ -185,19 +374,266 @@ func (g *game) Interact(a Audio, col, row int, t time.Duration) (result actionRe
 	return
 }
 
+// Press handles the key action like a tap on the control of it, a move key slides the tile next to the blank.
+func (g *game) Press(a Audio, action KeyAction) actionResult {
+	if action != KeyScreen && g.starting.Load() != nil {
+		return resultNone
+	}
+	switch action {
+	case KeyScreen:
+		return resultSwitchForm
+	case KeyMute:
+		g.muted = !g.muted
+	case KeyNewGame:
+		if g.showCongrats() {
+			g.history = board.NewHistory(g.board)
+		} else if g.solved {
+			g.shuffle()
+		}
+	case KeyUndo:
+		if !g.solved {
+			g.undo(a)
+		}
+	case KeyRedo:
+		if !g.solved {
+			g.redo(a)
+		}
+	case KeyReplay:
+		if g.solved && !g.daily {
+			g.requestReplay(g.leaderboard(g.tier), 1)
+		}
+	default:
+		if d, ok := keyMoves[action]; ok && !g.solved && !g.sliding() {
+			g.slide(a, d, 1, func() { g.history.Push(d, 1) })
+		}
+	}
+	return resultNone
+}
+
+// Drag moves the tiles between the dragged one and the blank with the pointer, they slide on when released
+// past the middle or flicked and fall back otherwise. A swipe from elsewhere on the board slides the tile next to the blank.
+func (g *game) Drag(a Audio, from, to image.Point, t time.Duration, release bool) actionResult {
+	if g.starting.Load() != nil {
+		return resultNone
+	}
+	if g.solved || !from.In(g.layout.rect()) {
+		if release {
+			return g.Interact(a, to.X, to.Y, t)
+		}
+		return resultNone
+	}
+	if g.drag == nil || g.drag.from != from {
+		// a touch may be lost without release, its tiles fall back
+		if g.drag != nil {
+			for _, t := range g.drag.tiles {
+				t.Drag(g.drag.d, 0)
+			}
+		}
+		if g.sliding() {
+			g.drag = nil
+			return resultNone
+		}
+		g.drag = g.dragFrom(from)
+	}
+	dr := g.drag
+	f := g.layout.fraction(dr.d, to.Sub(from))
+	if !release {
+		for _, t := range dr.tiles {
+			t.Drag(dr.d, f)
+		}
+		return resultNone
+	}
+	g.drag = nil
+	if len(dr.tiles) == 0 {
+		if d, ok := g.layout.swipe(to.Sub(from)); ok {
+			g.slide(a, d, 1, func() { g.history.Push(d, 1) })
+			return resultNone
+		}
+		return g.Interact(a, to.X, to.Y, t)
+	}
+	if f >= .5 || (f > 0 && t < flickTime) {
+		g.slide(a, dr.d, dr.n, func() { g.history.Push(dr.d, dr.n) })
+	} else {
+		for _, t := range dr.tiles {
+			t.Drag(dr.d, 0)
+		}
+	}
+	return resultNone
+}
+
+// dragFrom returns the tiles to drag by the tile at the symbol p.
+func (g *game) dragFrom(p image.Point) *dragged {
+	for i := 1; i < len(g.tiles); i++ {
+		if !g.tiles[i].CanInteract(p.X, p.Y) {
+			continue
+		}
+		d, n, ok := g.board.Line(g.tiles[i].pos())
+		if !ok {
+			break
+		}
+		pos, _ := g.board.Source(d)
+		step := pos - g.board.Blank()
+		dr := &dragged{from: p, d: d, n: n}
+		for k := range n {
+			dr.tiles = append(dr.tiles, g.tiles[g.board.At(pos+step*k)])
+		}
+		return dr
+	}
+	return &dragged{from: p}
+}
+
+// rankTitle names the rank of the selected window, the daily challenge has no windows.
+func (g *game) rankTitle() string {
+	if g.daily {
+		return l10nRank(g.langCode)
+	}
+	return l10nWindow(g.langCode, g.window)
+}
+
+func (g *game) dailyTitle() string {
+	return fmt.Sprintf(l10nDaily(g.langCode)+" %s", checkbox[g.daily])
+}
+
+func (g *game) dailyRect() image.Rectangle {
+	return image.Rect(2, puzzleSymY-1, 2+utf8.RuneCountInString(g.dailyTitle()), puzzleSymY)
+}
+
+func (g *game) metricTitle() string {
+	return fmt.Sprintf(metricTemplate, l10nMetric(g.langCode, g.metric))
+}
+
+// metricRect is centered on the border under the header.
+func (g *game) metricRect() image.Rectangle {
+	w := utf8.RuneCountInString(g.metricTitle())
+	return image.Rect((puzzleSymX-w)/2, metricRow, (puzzleSymX+w)/2, metricRow+1)
+}
+
+// dailyScramble returns the challenge of the day told by the server, the local date is used when there is no response.
+func (g *game) dailyScramble(s board.Size) board.Scramble {
+	if d := g.dailyInfo.Load(); d != nil {
+		if sc, err := board.ParseScramble(d.Scramble); err == nil && sc.Size == s {
+			return sc
+		}
+	}
+	return board.Daily(s, time.Now())
+}
+
+// nextTier is the tier of the next game, the daily challenge is a random position.
+func (g *game) nextTier() board.Tier {
+	if g.daily {
+		return board.Expert
+	}
+	return g.tier
+}
+
+// prepare generates the scramble of the next game in the background, it takes a search to prove the optimal solution length.
+// The games started from the server are of its scrambles.
+func (g *game) prepare() {
+	if g.onStart != nil {
+		return
+	}
+	if g.cancelPrepare != nil {
+		g.cancelPrepare()
+	}
+	g.next.Store(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancelPrepare = cancel
+	go func(s board.Size, t board.Tier) {
+		sc, optimal, err := solver.Scramble(ctx, s, t)
+		if err == nil && ctx.Err() == nil {
+			g.next.Store(&prepared{scramble: sc, tier: t, optimal: optimal})
+		}
+	}(g.board.Size(), g.tier)
+}
+
+// shuffle starts a new game, a tier game waits for the scramble to be prepared.
+// The game of the server is asked from it and the input waits for the response.
 func (g *game) shuffle() {
-	rand.Shuffle(tiles, func(i, j int) { g.tiles[i].pos, g.tiles[j].pos = g.tiles[j].pos, g.tiles[i].pos })
-	var puzzle [tiles]byte
-	for i := range g.tiles {
-		puzzle[i] = byte(g.tiles[i].pos)
+	if g.onStart != nil {
+		req := &startRequest{lb: g.leaderboard(g.nextTier()), daily: g.daily}
+		g.starting.Store(req)
+		g.onStart(req.lb, req.daily, func(game *model.Game, session *string) {
+			if g.starting.Load() == req {
+				g.served.Store(&servedGame{request: req, game: game, session: session})
+			}
+		})
+		return
 	}
-	if solvable, err := IsSolvable(puzzle); err == nil && !solvable {
-		g.tiles[1].pos, g.tiles[2].pos = g.tiles[2].pos, g.tiles[1].pos
+	if g.daily {
+		g.scramble, g.played, g.optimal = g.dailyScramble(g.board.Size()), board.Expert, 0
+	} else if next := g.next.Load(); next != nil && next.scramble.Size == g.board.Size() && next.tier == g.tier {
+		g.scramble, g.played, g.optimal = next.scramble, next.tier, next.optimal
+		g.prepare()
+	} else {
+		return
 	}
-	g.moves = 0
+	g.begin()
+}
+
+// serve plays the game served for the start request, a response to other request is dropped.
+// The board stays solved for a new game when the request failed.
+func (g *game) serve() {
+	served := g.served.Swap(nil)
+	if served == nil || !g.starting.CompareAndSwap(served.request, nil) || served.game == nil || served.session == nil {
+		return
+	}
+	lb := served.request.lb
+	sc, err := board.ParseScramble(served.game.Scramble)
+	if err != nil || sc.Size != lb.Size || served.game.Tier != lb.Tier || served.game.Metric != lb.Metric {
+		return
+	}
+	g.scramble, g.played, g.optimal, g.session = sc, lb.Tier, served.game.Optimal, *served.session
+	g.begin()
+}
+
+// begin plays the scramble from its start position.
+func (g *game) begin() {
+	g.board = g.scramble.Board()
+	g.history = board.NewHistory(g.board)
+	g.drag = nil
 	g.solved = g.isSolved()
+	g.resetHints()
 }
 
+// requestHint searches for the next move in the background, the tile to move is highlighted when found.
+func (g *game) requestHint() {
+	if g.hints >= hintsPerGame || g.hintPending.Load() {
+		return
+	}
+	if h := g.hint.Load(); h != nil && h.board == g.board {
+		return
+	}
+	g.hints++
+	ctx, cancel := context.WithTimeout(context.Background(), hintTimeout)
+	g.cancelHint = cancel
+	g.hintPending.Store(true)
+	go func(b board.Board) {
+		defer g.hintPending.Store(false)
+		defer cancel()
+		var opts []solver.Option
+		if g.heuristic != nil {
+			opts = append(opts, solver.WithHeuristic(g.heuristic))
+		}
+		d, err := solver.NextMove(ctx, b, hintNodeLimit, opts...)
+		if err != nil {
+			return
+		}
+		if pos, ok := b.Source(d); ok && ctx.Err() == nil {
+			g.hint.Store(&hint{board: b, tile: b.At(pos)})
+		}
+	}(g.board)
+}
+
+func (g *game) resetHints() {
+	if g.cancelHint != nil {
+		g.cancelHint()
+	}
+	g.cancelHint = nil
+	g.hint.Store(nil)
+	g.hints = 0
+}
+
+// press slides the tapped tile with all the tiles between it and the blank.
 func (g *game) press(a Audio, t *tile) bool {
 	if g.solved {
 		if g.tiles[0].num == t.num {
@@ -205,44 +641,92 @@ func (g *game) press(a Audio, t *tile) bool {
 		}
 		return true
 	}
-	exchange := func() { t.pos, g.tiles[0].pos = g.tiles[0].pos, t.pos; g.onMove(a) }
-	switch {
-	case t.Col() != g.tiles[0].Col() && t.Row() != g.tiles[0].Row():
+	if t.Col() != g.tiles[0].Col() && t.Row() != g.tiles[0].Row() {
 		return false
-	case t.Col() == g.tiles[0].Col() && g.tiles[0].Row()-t.Row() == 1:
-		t.MoveDown(exchange)
-	case t.Col() == g.tiles[0].Col() && t.Row()-g.tiles[0].Row() == 1:
-		t.MoveUp(exchange)
-	case t.Row() == g.tiles[0].Row() && g.tiles[0].Col()-t.Col() == 1:
-		t.MoveRight(exchange)
-	case t.Row() == g.tiles[0].Row() && t.Col()-g.tiles[0].Col() == 1:
-		t.MoveLeft(exchange)
+	}
+	if d, n, ok := g.board.Line(t.pos()); ok && !g.sliding() {
+		g.slide(a, d, n, func() { g.history.Push(d, n) })
 	}
 	return true
 }
 
-func (g *game) onMove(a Audio) {
+// undo slides back the last move, the history is updated at once so no other move is accepted until the slide ends.
+func (g *game) undo(a Audio) {
+	if g.sliding() {
+		return
+	}
+	if d, n, ok := g.history.Undo(); ok {
+		g.slide(a, d, n, func() {})
+	}
+}
+
+func (g *game) redo(a Audio) {
+	if g.sliding() {
+		return
+	}
+	if d, n, ok := g.history.Redo(); ok {
+		g.slide(a, d, n, func() {})
+	}
+}
+
+// slide animates the n tiles next to the blank in the direction d together,
+// the board is moved when the last of them stops, then moved is called.
+func (g *game) slide(a Audio, d board.Direction, n int, moved func()) {
+	pos, ok := g.board.Source(d)
+	if !ok {
+		return
+	}
+	step := pos - g.board.Blank()
+	var left atomic.Int32
+	left.Store(int32(n))
+	for i := range n {
+		g.tiles[g.board.At(pos+step*i)].Slide(d, func() {
+			if left.Add(-1) > 0 {
+				return
+			}
+			for range n {
+				if err := g.board.Move(d); err != nil {
+					return
+				}
+			}
+			moved()
+			g.onMove(a, n)
+		})
+	}
+}
+
+func (g *game) sliding() bool {
+	for i := range g.tiles {
+		if g.tiles[i].moving {
+			return true
+		}
+	}
+	return false
+}
+
+// onMove is called after n tiles are slid.
+func (g *game) onMove(a Audio, n int) {
 	if !g.muted {
 		a.PlaySound()
 	}
-	if g.moves == 0 {
-		g.onStart()
-	}
-	g.moves++
 	solved := g.isSolved()
 	if solved && !g.solved {
-		g.onSolve(g.moves)
+		g.onSolve(g.leaderboard(g.played), model.Solve{
+			Scramble:  g.scramble.String(),
+			Tier:      g.played,
+			Metric:    g.metric,
+			Optimal:   g.optimal,
+			Duration:  model.JSONDuration(g.history.Recording().Duration()),
+			Recording: g.history.Recording(),
+			Hints:     g.hints,
+			Session:   g.session,
+		})
 	}
 	g.solved = solved
 }
 
 func (g *game) isSolved() bool {
-	for i := 1; i < tiles; i++ {
-		if g.tiles[i].pos != i-1 {
-			return false
-		}
-	}
-	return g.moves > 0
+	return g.board.IsSolved() && g.history.Len() > 0
 }
 
 func (g *game) isTopRated() bool {
@@ -256,7 +740,8 @@ func (g *game) showCongrats() bool {
 }
 
 func (g *game) withStats(consumer func(model.Stats)) bool {
-	if stats := g.stats.Load(); stats != nil {
+	if stats := g.stats.Load(); stats != nil && stats.(model.Stats).Size == g.board.Size().String() && (stats.(model.Stats).Daily != "") == g.daily &&
+		(g.daily || stats.(model.Stats).Tier == g.tier && stats.(model.Stats).Window == g.window) && stats.(model.Stats).Metric == g.metric {
 		consumer(stats.(model.Stats))
 		return true
 	}
@@ -265,31 +750,11 @@ func (g *game) withStats(consumer func(model.Stats)) bool {
 
 func (g *game) Activate() {}
 
-func IsSolvable(puzzle [tiles]byte) (bool, error) {
-	var x *int
-	for i := range puzzle {
-		if puzzle[i] == 0 {
-			if x != nil {
-				return false, errors.New("only one blank position allowed")
-			}
-			x = &i
-		}
-	}
-	if x == nil {
-		return false, errors.New("blank position required to be set")
-	}
-	blank := *x
-	invCount := 0
-	for i := range tiles - 1 {
-		for j := i + 1; j < tiles; j++ {
-			if puzzle[j] > 0 && puzzle[i] > 0 && puzzle[i] > puzzle[j] {
-				invCount += 1
-			}
-		}
-	}
-	if (blank/4+1)%2 == 0 {
-		return invCount%2 == 0, nil
+// SetActive stops the clock of the game while the app is hidden.
+func (g *game) SetActive(active bool) {
+	if active {
+		g.history.Resume()
 	} else {
-		return invCount%2 == 1, nil
+		g.history.Pause()
 	}
 }
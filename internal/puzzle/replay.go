@@ -0,0 +1,166 @@
+package puzzle
+
+import (
+	"15-puzzle/internal/board"
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+)
+
+const (
+	replayBackTemplate  = `[<<]`
+	replayPlayTemplate  = `[ > ]`
+	replayPauseTemplate = `[ = ]`
+	replayStepTemplate  = `[>>]`
+	replayTick          = time.Millisecond * 40
+)
+
+var (
+	replayBackRect = image.Rect(2, puzzleSymY-1, 2+len(replayBackTemplate), puzzleSymY)
+	replayPlayRect = image.Rect((puzzleSymX-len(replayPlayTemplate))/2, puzzleSymY-1, (puzzleSymX+len(replayPlayTemplate))/2, puzzleSymY)
+	replayStepRect = image.Rect(puzzleSymX-2-len(replayStepTemplate), puzzleSymY-1, puzzleSymX-2, puzzleSymY)
+)
+
+// replay plays a recorded game back at its own pace, when paused the moves are stepped one by one.
+type replay struct {
+	langCode  langCode
+	recording board.Recording
+	board     board.Board
+	layout    layout
+	tiles     []*tile
+	step      int           // moves played
+	clock     time.Duration // time played since the start
+	playing   bool
+}
+
+func newReplay() *replay {
+	r := &replay{langCode: langCodeEn}
+	r.Load(board.Recording{Start: board.Solved(board.Classic)})
+	return r
+}
+
+func (r *replay) Load(rec board.Recording) {
+	r.recording = rec
+	r.board = rec.Start
+	r.layout = newLayout(rec.Start.Size())
+	r.tiles = make([]*tile, rec.Start.Size().Tiles())
+	for i := range r.tiles {
+		r.tiles[i] = NewTile(i, func() int { return r.board.Pos(byte(i)) }, r.layout)
+	}
+	r.step = 0
+	r.clock = 0
+	r.playing = false
+}
+
+func (r *replay) Tick(t ticker) {
+	if t != ticker25Hz || !r.playing {
+		return
+	}
+	r.clock += replayTick
+	if r.step < len(r.recording.Moves) && r.recording.Times[r.step] <= r.clock {
+		r.forward()
+	}
+	if r.step == len(r.recording.Moves) {
+		r.playing = false
+	}
+}
+
+func (r *replay) Draw(s Screen) {
+	drawGameField(s)
+	printHeader(s, fmt.Sprintf(l10nMoves(r.langCode)+": %d/%d", r.step, len(r.recording.Moves)), -1)
+	printHeader(s, formatClock(r.clock), 1)
+
+	s.Fill(replayBackRect, nil)
+	s.Print(replayBackTemplate, replayBackRect.Min, buttonColor(r.step > 0))
+	s.Fill(replayPlayRect, nil)
+	if r.playing {
+		s.Print(replayPauseTemplate, replayPlayRect.Min, color.White)
+	} else {
+		s.Print(replayPlayTemplate, replayPlayRect.Min, buttonColor(r.step < len(r.recording.Moves)))
+	}
+	s.Fill(replayStepRect, nil)
+	s.Print(replayStepTemplate, replayStepRect.Min, buttonColor(r.step < len(r.recording.Moves)))
+
+	for i := range r.tiles {
+		r.tiles[i].Draw(s, color.RGBA{0, 0, 0xA0, 0xFF}, color.RGBA{0, 0xFF, 0xFF, 0xFF}, i == 0)
+	}
+}
+
+func (r *replay) Interact(a Audio, col, row int, t time.Duration) actionResult {
+	p := image.Point{col, row}
+	switch {
+	case row <= 1:
+		r.playing = false
+		return resultSwitchGame
+	case p.In(replayPlayRect):
+		if !r.playing && r.step == len(r.recording.Moves) {
+			return resultNone
+		}
+		r.playing = !r.playing
+	case p.In(replayBackRect) && !r.playing:
+		r.back()
+	case p.In(replayStepRect) && !r.playing:
+		r.forward()
+	}
+	return resultNone
+}
+
+// Press leaves the replay by the screen key.
+func (r *replay) Press(a Audio, action KeyAction) actionResult {
+	if action == KeyScreen {
+		r.playing = false
+		return resultSwitchGame
+	}
+	return resultNone
+}
+
+func (r *replay) forward() {
+	if r.step == len(r.recording.Moves) || r.sliding() {
+		return
+	}
+	r.slide(r.recording.Moves[r.step])
+	r.step++
+	if !r.playing {
+		r.clock = r.recording.Times[r.step-1]
+	}
+}
+
+func (r *replay) back() {
+	if r.step == 0 || r.sliding() {
+		return
+	}
+	r.step--
+	r.slide(r.recording.Moves[r.step].Opposite())
+	r.clock = 0
+	if r.step > 0 {
+		r.clock = r.recording.Times[r.step-1]
+	}
+}
+
+func (r *replay) slide(d board.Direction) {
+	pos, ok := r.board.Source(d)
+	if !ok {
+		return
+	}
+	r.tiles[r.board.At(pos)].Slide(d, func() { _ = r.board.Move(d) })
+}
+
+func (r *replay) sliding() bool {
+	for i := range r.tiles {
+		if r.tiles[i].moving {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *replay) SetLang(lc langCode) {
+	r.langCode = lc
+}
+
+func (r *replay) Activate() {}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d.%d", int(d.Minutes()), int(d.Seconds())%60, d.Milliseconds()%1000/100)
+}
@@ -53,6 +53,14 @@ func (sp *splash) Interact(a Audio, col, row int, t time.Duration) actionResult
 	return resultSwitchGame
 }
 
+// Press starts the game on any key.
+func (sp *splash) Press(Audio, KeyAction) actionResult {
+	if sp.gopherDx > 0 {
+		return resultNone
+	}
+	return resultSwitchGame
+}
+
 func (sp *splash) Draw(s Screen) {
 	s.Fill(image.Rect(0, 0, puzzleSymX, puzzleSymY), color.RGBA{0x0A, 0x23, 0x4E, 0xFF})
 	sp.drawGopher(s)
@@ -61,7 +61,7 @@ func (st *stats) Draw(s Screen) {
 	if v := st.mon.Load(); v != nil {
 		m := v.(model.Monitoring)
 		printHeader(s, "Usage Statistics", 0)
-		s.Print(fmt.Sprintf("Players: %d\nGames: %d\nSolved: %d", m.Users, m.GamesStarted, m.GamesSolved),
+		s.Print(fmt.Sprintf("Players: %d\nGames: %d\nSolved: %d\nRejected: %d\nAbandoned: %d\nQuarantined: %d", m.Users, m.GamesStarted, m.GamesSolved, m.GamesRejected, m.GamesAbandoned, m.GamesQuarantined),
 			image.Point{3, 4},
 			color.RGBA{0, 0xFF, 0xFF, 0xFF})
 	} else {
@@ -80,11 +80,7 @@ func (st *stats) Draw(s Screen) {
 
 func (st *stats) Interact(a Audio, col, row int, t time.Duration) actionResult {
 	if (image.Point{col, row}).In(image.Rect(2, 1, puzzleSymX-2, 2)) {
-		st.input = [4]byte{}
-		st.authFail = false
-		st.requestSent = false
-		st.idx = 0
-		return resultSwitchGame
+		return st.leave()
 	}
 	if st.mon.Load() == nil {
 		for i := range st.dials {
@@ -97,6 +93,22 @@ func (st *stats) Interact(a Audio, col, row int, t time.Duration) actionResult {
 	return resultNone
 }
 
+// Press leaves the screen by the screen key.
+func (st *stats) Press(a Audio, action KeyAction) actionResult {
+	if action == KeyScreen {
+		return st.leave()
+	}
+	return resultNone
+}
+
+func (st *stats) leave() actionResult {
+	st.input = [4]byte{}
+	st.authFail = false
+	st.requestSent = false
+	st.idx = 0
+	return resultSwitchGame
+}
+
 func (st *stats) pressNextButton(digit byte) {
 	if st.idx >= len(st.input) {
 		return
@@ -1,11 +1,13 @@
 package puzzle
 
 import (
+	"15-puzzle/internal/board"
 	"fmt"
 	"image"
 	"image/color"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,17 +17,101 @@ func (t tileFormatter) Format(c string) string {
 	return fmt.Sprintf(string(t), c[:int(math.Min(float64(len(c)), 2))])
 }
 
-const tileTemplate tileFormatter = `╔════╗
-║ %2s ║
-╚════╝`
+var tileTemplate = newTileFormatter(puzzleTileSymW, puzzleTileSymH)
+
+// newTileFormatter makes a template for a tile of w*h symbols, the last column is left as a gap between tiles.
+// A tile gets a frame when there is enough room for it, otherwise only the number is shown.
+func newTileFormatter(w, h int) tileFormatter {
+	inner := w - 1
+	center := func(width int) string {
+		pad := max(width-2, 0)
+		return strings.Repeat(" ", pad/2) + "%2s" + strings.Repeat(" ", pad-pad/2)
+	}
+	switch {
+	case h >= 3 && inner >= 4:
+		bar := strings.Repeat("═", inner-2)
+		return tileFormatter("╔" + bar + "╗\n║" + center(inner-2) + "║\n╚" + bar + "╝")
+	case h == 2:
+		return tileFormatter(center(inner) + "\n" + strings.Repeat("▀", inner))
+	default:
+		return tileFormatter(strings.Repeat("\n", (h-1)/2) + center(inner))
+	}
+}
+
+// layout places tiles of a board into the play field, tiles are scaled down to fit the grid.
+type layout struct {
+	size     board.Size
+	w, h     int // tile size
+	x, y     int // board's top left corner
+	template tileFormatter
+}
+
+func newLayout(s board.Size) layout {
+	w := min(puzzleTileSymW, fieldSymX/s.W)
+	h := min(puzzleTileSymH, fieldSymY/s.H)
+	return layout{
+		size:     s,
+		w:        w,
+		h:        h,
+		x:        1 + (fieldSymX-w*s.W+1)/2,
+		y:        3 + (fieldSymY-h*s.H)/2,
+		template: newTileFormatter(w, h),
+	}
+}
+
+// rect is the board in the play field.
+func (l layout) rect() image.Rectangle {
+	return image.Rect(l.x, l.y, l.x+l.w*l.size.W, l.y+l.h*l.size.H)
+}
+
+// swipe returns the direction the pointer moved by v, a swipe is half a tile long at least.
+func (l layout) swipe(v image.Point) (board.Direction, bool) {
+	switch {
+	case abs(v.X)*l.h >= abs(v.Y)*l.w && 2*abs(v.X) >= l.w:
+		if v.X > 0 {
+			return board.Right, true
+		}
+		return board.Left, true
+	case abs(v.Y)*l.w > abs(v.X)*l.h && 2*abs(v.Y) >= l.h:
+		if v.Y > 0 {
+			return board.Down, true
+		}
+		return board.Up, true
+	}
+	return 0, false
+}
+
+// fraction returns the part of a tile the pointer moved by v in the direction d, from 0 to 1.
+func (l layout) fraction(d board.Direction, v image.Point) float64 {
+	var f float64
+	switch d {
+	case board.Up:
+		f = -float64(v.Y) / float64(l.h)
+	case board.Down:
+		f = float64(v.Y) / float64(l.h)
+	case board.Left:
+		f = -float64(v.X) / float64(l.w)
+	case board.Right:
+		f = float64(v.X) / float64(l.w)
+	}
+	return min(max(f, 0), 1)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
 
 type button struct {
 	title func() string
 	x, y  func() int
+	w, h  int
 }
 
 func (t *button) Interact(x, y int) bool {
-	if (image.Point{x, y}).In(image.Rect(t.x(), t.y(), t.x()+puzzleTileSymW, t.y()+puzzleTileSymH)) {
+	if (image.Point{x, y}).In(image.Rect(t.x(), t.y(), t.x()+t.w, t.y()+t.h)) {
 		t.onTrigger()
 		return true
 	}
@@ -51,24 +137,29 @@ func NewButton(title func() string, x, y func() int) *button {
 		title: title,
 		x:     x,
 		y:     y,
+		w:     puzzleTileSymW,
+		h:     puzzleTileSymH,
 	}
 }
 
 type tile struct {
 	button
-	num int
-	pos int
+	num    int
+	pos    func() int
+	layout layout
 
 	moving bool
 	dx, dy float64
 }
 
-func NewTile(num, pos int) *tile {
+func NewTile(num int, pos func() int, l layout) *tile {
 	t := &tile{
-		num: num,
-		pos: pos,
+		num:    num,
+		pos:    pos,
+		layout: l,
 	}
 	t.button = *NewButton(t.title, t.X, t.Y)
+	t.button.w, t.button.h = l.w, l.h
 	return t
 }
 
@@ -83,28 +174,42 @@ func (t *tile) CanInteract(x, y int) bool {
 	return !t.moving && t.button.Interact(x, y)
 }
 
-func (t *tile) MoveUp(finish func()) {
-	t.shift(&t.dy, -1, finish)
-}
-
-func (t *tile) MoveDown(finish func()) {
-	t.shift(&t.dy, 1, finish)
-}
-
-func (t *tile) MoveLeft(finish func()) {
-	t.shift(&t.dx, -1, finish)
+func (t *tile) Slide(d board.Direction, finish func()) {
+	switch d {
+	case board.Up:
+		t.shift(&t.dy, -1, finish)
+	case board.Down:
+		t.shift(&t.dy, 1, finish)
+	case board.Left:
+		t.shift(&t.dx, -1, finish)
+	case board.Right:
+		t.shift(&t.dx, 1, finish)
+	}
 }
 
-func (t *tile) MoveRight(finish func()) {
-	t.shift(&t.dx, 1, finish)
+// Drag shifts the tile by the fraction f of its size in the direction d unless the tile slides.
+func (t *tile) Drag(d board.Direction, f float64) {
+	if t.moving {
+		return
+	}
+	switch d {
+	case board.Up:
+		t.dx, t.dy = 0, -f
+	case board.Down:
+		t.dx, t.dy = 0, f
+	case board.Left:
+		t.dx, t.dy = -f, 0
+	case board.Right:
+		t.dx, t.dy = f, 0
+	}
 }
 
+// shift slides the tile on from where it was dragged to.
 func (t *tile) shift(src *float64, target float64, finish func()) {
 	if t.moving {
 		return
 	}
 	t.moving = true
-	*src = 0
 	go t.shifter(src, target, func() { finish(); t.moving = false })
 }
 
@@ -122,18 +227,41 @@ func (t *tile) shifter(src *float64, target float64, finish func()) {
 	finish()
 }
 
+// Draw fills the tile and prints its title, the blank is drawn as a hole in the board.
+func (t *tile) Draw(s Screen, background, foreground color.Color, blank bool) {
+	boardBottomColor := color.Black
+	// boardBottomColor := color.RGBA{0xFF, 0x00, 0xFF, 0xFF}
+
+	fillRect := image.Rect(t.X(), t.Y(), t.X()+t.layout.w, t.Y()+t.layout.h)
+	if blank {
+		background = boardBottomColor
+		fillRect.Max.X--
+	}
+	if t.moving || t.dx != 0 || t.dy != 0 {
+		// fillRect contains unshifted yet coordinates, time to fix font gallucinations when animating adjacent tiles
+		fillRect.Max.X--                   // right border: skip last column to justify on move
+		s.Fill(fillRect, boardBottomColor) // partial "board bottom" from both sides
+		fillRect.Max.X++                   // right border: restore
+		fillRect.Min.X--                   // left border: extend to 1 col left to justify on move
+	}
+	s.Fill(fillRect, background)
+	if !blank {
+		s.Print(t.layout.template.Format(t.Title()), image.Point{t.X(), t.Y()}, foreground)
+	}
+}
+
 func (t *tile) Col() int {
-	return t.pos % 4
+	return t.layout.size.Col(t.pos())
 }
 
 func (t *tile) Row() int {
-	return t.pos / 4
+	return t.layout.size.Row(t.pos())
 }
 
 func (t *tile) X() int {
-	return t.Col()*puzzleTileSymW + int(t.dx*puzzleTileSymW) + 2
+	return t.Col()*t.layout.w + int(t.dx*float64(t.layout.w)) + t.layout.x
 }
 
 func (t *tile) Y() int {
-	return t.Row()*puzzleTileSymH + int(t.dy*puzzleTileSymH) + 3
+	return t.Row()*t.layout.h + int(t.dy*float64(t.layout.h)) + t.layout.y
 }
@@ -0,0 +1,182 @@
+package repo
+
+import (
+	"15-puzzle/internal/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	syncInterval   = time.Second
+	minCompactSize = 1 << 20
+)
+
+// FileRepo keeps the data in memory, the writes are appended to the journal of the data file.
+// The data file is rewritten by the compaction once the journal outgrows it.
+type FileRepo struct {
+	*MemRepo
+	dataFile   string
+	policy     Sync
+	journal    *journal
+	dataSize   atomic.Int64
+	compacting sync.Mutex
+	compact    chan struct{}
+	done       chan struct{}
+	stopped    chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewFileRepo opens the data file and replays its journal, players are ranked by DefaultRankings unless other rankings are given.
+// The repository syncs and compacts the journal in background until the context is done or the repository is closed.
+func NewFileRepo(ctx context.Context, dataFile string, policy Sync, rankings ...Ranking) (*FileRepo, error) {
+	dataFile = path.Clean(dataFile)
+	r := &FileRepo{
+		MemRepo:  NewMemRepo(rankings...),
+		dataFile: dataFile,
+		policy:   policy,
+		compact:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	b, err := os.ReadFile(dataFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	b, report, err := migrate(dataFile, b, false)
+	if err != nil {
+		return nil, fmt.Errorf("migrate %s: %s", dataFile, err)
+	}
+	if report.From != report.To {
+		slog.Info(report.String())
+	}
+
+	if len(b) > 0 {
+		if err := r.load(b); err != nil {
+			return nil, err
+		}
+	}
+	r.dataSize.Store(int64(len(b)))
+
+	j, err := openJournal(dataFile+JournalSuffix, policy, r.data.Seq, func(e entry) error { return e.apply(r.data) })
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	r.indexData()
+	r.persist = r.appendEntry
+
+	if len(b) == 0 {
+		// init with empty writeable file
+		if err := r.Compact(); err != nil {
+			return nil, errors.Join(err, j.close())
+		}
+	}
+
+	go r.background(ctx)
+	return r, nil
+}
+
+// load reads the data file of the SchemaVersion, the fields unknown to the version fail the load rather than being dropped.
+func (r *FileRepo) load(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&r.data); err != nil {
+		return err
+	}
+	if r.data.Version != SchemaVersion {
+		return fmt.Errorf("schema version %d, expected %d", r.data.Version, SchemaVersion)
+	}
+	if r.data.Boards == nil {
+		r.data.Boards = make(map[string]map[int]model.User)
+	}
+	return nil
+}
+
+// appendEntry appends the entry of the write to the journal, the write is committed by the sync policy.
+func (r *FileRepo) appendEntry(e entry) (func() error, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("entry marshall: %s", err)
+	}
+	r.journal.append(e.Seq, b)
+	return func() error {
+		if err := r.journal.commit(e.Seq); err != nil {
+			return err
+		}
+		if size := r.journal.length(); size > max(r.dataSize.Load(), minCompactSize) {
+			select {
+			case r.compact <- struct{}{}:
+			default:
+			}
+		}
+		return nil
+	}, nil
+}
+
+// background syncs the journal by the SyncInterval policy and compacts it.
+func (r *FileRepo) background(ctx context.Context) {
+	defer close(r.stopped)
+	var tick <-chan time.Time
+	if r.policy == SyncInterval {
+		t := time.NewTicker(syncInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.done:
+			return
+		case <-tick:
+			if err := r.journal.sync(); err != nil {
+				slog.Error(fmt.Sprintf("journal sync: %s", err))
+			}
+		case <-r.compact:
+			if err := r.Compact(); err != nil {
+				slog.Error(fmt.Sprintf("journal compaction: %s", err))
+			}
+		}
+	}
+}
+
+// Compact writes the data file and drops the journal entries it keeps, the writes are not blocked by the disk I/O.
+func (r *FileRepo) Compact() error {
+	r.compacting.Lock()
+	defer r.compacting.Unlock()
+
+	r.latch.RLock()
+	b, err := json.Marshal(r.data)
+	seq := r.data.Seq
+	r.latch.RUnlock()
+	if err != nil {
+		return fmt.Errorf("data marshall: %s", err)
+	}
+
+	if err := writeFile(r.dataFile, b, r.policy != SyncNever); err != nil {
+		return err
+	}
+	r.dataSize.Store(int64(len(b)))
+	return r.journal.truncate(seq)
+}
+
+// Close stops the background sync and compaction, and syncs the journal.
+func (r *FileRepo) Close() error {
+	err := errors.New("repository closed")
+	r.closeOnce.Do(func() {
+		close(r.done)
+		<-r.stopped
+		err = r.journal.close()
+	})
+	return err
+}
@@ -0,0 +1,319 @@
+package repo
+
+import (
+	"15-puzzle/internal/model"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Sync is the fsync policy of the journal and the data file.
+type Sync int
+
+const (
+	SyncAlways   Sync = iota // a write returns once it is synced, concurrent writes are synced at once
+	SyncInterval             // the journal is synced every second, a crash loses a second of writes at most
+	SyncNever                // the system syncs the files
+)
+
+var syncNames = []string{"always", "interval", "never"}
+
+func ParseSync(s string) (Sync, error) {
+	if i := slices.Index(syncNames, s); i >= 0 {
+		return Sync(i), nil
+	}
+	return SyncAlways, fmt.Errorf("unknown sync policy %q, expected one of: %s", s, strings.Join(syncNames, ", "))
+}
+
+func (s Sync) String() string {
+	if int(s) < len(syncNames) {
+		return syncNames[s]
+	}
+	return fmt.Sprintf("Sync(%d)", int(s))
+}
+
+// JournalSuffix is appended to the data file name to name its journal.
+const JournalSuffix = ".journal"
+
+// entry is a journal record of the users and games changed by a write, the sequence numbers of the entries have no gaps.
+type entry struct {
+	Seq    uint64                        `json:"seq"`
+	Boards map[string]map[int]model.User `json:"boards,omitempty"`
+	Daily  map[string]map[int]model.User `json:"daily,omitempty"`
+	Games  []model.Game                  `json:"games,omitempty"` // in order of id, a game next to the last one is appended
+}
+
+func (e entry) apply(d *model.Data) error {
+	for key, users := range e.Boards {
+		b := boardKey{key: key}.users(d)
+		for id, u := range users {
+			b[id] = u
+		}
+	}
+	for key, users := range e.Daily {
+		b := boardKey{daily: true, key: key}.users(d)
+		for id, u := range users {
+			b[id] = u
+		}
+	}
+	for _, g := range e.Games {
+		switch {
+		case g.ID >= 1 && g.ID <= len(d.Games):
+			d.Games[g.ID-1] = g
+		case g.ID == len(d.Games)+1:
+			d.Games = append(d.Games, g)
+		default:
+			return fmt.Errorf("entry %d: game_id=%d out of %d games", e.Seq, g.ID, len(d.Games))
+		}
+	}
+	d.Seq = e.Seq
+	return nil
+}
+
+// journal is the append-only log of the writes since the data file snapshot, a line of JSON an entry.
+// Entries are appended in memory under the data lock and written to the file by the first writer to commit,
+// so the data lock is not held during the disk I/O. A failed write fails the later ones.
+type journal struct {
+	name   string
+	policy Sync
+
+	pendingLatch sync.Mutex
+	pending      [][]byte
+	last         uint64 // the sequence number of the last entry appended
+
+	latch   sync.Mutex
+	f       *os.File
+	size    int64
+	base    uint64  // the sequence number of the first entry in the file
+	ends    []int64 // the end offsets of the entries in the file
+	written uint64
+	synced  uint64
+	err     error
+}
+
+// openJournal replays the entries after the snapshot sequence number, a torn last entry is truncated.
+func openJournal(name string, policy Sync, snapshot uint64, apply func(entry) error) (*journal, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %s", err)
+	}
+	j := &journal{name: name, policy: policy, f: f, last: snapshot, written: snapshot, synced: snapshot}
+	if err := j.replay(snapshot, apply); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("replay %s: %s", name, err)
+	}
+	return j, nil
+}
+
+func (j *journal) replay(snapshot uint64, apply func(entry) error) error {
+	rd := bufio.NewReader(j.f)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) == 0 {
+			break
+		}
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil || line[len(line)-1] != '\n' {
+			if _, peek := rd.Peek(1); peek != io.EOF {
+				return fmt.Errorf("corrupt entry at offset %d", j.size)
+			}
+			slog.Warn(fmt.Sprintf("journal %s: torn entry of %d bytes at offset %d truncated", j.name, len(line), j.size))
+			if err := j.f.Truncate(j.size); err != nil {
+				return err
+			}
+			break
+		}
+		if len(j.ends) == 0 {
+			j.base = e.Seq
+		}
+		j.size += int64(len(line))
+		j.ends = append(j.ends, j.size)
+		if e.Seq <= snapshot {
+			// the data file is written before the journal is compacted
+			continue
+		}
+		if e.Seq != j.last+1 {
+			return fmt.Errorf("entry %d follows %d", e.Seq, j.last)
+		}
+		if err := apply(e); err != nil {
+			return err
+		}
+		j.last, j.written, j.synced = e.Seq, e.Seq, e.Seq
+	}
+	if len(j.ends) == 0 {
+		j.base = j.last + 1
+	}
+	return nil
+}
+
+// append keeps the entry to be written by a commit, the entries are appended in order of sequence numbers.
+func (j *journal) append(seq uint64, b []byte) {
+	j.pendingLatch.Lock()
+	defer j.pendingLatch.Unlock()
+
+	j.pending = append(j.pending, append(b, '\n'))
+	j.last = seq
+}
+
+// commit returns once the entry of the sequence number is written, and synced by the SyncAlways policy.
+func (j *journal) commit(seq uint64) error {
+	j.latch.Lock()
+	defer j.latch.Unlock()
+
+	if j.written < seq {
+		j.flush()
+	}
+	if j.err != nil {
+		return j.err
+	}
+	if j.policy == SyncAlways && j.synced < seq {
+		j.syncFile()
+	}
+	return j.err
+}
+
+// sync writes and syncs the pending entries.
+func (j *journal) sync() error {
+	j.latch.Lock()
+	defer j.latch.Unlock()
+
+	j.flush()
+	if j.synced < j.written {
+		j.syncFile()
+	}
+	return j.err
+}
+
+func (j *journal) flush() {
+	if j.err != nil {
+		return
+	}
+	j.pendingLatch.Lock()
+	pending, last := j.pending, j.last
+	j.pending = nil
+	j.pendingLatch.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	if _, err := j.f.Write(bytes.Join(pending, nil)); err != nil {
+		j.err = fmt.Errorf("write %s: %s", j.name, err)
+		return
+	}
+	for _, b := range pending {
+		j.size += int64(len(b))
+		j.ends = append(j.ends, j.size)
+	}
+	j.written = last
+}
+
+func (j *journal) syncFile() {
+	if err := j.f.Sync(); err != nil {
+		j.err = fmt.Errorf("sync %s: %s", j.name, err)
+		return
+	}
+	j.synced = j.written
+}
+
+func (j *journal) length() int64 {
+	j.latch.Lock()
+	defer j.latch.Unlock()
+
+	return j.size
+}
+
+// truncate drops the entries up to the sequence number which are kept by the data file,
+// the entries after it are copied to the new journal file.
+func (j *journal) truncate(seq uint64) error {
+	j.latch.Lock()
+	defer j.latch.Unlock()
+
+	j.flush()
+	if j.err != nil {
+		return j.err
+	}
+	if seq < j.base {
+		return nil
+	}
+	keep := int(seq-j.base) + 1
+	cut := j.ends[keep-1]
+	tail := make([]byte, j.size-cut)
+	if _, err := j.f.ReadAt(tail, cut); err != nil {
+		return fmt.Errorf("read %s: %s", j.name, err)
+	}
+	if err := writeFile(j.name, tail, j.policy != SyncNever); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.name, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		j.err = fmt.Errorf("reopen %s: %s", j.name, err)
+		return j.err
+	}
+	if err := j.f.Close(); err != nil {
+		slog.Warn(fmt.Sprintf("close %s: %s", j.name, err))
+	}
+	j.f = f
+	ends := make([]int64, 0, len(j.ends)-keep)
+	for _, end := range j.ends[keep:] {
+		ends = append(ends, end-cut)
+	}
+	j.ends, j.size, j.base = ends, j.size-cut, seq+1
+	j.synced = max(j.synced, seq)
+	return nil
+}
+
+func (j *journal) close() error {
+	err := j.sync()
+	return errors.Join(err, j.f.Close())
+}
+
+// writeFile replaces the file atomically by renaming the temporary file written next to it.
+func writeFile(name string, b []byte, fsync bool) error {
+	dir := path.Dir(name)
+	tf, err := os.CreateTemp(dir, "15-puzzle")
+	if err != nil {
+		return fmt.Errorf("create temp file at %s: %s", dir, err)
+	}
+
+	if _, err := tf.Write(b); err != nil {
+		return fmt.Errorf("write %s: %s", tf.Name(), err)
+	}
+
+	if fsync {
+		if err := tf.Sync(); err != nil {
+			return fmt.Errorf("sync %s: %s", tf.Name(), err)
+		}
+	}
+
+	if err := tf.Close(); err != nil {
+		return fmt.Errorf("close %s to %s: %s", tf.Name(), name, err)
+	}
+
+	if err := os.Rename(tf.Name(), name); err != nil {
+		return fmt.Errorf("rename %s to %s: %s", tf.Name(), name, err)
+	}
+
+	if fsync {
+		d, err := os.Open(dir)
+		if err != nil {
+			return fmt.Errorf("open %s: %s", dir, err)
+		}
+		defer d.Close()
+		if err := d.Sync(); err != nil {
+			return fmt.Errorf("sync %s: %s", dir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package repo_test
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/repo"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ctx     = context.Background()
+	classic = model.Leaderboard{Size: board.Classic}
+)
+
+func TestParseSync(t *testing.T) {
+	for _, s := range []repo.Sync{repo.SyncAlways, repo.SyncInterval, repo.SyncNever} {
+		actual, err := repo.ParseSync(s.String())
+		assert.NoError(t, err)
+		assert.Equal(t, s, actual)
+	}
+	_, err := repo.ParseSync("")
+	assert.Error(t, err)
+}
+
+func TestJournalReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	withReopenedRepo(t, file, func(r *repo.FileRepo) {
+		_, g, err := r.RegisterGameStart(ctx, 1, classic, "4x4:1")
+		assert.NoError(t, err)
+		_, err = r.RegisterGameSolve(ctx, 1, classic, g.ID, solveOf(recording(10)))
+		assert.NoError(t, err)
+		_, err = r.RegisterDailySolve(ctx, 1, "2024-12-31", classic, solveOf(recording(10)))
+		assert.NoError(t, err)
+		_, _, err = r.RegisterGameStart(ctx, 1, classic, "4x4:2")
+		assert.NoError(t, err)
+	})
+	snapshot, err := os.ReadFile(file)
+	assert.NoError(t, err)
+
+	expected := withReopenedRepo(t, file, func(r *repo.FileRepo) {})
+	assert.Equal(t, 2, len(expected.games), "games should be replayed")
+	assert.Equal(t, 1, expected.user.GamesSolved, "solve should be replayed")
+	assert.Equal(t, 1, expected.daily.GamesSolved, "daily solve should be replayed")
+	actual, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot, actual, "data file should not be written before the compaction")
+
+	// the journal kept by an interrupted compaction is replayed after the data file entries
+	kept, err := os.ReadFile(file + repo.JournalSuffix)
+	assert.NoError(t, err)
+	withReopenedRepo(t, file, func(r *repo.FileRepo) {
+		assert.NoError(t, r.Compact())
+		info, err := os.Stat(file + repo.JournalSuffix)
+		assert.NoError(t, err)
+		assert.Zero(t, info.Size(), "compacted journal should be empty")
+	})
+	assert.Equal(t, expected, withReopenedRepo(t, file, func(r *repo.FileRepo) {}))
+	assert.NoError(t, os.WriteFile(file+repo.JournalSuffix, kept, 0o644))
+	assert.Equal(t, expected, withReopenedRepo(t, file, func(r *repo.FileRepo) {}))
+	withReopenedRepo(t, file, func(r *repo.FileRepo) {
+		_, _, err := r.RegisterGameStart(ctx, 1, classic, "4x4:3")
+		assert.NoError(t, err)
+	})
+	assert.Equal(t, 3, len(withReopenedRepo(t, file, func(r *repo.FileRepo) {}).games))
+}
+
+func TestJournalRecovery(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	withReopenedRepo(t, file, func(r *repo.FileRepo) {
+		_, _, err := r.RegisterGameStart(ctx, 1, classic, "4x4:1")
+		assert.NoError(t, err)
+	})
+	info, err := os.Stat(file + repo.JournalSuffix)
+	assert.NoError(t, err)
+
+	appendFile(t, file+repo.JournalSuffix, `{"seq":2,"boards":{"4x4":{"1":`)
+	withReopenedRepo(t, file, func(r *repo.FileRepo) {
+		torn, err := os.Stat(file + repo.JournalSuffix)
+		assert.NoError(t, err)
+		assert.Equal(t, info.Size(), torn.Size(), "torn entry should be truncated")
+		_, _, err = r.RegisterGameStart(ctx, 1, classic, "4x4:2")
+		assert.NoError(t, err)
+	})
+	assert.Equal(t, 2, withReopenedRepo(t, file, func(r *repo.FileRepo) {}).user.GamesStarted)
+
+	appendFile(t, file+repo.JournalSuffix, "{}\n{\"seq\":4}\n")
+	_, err = repo.NewFileRepo(context.Background(), file, repo.SyncAlways)
+	assert.Error(t, err, "corrupt entry should fail the replay")
+}
+
+func TestJournalConcurrentWrites(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	for _, policy := range []repo.Sync{repo.SyncAlways, repo.SyncInterval, repo.SyncNever} {
+		r, err := repo.NewFileRepo(context.Background(), file, policy)
+		if err != nil {
+			t.Fatalf("repo init: %s", err)
+		}
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, _, err := r.RegisterGameStart(ctx, i, classic, "4x4:1"); err != nil {
+					t.Errorf("RegisterGameStart: %s", err)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.NoError(t, r.Close())
+	}
+	withReopenedRepo(t, file, func(r *repo.FileRepo) {
+		m, err := r.Monitoring(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 150, m.GamesStarted, "every write should be replayed")
+	})
+}
+
+type repoState struct {
+	user  model.User
+	daily model.User
+	games []model.Game
+}
+
+// withReopenedRepo opens the repository of the file for the test and returns the state of user 1 after it is closed.
+func withReopenedRepo(t *testing.T, file string, test func(r *repo.FileRepo)) repoState {
+	r, err := repo.NewFileRepo(context.Background(), file, repo.SyncAlways)
+	if err != nil {
+		t.Fatalf("repo init: %s", err)
+	}
+	test(r)
+	var s repoState
+	s.user, _ = r.Stats(ctx, 1, classic)
+	s.daily, _ = r.DailyStats(ctx, 1, "2024-12-31", classic)
+	s.games, _ = r.Games(ctx, 1)
+	if err := r.Close(); err != nil {
+		t.Fatalf("repo close: %s", err)
+	}
+	return s
+}
+
+func appendFile(t *testing.T, name, s string) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open %s: %s", name, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatalf("write %s: %s", name, err)
+	}
+}
+
+func TestWriteNotPersisted(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	r, err := repo.NewFileRepo(context.Background(), file, repo.SyncAlways)
+	if err != nil {
+		t.Fatalf("repo init: %s", err)
+	}
+	_, g, err := r.RegisterGameStart(ctx, 1, classic, "4x4:1")
+	if err != nil {
+		t.Fatalf("RegisterGameStart: %s", err)
+	}
+	if _, err := r.RegisterGameSolve(ctx, 1, classic, g.ID, solveOf(recording(10))); err != nil {
+		t.Fatalf("RegisterGameSolve: %s", err)
+	}
+	before, err := r.Stats(ctx, 1, classic)
+	assert.NoError(t, err)
+	_, g, err = r.RegisterGameStart(ctx, 1, classic, "4x4:1")
+	if err != nil {
+		t.Fatalf("RegisterGameStart: %s", err)
+	}
+
+	// the move average of the solve of no moves is not a number the entry is encoded with
+	_, err = r.RegisterGameSolve(ctx, 1, classic, g.ID, model.Solve{Duration: model.JSONDuration(time.Second)})
+	assert.Error(t, err)
+	_, err = r.RegisterDailySolve(ctx, 2, "2024-01-01", classic, model.Solve{Duration: model.JSONDuration(time.Second)})
+	assert.Error(t, err)
+	after, err := r.Stats(ctx, 1, classic)
+	assert.NoError(t, err)
+	assert.Equal(t, before.GamesSolved, after.GamesSolved, "solve should be undone")
+	assert.Equal(t, before.BestTime, after.BestTime, "solve should be undone")
+	game, err := r.Game(ctx, 1, g.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.GameStarted, game.Status, "game should stay started")
+	ranks, err := r.DailyRanks(ctx, 2, "2024-01-01", classic)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, ranks[0].Position, "daily player should not be rated")
+	if _, err := r.RegisterGameSolve(ctx, 1, classic, g.ID, solveOf(recording(8))); err != nil {
+		t.Fatalf("RegisterGameSolve: %s", err)
+	}
+	expected, err := r.Monitoring(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+
+	testWithRepo(t, file, func(t *testing.T, r *repo.FileRepo) {
+		monitoring, err := r.Monitoring(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, monitoring, "journal should have the persisted writes only")
+		assert.Equal(t, []int{1}, r.Rating(classic))
+	})
+}
+
+func TestWriteNotCommitted(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	r, err := repo.NewFileRepo(context.Background(), file, repo.SyncAlways)
+	if err != nil {
+		t.Fatalf("repo init: %s", err)
+	}
+	_, g, err := r.RegisterGameStart(ctx, 1, classic, "4x4:1")
+	if err != nil {
+		t.Fatalf("RegisterGameStart: %s", err)
+	}
+	if _, err := r.RegisterGameSolve(ctx, 1, classic, g.ID, solveOf(recording(10))); err != nil {
+		t.Fatalf("RegisterGameSolve: %s", err)
+	}
+	expected, err := r.Monitoring(ctx)
+	assert.NoError(t, err)
+
+	// the journal file is closed, the entry fails to be written after the data is changed
+	assert.NoError(t, r.Close())
+	_, _, err = r.RegisterGameStart(ctx, 2, classic, "4x4:1")
+	assert.Error(t, err)
+	_, err = r.Stats(ctx, 2, classic)
+	assert.Error(t, err, "player of the write not committed should not be served")
+	_, err = r.Monitoring(ctx)
+	assert.Error(t, err, "reads should stop")
+	_, err = r.RegisterGameSolve(ctx, 1, classic, g.ID, solveOf(recording(8)))
+	assert.Error(t, err, "writes should stop")
+
+	testWithRepo(t, file, func(t *testing.T, r *repo.FileRepo) {
+		m, err := r.Monitoring(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, m, "write not committed should be lost")
+	})
+}
@@ -0,0 +1,354 @@
+// Package kv is an embedded key-value store of a single file. The keys are kept in order by a B+tree of pages
+// which is written copy-on-write, a write transaction is committed by switching the meta page,
+// so a crash keeps the tree of the last committed transaction.
+package kv
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+	"sync"
+)
+
+const (
+	pageSize   = 4096
+	headerSize = 16
+	magic      = 0x15b0ee
+	version    = 1
+	// fillSize is the size a split node is filled up to, the room left saves the next writes a split
+	fillSize = pageSize * 3 / 4
+	// mergeSize is the size a node is merged with its sibling below
+	mergeSize         = pageSize / 4
+	defaultCacheNodes = 4096
+)
+
+const (
+	leafPage uint16 = 1 << iota
+	branchPage
+	metaPage
+	freelistPage
+)
+
+type pgid uint64
+
+var ErrCorrupt = errors.New("kv: file corrupt")
+
+// Options of the store, the zero value syncs every commit.
+type Options struct {
+	NoSync     bool // the commits are not synced, the system crash may lose or corrupt the last ones
+	CacheNodes int  // the nodes read from the file kept in memory, 4096 pages by default
+}
+
+// DB is the store of a file, a write transaction excludes the others while the read ones run concurrently.
+type DB struct {
+	mu     sync.RWMutex
+	file   *os.File
+	noSync bool
+	meta   meta
+	free   []pgid // the free pages in order
+	cache  *cache
+}
+
+// meta is the root of the committed tree, the meta pages 0 and 1 are written in turn.
+type meta struct {
+	root     pgid
+	count    uint64 // the keys of the tree
+	freelist pgid
+	high     pgid // the pages of the file
+	txid     uint64
+}
+
+// Open opens the store of the file, the file is created when it is missing.
+func Open(name string, opts Options) (*DB, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	cacheNodes := opts.CacheNodes
+	if cacheNodes <= 0 {
+		cacheNodes = defaultCacheNodes
+	}
+	db := &DB{file: f, noSync: opts.NoSync, cache: newCache(cacheNodes)}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+	if info.Size() == 0 {
+		err = db.init()
+	} else {
+		err = db.load()
+	}
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("kv %s: %w", name, err), f.Close())
+	}
+	return db, nil
+}
+
+// init writes the empty tree: the meta pages, the empty freelist and the empty root leaf.
+func (db *DB) init() error {
+	db.meta = meta{freelist: 2, root: 3, high: 4}
+	if err := db.writePage(2, freelistPage, 0, binary.LittleEndian.AppendUint64(nil, 0)); err != nil {
+		return err
+	}
+	if err := db.writePage(3, leafPage, 0, nil); err != nil {
+		return err
+	}
+	for txid := range uint64(2) {
+		db.meta.txid = txid
+		if err := db.writeMeta(db.meta); err != nil {
+			return err
+		}
+	}
+	return db.sync()
+}
+
+// load reads the meta page of the last committed transaction and its freelist.
+func (db *DB) load() error {
+	var metas []meta
+	for id := range pgid(2) {
+		b := make([]byte, pageSize)
+		if _, err := db.file.ReadAt(b, int64(id)*pageSize); err != nil {
+			continue
+		}
+		if m, ok := decodeMeta(b); ok {
+			metas = append(metas, m)
+		}
+	}
+	if len(metas) == 0 {
+		return fmt.Errorf("%w: no valid meta page", ErrCorrupt)
+	}
+	db.meta = slices.MaxFunc(metas, func(a, b meta) int { return cmp.Compare(a.txid, b.txid) })
+
+	p, err := db.readPage(db.meta.freelist)
+	if err != nil {
+		return err
+	}
+	b := p.data
+	if p.flags != freelistPage || len(b) < 8 {
+		return fmt.Errorf("%w: page %d is not the freelist", ErrCorrupt, db.meta.freelist)
+	}
+	n := binary.LittleEndian.Uint64(b)
+	if uint64(len(b)-8)/8 < n {
+		return fmt.Errorf("%w: freelist of %d pages overflows its page", ErrCorrupt, n)
+	}
+	db.free = make([]pgid, n)
+	for i := range db.free {
+		db.free[i] = pgid(binary.LittleEndian.Uint64(b[8+i*8:]))
+	}
+	return nil
+}
+
+// Close closes the file, the committed transactions are synced unless the store does not sync.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return errors.Join(db.sync(), db.file.Close())
+}
+
+// Sync syncs the committed transactions of the store which does not sync them.
+func (db *DB) Sync() error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.file.Sync()
+}
+
+func (db *DB) sync() error {
+	if db.noSync {
+		return nil
+	}
+	return db.file.Sync()
+}
+
+// View runs the read transaction.
+func (db *DB) View(fn func(tx *Tx) error) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return fn(&Tx{db: db, root: child{pgid: db.meta.root, count: db.meta.count}})
+}
+
+// Update runs the write transaction, it is committed unless fn fails.
+func (db *DB) Update(fn func(tx *Tx) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tx := &Tx{
+		db:       db,
+		writable: true,
+		root:     child{pgid: db.meta.root, count: db.meta.count},
+		free:     slices.Clone(db.free),
+		high:     db.meta.high,
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+// node returns the node of the page, the nodes read are cached and must not be changed.
+func (db *DB) node(id pgid) (*node, error) {
+	if n, ok := db.cache.get(id); ok {
+		return n, nil
+	}
+	p, err := db.readPage(id)
+	if err != nil {
+		return nil, err
+	}
+	n, err := decodeNode(p)
+	if err != nil {
+		return nil, fmt.Errorf("page %d: %w", id, err)
+	}
+	db.cache.put(id, n)
+	return n, nil
+}
+
+// page is the header and the data of a page with its overflow pages.
+type page struct {
+	flags uint16
+	count int // the elements of the node
+	pages int
+	data  []byte
+}
+
+func (db *DB) readPage(id pgid) (page, error) {
+	b := make([]byte, pageSize)
+	if _, err := db.file.ReadAt(b, int64(id)*pageSize); err != nil {
+		return page{}, fmt.Errorf("read page %d: %s", id, err)
+	}
+	if pgid(binary.LittleEndian.Uint64(b)) != id {
+		return page{}, fmt.Errorf("%w: page %d has id %d", ErrCorrupt, id, binary.LittleEndian.Uint64(b))
+	}
+	p := page{
+		flags: binary.LittleEndian.Uint16(b[8:]),
+		count: int(binary.LittleEndian.Uint16(b[10:])),
+		pages: int(binary.LittleEndian.Uint32(b[12:])) + 1,
+	}
+	if p.pages > 1 {
+		b = append(b, make([]byte, (p.pages-1)*pageSize)...)
+		if _, err := db.file.ReadAt(b[pageSize:], int64(id+1)*pageSize); err != nil {
+			return page{}, fmt.Errorf("read page %d overflow: %s", id, err)
+		}
+	}
+	p.data = b[headerSize:]
+	return p, nil
+}
+
+// writePage writes the data to the page and the overflow pages it needs.
+func (db *DB) writePage(id pgid, flags uint16, count int, data []byte) error {
+	pages := pagesOf(len(data))
+	b := make([]byte, pages*pageSize)
+	binary.LittleEndian.PutUint64(b, uint64(id))
+	binary.LittleEndian.PutUint16(b[8:], flags)
+	binary.LittleEndian.PutUint16(b[10:], uint16(count))
+	binary.LittleEndian.PutUint32(b[12:], uint32(pages-1))
+	copy(b[headerSize:], data)
+	if _, err := db.file.WriteAt(b, int64(id)*pageSize); err != nil {
+		return fmt.Errorf("write page %d: %s", id, err)
+	}
+	return nil
+}
+
+func (db *DB) writeMeta(m meta) error {
+	b := binary.LittleEndian.AppendUint32(nil, magic)
+	b = binary.LittleEndian.AppendUint32(b, version)
+	b = binary.LittleEndian.AppendUint32(b, pageSize)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	for _, v := range []uint64{uint64(m.root), m.count, uint64(m.freelist), uint64(m.high), m.txid} {
+		b = binary.LittleEndian.AppendUint64(b, v)
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return db.writePage(pgid(m.txid%2), metaPage, 0, binary.LittleEndian.AppendUint64(b, h.Sum64()))
+}
+
+func decodeMeta(raw []byte) (meta, bool) {
+	const size = 16 + 5*8
+	if binary.LittleEndian.Uint16(raw[8:]) != metaPage {
+		return meta{}, false
+	}
+	b := raw[headerSize:]
+	h := fnv.New64a()
+	h.Write(b[:size])
+	if binary.LittleEndian.Uint64(b[size:]) != h.Sum64() ||
+		binary.LittleEndian.Uint32(b) != magic || binary.LittleEndian.Uint32(b[4:]) != version || binary.LittleEndian.Uint32(b[8:]) != pageSize {
+		return meta{}, false
+	}
+	v := func(i int) uint64 { return binary.LittleEndian.Uint64(b[16+i*8:]) }
+	return meta{root: pgid(v(0)), count: v(1), freelist: pgid(v(2)), high: pgid(v(3)), txid: v(4)}, true
+}
+
+// pagesOf returns the pages the data of the size takes with the page header.
+func pagesOf(size int) int {
+	return (headerSize + size + pageSize - 1) / pageSize
+}
+
+// cache keeps the nodes read recently, the least recently read one is evicted first.
+type cache struct {
+	mu    sync.Mutex
+	limit int
+	nodes map[pgid]*cacheEntry
+	head  cacheEntry // the most recent entry follows the head
+}
+
+type cacheEntry struct {
+	id         pgid
+	node       *node
+	prev, next *cacheEntry
+}
+
+func newCache(limit int) *cache {
+	c := &cache{limit: limit, nodes: make(map[pgid]*cacheEntry)}
+	c.head.prev, c.head.next = &c.head, &c.head
+	return c
+}
+
+func (c *cache) get(id pgid) (*node, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.nodes[id]
+	if !ok {
+		return nil, false
+	}
+	c.unlink(e)
+	c.link(e)
+	return e.node, true
+}
+
+func (c *cache) put(id pgid, n *node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.nodes[id]; ok {
+		c.unlink(e)
+	}
+	e := &cacheEntry{id: id, node: n}
+	c.nodes[id] = e
+	c.link(e)
+	for len(c.nodes) > c.limit {
+		last := c.head.prev
+		c.unlink(last)
+		delete(c.nodes, last.id)
+	}
+}
+
+// drop evicts the node of the page which is written anew.
+func (c *cache) drop(id pgid) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.nodes[id]; ok {
+		c.unlink(e)
+		delete(c.nodes, id)
+	}
+}
+
+func (c *cache) link(e *cacheEntry) {
+	e.prev, e.next = &c.head, c.head.next
+	c.head.next.prev = e
+	c.head.next = e
+}
+
+func (c *cache) unlink(e *cacheEntry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+}
@@ -0,0 +1,208 @@
+package kv_test
+
+import (
+	"15-puzzle/internal/repo/kv"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomWrites(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.db")
+	db := open(t, file)
+	rnd := rand.New(rand.NewPCG(1, 2))
+	expected := make(map[string][]byte)
+	for round := range 30 {
+		err := db.Update(func(tx *kv.Tx) error {
+			for range 300 {
+				key := []byte(fmt.Sprintf("key-%05d", rnd.IntN(3000)))
+				if rnd.IntN(3) == 0 {
+					delete(expected, string(key))
+					if err := tx.Delete(key); err != nil {
+						return err
+					}
+					continue
+				}
+				// the values of some keys outgrow a page
+				value := bytes.Repeat([]byte{byte(round)}, rnd.IntN(100)+[]int{0, 5000}[min(rnd.IntN(50), 1)^1])
+				expected[string(key)] = value
+				if err := tx.Put(key, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Update: %s", err)
+		}
+		if round%10 == 9 {
+			assert.NoError(t, db.Close())
+			db = open(t, file)
+		}
+		assertContent(t, db, expected)
+	}
+	assert.NoError(t, db.Close())
+}
+
+func TestDeleteAll(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.db")
+	db := open(t, file)
+	update(t, db, func(tx *kv.Tx) error {
+		for i := range 5000 {
+			if err := tx.Put(key(i), key(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	for from := 0; from < 5000; from += 1000 {
+		update(t, db, func(tx *kv.Tx) error {
+			for i := from; i < from+1000; i++ {
+				if err := tx.Delete(key(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	assertContent(t, db, map[string][]byte{})
+	update(t, db, func(tx *kv.Tx) error {
+		for i := range 5000 {
+			if err := tx.Put(key(i), key(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	grown, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, grown.Size(), 2*info.Size(), "freed pages should be written again")
+	assert.NoError(t, db.Close())
+}
+
+func TestScanAndCount(t *testing.T) {
+	db := open(t, filepath.Join(t.TempDir(), "data.db"))
+	defer db.Close()
+	update(t, db, func(tx *kv.Tx) error {
+		for i := 0; i < 10000; i += 2 {
+			if err := tx.Put(key(i), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, db.View(func(tx *kv.Tx) error {
+		for _, r := range []struct{ start, end, count int }{{0, 10000, 5000}, {1, 5, 2}, {100, 101, 1}, {101, 102, 0}, {9000, 20000, 500}} {
+			count, err := tx.Count(key(r.start), key(r.end))
+			assert.NoError(t, err)
+			assert.Equal(t, r.count, count, "count of [%d, %d)", r.start, r.end)
+			var keys [][]byte
+			assert.NoError(t, tx.Scan(key(r.start), key(r.end), func(k, v []byte) bool {
+				keys = append(keys, k)
+				return true
+			}))
+			assert.Len(t, keys, r.count, "scan of [%d, %d)", r.start, r.end)
+			assert.True(t, slices.IsSortedFunc(keys, bytes.Compare))
+		}
+		count, err := tx.Count(nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 5000, count)
+
+		var first []byte
+		assert.NoError(t, tx.Scan(key(31), nil, func(k, v []byte) bool {
+			first = k
+			return false
+		}))
+		assert.Equal(t, key(32), first, "scan should stop")
+		return nil
+	}))
+}
+
+func TestRollback(t *testing.T) {
+	db := open(t, filepath.Join(t.TempDir(), "data.db"))
+	defer db.Close()
+	update(t, db, func(tx *kv.Tx) error { return tx.Put([]byte("a"), []byte("1")) })
+	failed := fmt.Errorf("failed")
+	assert.ErrorIs(t, db.Update(func(tx *kv.Tx) error {
+		if err := tx.Put([]byte("a"), []byte("2")); err != nil {
+			return err
+		}
+		return failed
+	}), failed)
+	assertContent(t, db, map[string][]byte{"a": []byte("1")})
+	assert.ErrorIs(t, db.View(func(tx *kv.Tx) error { return tx.Put([]byte("b"), nil) }), kv.ErrReadOnly)
+}
+
+// TestTornMeta expects the transaction committed before the meta page of the last one is torn.
+func TestTornMeta(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.db")
+	db := open(t, file)
+	update(t, db, func(tx *kv.Tx) error { return tx.Put([]byte("a"), []byte("1")) })
+	update(t, db, func(tx *kv.Tx) error { return tx.Put([]byte("a"), []byte("2")) })
+	assert.NoError(t, db.Close())
+
+	// the transactions are of the ids 2 and 3, the last meta page is the second one
+	f, err := os.OpenFile(file, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	_, err = f.WriteAt(binary.LittleEndian.AppendUint64(nil, 0xdead), 4096+40)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	db = open(t, file)
+	assertContent(t, db, map[string][]byte{"a": []byte("1")})
+	assert.NoError(t, db.Close())
+}
+
+func open(t *testing.T, file string) *kv.DB {
+	db, err := kv.Open(file, kv.Options{NoSync: true, CacheNodes: 64})
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	return db
+}
+
+func update(t *testing.T, db *kv.DB, fn func(tx *kv.Tx) error) {
+	if err := db.Update(fn); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+}
+
+func key(i int) []byte {
+	return []byte(fmt.Sprintf("key-%05d", i))
+}
+
+func assertContent(t *testing.T, db *kv.DB, expected map[string][]byte) {
+	assert.NoError(t, db.View(func(tx *kv.Tx) error {
+		var keys []string
+		if err := tx.Scan(nil, nil, func(k, v []byte) bool {
+			keys = append(keys, string(k))
+			assert.Equal(t, expected[string(k)], v, "value of %s", k)
+			return true
+		}); err != nil {
+			return err
+		}
+		assert.Equal(t, slices.Sorted(maps.Keys(expected)), keys)
+		for k, v := range expected {
+			actual, ok, err := tx.Get([]byte(k))
+			assert.NoError(t, err)
+			assert.True(t, ok, "key %s", k)
+			assert.Equal(t, v, actual)
+		}
+		count, err := tx.Count(nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, len(expected), count)
+		return nil
+	}))
+}
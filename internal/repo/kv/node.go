@@ -0,0 +1,117 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// node is a leaf of the keys and their values, or a branch of the children with the first keys of their subtrees.
+type node struct {
+	leaf     bool
+	keys     [][]byte
+	values   [][]byte // of the leaf
+	children []child  // of the branch
+	pages    int      // the pages the node was read from
+}
+
+// child is a subtree of the branch, the node is set once the subtree is changed by the transaction.
+type child struct {
+	pgid  pgid
+	count uint64 // the keys of the subtree as written
+	node  *node
+}
+
+// search returns the position of the first key not less than the key, and whether it is the key.
+func (n *node) search(key []byte) (int, bool) {
+	return slices.BinarySearchFunc(n.keys, key, bytes.Compare)
+}
+
+// childIndex returns the child of the branch the key belongs to, the first one for the keys before all of them.
+func (n *node) childIndex(key []byte) int {
+	i := sort.Search(len(n.keys), func(i int) bool { return bytes.Compare(n.keys[i], key) > 0 }) - 1
+	return max(i, 0)
+}
+
+func (n *node) clone() *node {
+	return &node{leaf: n.leaf, keys: slices.Clone(n.keys), values: slices.Clone(n.values), children: slices.Clone(n.children)}
+}
+
+// size returns the size of the node data without the page header.
+func (n *node) size() int {
+	size := 0
+	for i, k := range n.keys {
+		if n.leaf {
+			size += 8 + len(k) + len(n.values[i])
+		} else {
+			size += 20 + len(k)
+		}
+	}
+	return size
+}
+
+// encode writes the leaf elements as the key and value sizes, the key and the value,
+// and the branch elements as the page, the count of keys and the key size, and the key.
+func (n *node) encode() []byte {
+	b := make([]byte, 0, n.size())
+	for i, k := range n.keys {
+		if n.leaf {
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(k)))
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(n.values[i])))
+			b = append(append(b, k...), n.values[i]...)
+		} else {
+			b = binary.LittleEndian.AppendUint64(b, uint64(n.children[i].pgid))
+			b = binary.LittleEndian.AppendUint64(b, n.children[i].count)
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(k)))
+			b = append(b, k...)
+		}
+	}
+	return b
+}
+
+func decodeNode(p page) (*node, error) {
+	if p.flags != leafPage && p.flags != branchPage {
+		return nil, fmt.Errorf("%w: flags %d of no node", ErrCorrupt, p.flags)
+	}
+	n := &node{leaf: p.flags == leafPage, keys: make([][]byte, p.count), pages: p.pages}
+	if n.leaf {
+		n.values = make([][]byte, p.count)
+	} else {
+		n.children = make([]child, p.count)
+	}
+	b := p.data
+	// next returns the next bytes of the data, nil when the data is short
+	next := func(size int) []byte {
+		if size > len(b) {
+			return nil
+		}
+		v := b[:size:size]
+		b = b[size:]
+		return v
+	}
+	for i := range p.count {
+		if n.leaf {
+			sizes := next(8)
+			if sizes == nil {
+				return nil, fmt.Errorf("%w: leaf element %d of %d", ErrCorrupt, i, p.count)
+			}
+			n.keys[i] = next(int(binary.LittleEndian.Uint32(sizes)))
+			n.values[i] = next(int(binary.LittleEndian.Uint32(sizes[4:])))
+			if n.keys[i] == nil || n.values[i] == nil {
+				return nil, fmt.Errorf("%w: leaf element %d of %d", ErrCorrupt, i, p.count)
+			}
+		} else {
+			head := next(20)
+			if head == nil {
+				return nil, fmt.Errorf("%w: branch element %d of %d", ErrCorrupt, i, p.count)
+			}
+			n.children[i] = child{pgid: pgid(binary.LittleEndian.Uint64(head)), count: binary.LittleEndian.Uint64(head[8:])}
+			if n.keys[i] = next(int(binary.LittleEndian.Uint32(head[16:]))); n.keys[i] == nil {
+				return nil, fmt.Errorf("%w: branch element %d of %d", ErrCorrupt, i, p.count)
+			}
+		}
+	}
+	return n, nil
+}
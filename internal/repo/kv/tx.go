@@ -0,0 +1,423 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+var ErrReadOnly = errors.New("kv: write of the read transaction")
+
+// Tx is a transaction of the store. The keys and values it returns must not be changed,
+// the ones of a write transaction are valid until it is committed.
+type Tx struct {
+	db       *DB
+	writable bool
+	root     child
+	free     []pgid // the pages free for the writes, in order
+	pending  []pgid // the pages the transaction frees, free once it is committed
+	high     pgid
+	changed  bool
+}
+
+// Get returns the value of the key, it reports whether the key is found.
+func (tx *Tx) Get(key []byte) ([]byte, bool, error) {
+	c := tx.root
+	for {
+		n, err := tx.node(&c)
+		if err != nil {
+			return nil, false, err
+		}
+		if n.leaf {
+			i, found := n.search(key)
+			if !found {
+				return nil, false, nil
+			}
+			return n.values[i], true, nil
+		}
+		if len(n.children) == 0 {
+			return nil, false, nil
+		}
+		c = n.children[n.childIndex(key)]
+	}
+}
+
+// Put sets the value of the key.
+func (tx *Tx) Put(key, value []byte) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	tx.changed = true
+	return tx.put(&tx.root, bytes.Clone(key), bytes.Clone(value))
+}
+
+func (tx *Tx) put(c *child, key, value []byte) error {
+	n, err := tx.writableNode(c)
+	if err != nil {
+		return err
+	}
+	if n.leaf {
+		i, found := n.search(key)
+		if found {
+			n.values[i] = value
+			return nil
+		}
+		n.keys = slices.Insert(n.keys, i, key)
+		n.values = slices.Insert(n.values, i, value)
+		return nil
+	}
+	i := n.childIndex(key)
+	if err := tx.put(&n.children[i], key, value); err != nil {
+		return err
+	}
+	if bytes.Compare(key, n.keys[i]) < 0 {
+		n.keys[i] = key
+	}
+	return nil
+}
+
+// Delete removes the key, a missing key is not an error.
+func (tx *Tx) Delete(key []byte) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	if _, found, err := tx.Get(key); err != nil || !found {
+		return err
+	}
+	tx.changed = true
+	if err := tx.delete(&tx.root, key); err != nil {
+		return err
+	}
+	// the root of a single child is dropped, the tree of no keys is an empty leaf
+	for tx.root.node != nil && !tx.root.node.leaf {
+		switch len(tx.root.node.children) {
+		case 0:
+			tx.root = child{node: &node{leaf: true}}
+		case 1:
+			tx.root = tx.root.node.children[0]
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (tx *Tx) delete(c *child, key []byte) error {
+	n, err := tx.writableNode(c)
+	if err != nil {
+		return err
+	}
+	if n.leaf {
+		if i, found := n.search(key); found {
+			n.keys = slices.Delete(n.keys, i, i+1)
+			n.values = slices.Delete(n.values, i, i+1)
+		}
+		return nil
+	}
+	i := n.childIndex(key)
+	if err := tx.delete(&n.children[i], key); err != nil {
+		return err
+	}
+	cn := n.children[i].node
+	switch {
+	case len(cn.keys) == 0:
+		n.keys = slices.Delete(n.keys, i, i+1)
+		n.children = slices.Delete(n.children, i, i+1)
+	case cn.size() < mergeSize && len(n.children) > 1:
+		// the small child is merged with its sibling, the merged one is split on commit when it outgrows a page
+		if i == len(n.children)-1 {
+			i--
+		}
+		left, err := tx.writableNode(&n.children[i])
+		if err != nil {
+			return err
+		}
+		right, err := tx.writableNode(&n.children[i+1])
+		if err != nil {
+			return err
+		}
+		left.keys = append(left.keys, right.keys...)
+		left.values = append(left.values, right.values...)
+		left.children = append(left.children, right.children...)
+		n.keys = slices.Delete(n.keys, i+1, i+2)
+		n.children = slices.Delete(n.children, i+1, i+2)
+	}
+	return nil
+}
+
+// Scan calls fn with the keys from the start up to the end in order, the end is excluded and nil ends with the last key.
+// The scan stops once fn returns false.
+func (tx *Tx) Scan(start, end []byte, fn func(key, value []byte) bool) error {
+	_, err := tx.scan(tx.root, start, end, fn)
+	return err
+}
+
+func (tx *Tx) scan(c child, start, end []byte, fn func(key, value []byte) bool) (bool, error) {
+	n, err := tx.node(&c)
+	if err != nil {
+		return false, err
+	}
+	if n.leaf {
+		i, _ := n.search(start)
+		for ; i < len(n.keys); i++ {
+			if end != nil && bytes.Compare(n.keys[i], end) >= 0 || !fn(n.keys[i], n.values[i]) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	for i := n.childIndex(start); i < len(n.children); i++ {
+		if end != nil && bytes.Compare(n.keys[i], end) >= 0 {
+			return false, nil
+		}
+		if more, err := tx.scan(n.children[i], start, end, fn); err != nil || !more {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Count returns the number of the keys from the start up to the end, the end is excluded and nil ends with the last key.
+// The keys are counted by the subtrees in logarithmic time.
+func (tx *Tx) Count(start, end []byte) (int, error) {
+	from, err := tx.rank(tx.root, start)
+	if err != nil {
+		return 0, err
+	}
+	to := tx.count(tx.root)
+	if end != nil {
+		if to, err = tx.rank(tx.root, end); err != nil {
+			return 0, err
+		}
+	}
+	return max(to-from, 0), nil
+}
+
+// rank returns the number of the keys less than the key.
+func (tx *Tx) rank(c child, key []byte) (int, error) {
+	rank := 0
+	for {
+		n, err := tx.node(&c)
+		if err != nil {
+			return 0, err
+		}
+		if n.leaf {
+			i, _ := n.search(key)
+			return rank + i, nil
+		}
+		if len(n.children) == 0 {
+			return rank, nil
+		}
+		i := n.childIndex(key)
+		for _, prev := range n.children[:i] {
+			rank += tx.count(prev)
+		}
+		c = n.children[i]
+	}
+}
+
+// count returns the number of the keys of the subtree, the changed subtrees are counted by their nodes.
+func (tx *Tx) count(c child) int {
+	if c.node == nil {
+		return int(c.count)
+	}
+	if c.node.leaf {
+		return len(c.node.keys)
+	}
+	count := 0
+	for _, cc := range c.node.children {
+		count += tx.count(cc)
+	}
+	return count
+}
+
+func (tx *Tx) node(c *child) (*node, error) {
+	if c.node != nil {
+		return c.node, nil
+	}
+	return tx.db.node(c.pgid)
+}
+
+// writableNode returns the node of the subtree for the change, its pages are freed once it is read to be written anew.
+func (tx *Tx) writableNode(c *child) (*node, error) {
+	if c.node != nil {
+		return c.node, nil
+	}
+	n, err := tx.db.node(c.pgid)
+	if err != nil {
+		return nil, err
+	}
+	tx.release(c.pgid, n.pages)
+	c.node = n.clone()
+	return c.node, nil
+}
+
+func (tx *Tx) release(id pgid, pages int) {
+	for i := range pgid(pages) {
+		tx.pending = append(tx.pending, id+i)
+	}
+}
+
+// allocate returns the first of the free pages in a row, the file is extended when there are none.
+func (tx *Tx) allocate(pages int) pgid {
+	for i := 0; i+pages <= len(tx.free); i++ {
+		if tx.free[i+pages-1] == tx.free[i]+pgid(pages-1) {
+			id := tx.free[i]
+			tx.free = slices.Delete(tx.free, i, i+pages)
+			return id
+		}
+	}
+	id := tx.high
+	tx.high += pgid(pages)
+	return id
+}
+
+// commit writes the changed nodes and the freelist to the free pages, and then the meta page of the new root.
+func (tx *Tx) commit() error {
+	if !tx.changed {
+		return nil
+	}
+	root := tx.root
+	if root.node != nil {
+		elems, err := tx.spill(root.node)
+		if err != nil {
+			return err
+		}
+		for len(elems) > 1 {
+			branch := &node{}
+			for _, e := range elems {
+				branch.keys = append(branch.keys, e.key)
+				branch.children = append(branch.children, e.child)
+			}
+			if elems, err = tx.spill(branch); err != nil {
+				return err
+			}
+		}
+		if len(elems) > 0 {
+			root = elems[0].child
+		} else {
+			root = child{pgid: tx.allocate(1)}
+			if err := tx.write(root.pgid, leafPage, 0, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	db := tx.db
+	tx.release(db.meta.freelist, pagesOf(8+8*len(db.free)))
+	freelist := tx.allocate(pagesOf(8 + 8*(len(tx.free)+len(tx.pending))))
+	free := append(slices.Clone(tx.free), tx.pending...)
+	slices.Sort(free)
+	b := binary.LittleEndian.AppendUint64(nil, uint64(len(free)))
+	for _, id := range free {
+		b = binary.LittleEndian.AppendUint64(b, uint64(id))
+	}
+	if err := tx.write(freelist, freelistPage, 0, b); err != nil {
+		return err
+	}
+	if err := db.sync(); err != nil {
+		return err
+	}
+
+	m := meta{root: root.pgid, count: root.count, freelist: freelist, high: tx.high, txid: db.meta.txid + 1}
+	if err := db.writeMeta(m); err != nil {
+		return err
+	}
+	if err := db.sync(); err != nil {
+		return err
+	}
+	db.meta, db.free = m, free
+	return nil
+}
+
+// element is a subtree of a branch by its first key.
+type element struct {
+	key   []byte
+	child child
+}
+
+// spill writes the changed node with its changed subtrees, the node is split into the nodes of a page
+// unless an element outgrows it. The node of no keys is dropped.
+func (tx *Tx) spill(n *node) ([]element, error) {
+	if !n.leaf {
+		var keys [][]byte
+		var children []child
+		for i, c := range n.children {
+			if c.node == nil {
+				keys, children = append(keys, n.keys[i]), append(children, c)
+				continue
+			}
+			elems, err := tx.spill(c.node)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range elems {
+				keys, children = append(keys, e.key), append(children, e.child)
+			}
+		}
+		n.keys, n.children = keys, children
+	}
+	if len(n.keys) == 0 {
+		return nil, nil
+	}
+
+	var elems []element
+	for _, part := range n.split() {
+		flags, count := leafPage, uint64(len(part.keys))
+		if !part.leaf {
+			flags, count = branchPage, 0
+			for _, c := range part.children {
+				count += c.count
+			}
+		}
+		data := part.encode()
+		part.pages = pagesOf(len(data))
+		id := tx.allocate(part.pages)
+		if err := tx.write(id, flags, len(part.keys), data); err != nil {
+			return nil, err
+		}
+		tx.db.cache.put(id, part)
+		elems = append(elems, element{key: part.keys[0], child: child{pgid: id, count: count}})
+	}
+	return elems, nil
+}
+
+// split divides the node outgrowing a page into the nodes filled up to the fillSize,
+// a branch keeps two children at least.
+func (n *node) split() []*node {
+	if n.size() <= pageSize-headerSize {
+		return []*node{n}
+	}
+	minElems := 1
+	if !n.leaf {
+		minElems = 2
+	}
+	var parts []*node
+	part, size := &node{leaf: n.leaf}, 0
+	for i, k := range n.keys {
+		esize := 20 + len(k)
+		if n.leaf {
+			esize = 8 + len(k) + len(n.values[i])
+		}
+		if len(part.keys) >= minElems && size+esize > fillSize && len(n.keys)-i >= minElems {
+			parts = append(parts, part)
+			part, size = &node{leaf: n.leaf}, 0
+		}
+		part.keys = append(part.keys, k)
+		if n.leaf {
+			part.values = append(part.values, n.values[i])
+		} else {
+			part.children = append(part.children, n.children[i])
+		}
+		size += esize
+	}
+	return append(parts, part)
+}
+
+func (tx *Tx) write(id pgid, flags uint16, count int, data []byte) error {
+	for i := range pgid(pagesOf(len(data))) {
+		tx.db.cache.drop(id + i)
+	}
+	return tx.db.writePage(id, flags, count, data)
+}
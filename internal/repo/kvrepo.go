@@ -0,0 +1,893 @@
+package repo
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/repo/kv"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The records of the KVRepo are kept by the keys of the record kind followed by the fields in order,
+// the numbers are big-endian and the boards end with zero, so the keys of a board or a player are scanned in order.
+const (
+	recUser       = 'u' // board, user id: the results of the user on the board
+	recPlayer     = 'p' // user id: the user known by the results on a game board
+	recGame       = 'g' // game id: the game
+	recUserGame   = 'h' // user id, game id: the game of the user
+	recStart      = 's' // board, start time, game id: the game started on the board
+	recFinish     = 'f' // board, finish time, game id: the game solved on the board
+	recStarted    = 'a' // start time, game id: the game not finished yet, abandoned once started AbandonAfter ago
+	recQuarantine = 'q' // game id: the quarantined game
+	recRank       = 'r' // board, ranking, ranked values, user id: the player in the order of the ranking
+	recCounter    = 'c' // name: the count of the monitoring
+	recSetting    = 'm' // name: the setting the records are written by
+)
+
+// kvSchemaVersion is the version of the records of the KVRepo.
+const kvSchemaVersion = 1
+
+// KVSuffix is the suffix of the B+tree store kept next to the data file.
+const KVSuffix = ".btree"
+
+// KVRepo keeps the data in the B+tree store of a file, the players are ranked by the secondary index of every ranking
+// and the games are indexed by their times, so the reads scan the ranges of the keys instead of loading all the data.
+type KVRepo struct {
+	db        *kv.DB
+	rankings  []Ranking
+	policy    Sync
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewKVRepo opens the store of the data file kept next to it with the KVSuffix, players are ranked by DefaultRankings
+// unless other rankings are given. The store missing is imported once from the data file and the journal of the FileRepo
+// migrated to the SchemaVersion, the files are kept. The players are indexed anew when the rankings differ from the ones of the store.
+// The store is synced by the policy in background until the context is done or the repository is closed.
+func NewKVRepo(ctx context.Context, dataFile string, policy Sync, rankings ...Ranking) (*KVRepo, error) {
+	if len(rankings) == 0 {
+		rankings = defaultRankings()
+	}
+	file := dataFile + KVSuffix
+	data, err := fileData(ctx, dataFile, file)
+	if err != nil {
+		return nil, err
+	}
+	db, err := kv.Open(file, kv.Options{NoSync: policy != SyncAlways})
+	if err != nil {
+		return nil, err
+	}
+	r := &KVRepo{db: db, rankings: rankings, policy: policy, done: make(chan struct{}), stopped: make(chan struct{})}
+	if err := db.Update(func(tx *kv.Tx) error {
+		t := kvTx{tx, r}
+		if err := r.open(t); err != nil || data == nil {
+			return err
+		}
+		return t.importData(data)
+	}); err != nil {
+		err = errors.Join(err, db.Close())
+		if data != nil {
+			// the store is imported anew by the next open
+			err = errors.Join(err, os.Remove(file))
+		}
+		return nil, err
+	}
+	if data != nil {
+		// the store is the only copy of the writes from now on
+		if err := db.Sync(); err != nil {
+			return nil, errors.Join(err, db.Close(), os.Remove(file))
+		}
+		slog.Info(fmt.Sprintf("%s imported from %s: %d games", file, dataFile, len(data.Games)))
+	}
+	go r.background(ctx)
+	return r, nil
+}
+
+// fileData returns the data of the FileRepo to import to the store missing, nil when the store exists or there is no data file.
+func fileData(ctx context.Context, dataFile, store string) (*model.Data, error) {
+	if _, err := os.Stat(store); !os.IsNotExist(err) {
+		return nil, err
+	}
+	if _, err := os.Stat(dataFile); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	r, err := NewFileRepo(ctx, dataFile, SyncNever)
+	if err != nil {
+		return nil, fmt.Errorf("import %s: %s", dataFile, err)
+	}
+	if err := r.Close(); err != nil {
+		return nil, fmt.Errorf("import %s: %s", dataFile, err)
+	}
+	return r.data, nil
+}
+
+// open checks the schema version of the records and indexes the players by the rankings.
+func (r *KVRepo) open(t kvTx) error {
+	var version int
+	if ok, err := t.getJSON(settingKey("version"), &version); err != nil {
+		return err
+	} else if !ok {
+		version = kvSchemaVersion
+		if err := t.putJSON(settingKey("version"), &version); err != nil {
+			return err
+		}
+	}
+	if version != kvSchemaVersion {
+		return fmt.Errorf("kv schema version %d, expected %d", version, kvSchemaVersion)
+	}
+
+	defs := make([]string, len(r.rankings))
+	for i, rk := range r.rankings {
+		defs[i] = rk.String()
+	}
+	rankings := strings.Join(defs, ";")
+	var indexed string
+	if _, err := t.getJSON(settingKey("rankings"), &indexed); err != nil {
+		return err
+	}
+	if indexed == rankings {
+		return nil
+	}
+	slog.Info(fmt.Sprintf("players indexed by rankings %q", rankings))
+	if err := t.reindex(); err != nil {
+		return err
+	}
+	return t.putJSON(settingKey("rankings"), &rankings)
+}
+
+// background syncs the store by the SyncInterval policy.
+func (r *KVRepo) background(ctx context.Context) {
+	defer close(r.stopped)
+	var tick <-chan time.Time
+	if r.policy == SyncInterval {
+		t := time.NewTicker(syncInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.done:
+			return
+		case <-tick:
+			if err := r.db.Sync(); err != nil {
+				slog.Error(fmt.Sprintf("store sync: %s", err))
+			}
+		}
+	}
+}
+
+// Close stops the background sync and closes the store.
+func (r *KVRepo) Close() error {
+	err := errors.New("repository closed")
+	r.closeOnce.Do(func() {
+		close(r.done)
+		<-r.stopped
+		err = r.db.Close()
+	})
+	return err
+}
+
+func (r *KVRepo) view(ctx context.Context, fn func(t kvTx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.View(func(tx *kv.Tx) error { return fn(kvTx{tx, r}) })
+}
+
+func (r *KVRepo) update(ctx context.Context, fn func(t kvTx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *kv.Tx) error { return fn(kvTx{tx, r}) })
+}
+
+func (r *KVRepo) Monitoring(ctx context.Context) (model.Monitoring, error) {
+	var m model.Monitoring
+	err := r.view(ctx, func(t kvTx) error {
+		var err error
+		if m.Users, err = t.Count(prefix(recPlayer), prefixEnd(prefix(recPlayer))); err != nil {
+			return err
+		}
+		for name, v := range map[string]*int{"games_started": &m.GamesStarted, "games_solved": &m.GamesSolved, "games_rejected": &m.GamesRejected} {
+			if _, err := t.getJSON(counterKey(name), v); err != nil {
+				return err
+			}
+		}
+		// the started games are abandoned once started before the time, the times are kept in seconds
+		abandoned := time.Now().Add(-model.AbandonAfter)
+		end := timeKey(prefix(recStarted), abandoned)
+		if abandoned.Nanosecond() > 0 {
+			end = timeKey(prefix(recStarted), abandoned.Add(time.Second))
+		}
+		if m.GamesAbandoned, err = t.Count(prefix(recStarted), end); err != nil {
+			return err
+		}
+		m.GamesQuarantined, err = t.Count(prefix(recQuarantine), prefixEnd(prefix(recQuarantine)))
+		return err
+	})
+	return m, err
+}
+
+// Stats returns user's results on the leaderboard. A user known by results on other leaderboards has empty results.
+func (r *KVRepo) Stats(ctx context.Context, UserID int, lb model.Leaderboard) (model.User, error) {
+	var u model.User
+	err := r.view(ctx, func(t kvTx) error {
+		ok, err := t.user(gameBoard(lb), UserID, &u)
+		if err != nil || ok {
+			return err
+		}
+		if _, ok, err = t.Get(playerKey(UserID)); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("%w: user_id=%d", model.ErrUserNotFound, UserID)
+		}
+		u = model.User{UserID: UserID}
+		return nil
+	})
+	return u, err
+}
+
+func (r *KVRepo) AddUser(ctx context.Context, UserID int) error {
+	return r.update(ctx, func(t kvTx) error {
+		return t.withUser(gameBoard(model.Leaderboard{Size: board.Classic}), UserID, func(*model.User) {})
+	})
+}
+
+// RegisterGameStart keeps the new game of the scramble and counts it on the leaderboard.
+func (r *KVRepo) RegisterGameStart(ctx context.Context, UserID int, lb model.Leaderboard, scramble string) (model.User, model.Game, error) {
+	var result model.User
+	var game model.Game
+	err := r.update(ctx, func(t kvTx) error {
+		var games int
+		if _, err := t.getJSON(counterKey("games"), &games); err != nil {
+			return err
+		}
+		games++
+		if err := t.putJSON(counterKey("games"), &games); err != nil {
+			return err
+		}
+		ts := model.JSONTimestamp(time.Now().UTC())
+		game = model.Game{
+			ID:       games,
+			UserID:   UserID,
+			Size:     lb.Size.String(),
+			Tier:     lb.Tier,
+			Metric:   lb.Metric,
+			Scramble: scramble,
+			Status:   model.GameStarted,
+			Start:    &ts,
+		}
+		if err := t.putGame(nil, &game); err != nil {
+			return err
+		}
+		return t.withUser(gameBoard(lb), UserID, func(u *model.User) {
+			u.GamesStarted++
+			u.LastStartTime = game.Start
+			result = *u
+		})
+	})
+	return result, game, err
+}
+
+// Game returns the game of the user by id.
+func (r *KVRepo) Game(ctx context.Context, UserID, gameID int) (model.Game, error) {
+	var g model.Game
+	err := r.view(ctx, func(t kvTx) error { return t.game(UserID, gameID, &g) })
+	return g, err
+}
+
+// Games returns the games of the user in order of start.
+func (r *KVRepo) Games(ctx context.Context, UserID int) ([]model.Game, error) {
+	var games []model.Game
+	err := r.view(ctx, func(t kvTx) error {
+		var ids []int
+		if err := t.Scan(userGameKey(UserID, 0), userGameKey(UserID+1, 0), func(k, _ []byte) bool {
+			ids = append(ids, int(binary.BigEndian.Uint64(k[len(k)-8:])))
+			return true
+		}); err != nil {
+			return err
+		}
+		now := time.Now()
+		var err error
+		if games, err = t.games(ids); err != nil {
+			return err
+		}
+		for i := range games {
+			games[i].Status = games[i].StatusAt(now)
+		}
+		return nil
+	})
+	return games, err
+}
+
+// RegisterGameSolve finishes the started game of the user on the leaderboard and counts it, a game is solved once.
+func (r *KVRepo) RegisterGameSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int, solve model.Solve) (model.User, error) {
+	return r.registerSolve(ctx, UserID, lb, gameID, solve, nil)
+}
+
+// RegisterQuarantinedSolve finishes the started game as quarantined with the recording for its review, the game is not counted.
+func (r *KVRepo) RegisterQuarantinedSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int, solve model.Solve, suspicion model.Suspicion) (model.User, error) {
+	return r.registerSolve(ctx, UserID, lb, gameID, solve, &suspicion)
+}
+
+func (r *KVRepo) registerSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int, solve model.Solve, suspicion *model.Suspicion) (model.User, error) {
+	var result model.User
+	err := r.update(ctx, func(t kvTx) error {
+		var g model.Game
+		if err := t.game(UserID, gameID, &g); err != nil {
+			return err
+		}
+		switch {
+		case g.Status != model.GameStarted:
+			return fmt.Errorf("%w: %s user_id=%d game_id=%d", model.ErrGameNotStarted, g.Status, UserID, gameID)
+		case g.Size != lb.Size.String() || g.Tier != lb.Tier || g.Metric != lb.Metric:
+			return fmt.Errorf("%w than %s: user_id=%d game_id=%d", model.ErrOtherLeaderboard, lb, UserID, gameID)
+		}
+		old := g
+		if suspicion != nil {
+			finish(&g, model.GameQuarantined)
+			g.Suspicion, g.Recording = suspicion, &solve.Recording
+		} else {
+			finish(&g, model.GameSolved)
+		}
+		g.Optimal = solve.Optimal
+		g.Moves = lb.Metric.Count(solve.Recording.Moves)
+		g.Duration = solve.Duration
+		g.Hints = solve.Hints
+		if err := t.putGame(&old, &g); err != nil {
+			return err
+		}
+		return t.withUser(gameBoard(lb), UserID, func(u *model.User) {
+			u.LastStartTime = nil
+			if suspicion == nil && countSolve(u, g) {
+				u.BestGame = &solve.Recording
+			}
+			result = *u
+		})
+	})
+	return result, err
+}
+
+// RegisterRejectedSolve counts the solve which failed the verification, the started game of the id is rejected.
+func (r *KVRepo) RegisterRejectedSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int) error {
+	return r.update(ctx, func(t kvTx) error {
+		var g model.Game
+		if err := t.game(UserID, gameID, &g); err != nil && !errors.Is(err, model.ErrGameNotFound) {
+			return err
+		} else if err == nil && g.Status == model.GameStarted {
+			old := g
+			finish(&g, model.GameRejected)
+			if err := t.putGame(&old, &g); err != nil {
+				return err
+			}
+		}
+		return t.withUser(gameBoard(lb), UserID, func(u *model.User) { u.GamesRejected++ })
+	})
+}
+
+// Quarantine returns the quarantined games in order of start.
+func (r *KVRepo) Quarantine(ctx context.Context) ([]model.Game, error) {
+	var games []model.Game
+	err := r.view(ctx, func(t kvTx) error {
+		var ids []int
+		if err := t.Scan(prefix(recQuarantine), prefixEnd(prefix(recQuarantine)), func(k, _ []byte) bool {
+			ids = append(ids, int(binary.BigEndian.Uint64(k[1:])))
+			return true
+		}); err != nil {
+			return err
+		}
+		var err error
+		games, err = t.games(ids)
+		return err
+	})
+	return games, err
+}
+
+// ReviewGame counts the quarantined game as solved when it is approved, otherwise the game is rejected.
+// The daily challenge board does not count approved games.
+func (r *KVRepo) ReviewGame(ctx context.Context, gameID int, approve bool) (model.Game, error) {
+	var game model.Game
+	err := r.update(ctx, func(t kvTx) error {
+		if ok, err := t.getJSON(gameKey(gameID), &game); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("%w: game_id=%d", model.ErrGameNotFound, gameID)
+		}
+		if game.Status != model.GameQuarantined {
+			return fmt.Errorf("%w: %s game_id=%d", model.ErrNotQuarantined, game.Status, gameID)
+		}
+		size, err := board.ParseSize(game.Size)
+		if err != nil {
+			return fmt.Errorf("game_id=%d size: %s", gameID, err)
+		}
+		old := game
+		if approve {
+			game.Status = model.GameSolved
+		} else {
+			game.Status = model.GameRejected
+		}
+		if err := t.putGame(&old, &game); err != nil {
+			return err
+		}
+		return t.withUser(gameBoard(model.Leaderboard{Size: size, Tier: game.Tier, Metric: game.Metric}), game.UserID, func(u *model.User) {
+			if !approve {
+				u.GamesRejected++
+			} else if countSolve(u, game) {
+				u.BestGame = game.Recording
+			}
+		})
+	})
+	return game, err
+}
+
+// Top returns the first players of the leaderboard in the order of every ranking, all of them when the limit is negative.
+// The players are scanned in the order of the index.
+func (r *KVRepo) Top(ctx context.Context, lb model.Leaderboard, limit int) ([]model.Rating, error) {
+	ratings := make([]model.Rating, len(r.rankings))
+	err := r.view(ctx, func(t kvTx) error {
+		for i, rk := range r.rankings {
+			ratings[i] = model.Rating{Ranking: rk.Name, UserIDs: []int{}}
+			start := rankPrefix(gameBoard(lb), i)
+			if err := t.Scan(start, prefixEnd(start), func(k, _ []byte) bool {
+				ratings[i].UserIDs = append(ratings[i].UserIDs, int(binary.BigEndian.Uint64(k[len(k)-8:])))
+				return limit < 0 || len(ratings[i].UserIDs) < limit
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ratings, err
+}
+
+// Ranks returns the positions of the player on the leaderboard by every ranking, the main one first.
+func (r *KVRepo) Ranks(ctx context.Context, UserID int, lb model.Leaderboard) ([]model.Rank, error) {
+	return r.ranks(ctx, gameBoard(lb), UserID)
+}
+
+// DailyStats returns user's results of the daily challenge, a user who has not solved it yet has empty results.
+func (r *KVRepo) DailyStats(ctx context.Context, UserID int, day string, lb model.Leaderboard) (model.User, error) {
+	u := model.User{UserID: UserID}
+	err := r.view(ctx, func(t kvTx) error {
+		_, err := t.user(dailyBoard(day, lb), UserID, &u)
+		return err
+	})
+	return u, err
+}
+
+// RegisterDailySolve counts the solved daily challenge.
+func (r *KVRepo) RegisterDailySolve(ctx context.Context, UserID int, day string, lb model.Leaderboard, solve model.Solve) (model.User, error) {
+	var result model.User
+	ts := model.JSONTimestamp(time.Now().UTC())
+	game := model.Game{Status: model.GameSolved, Finish: &ts, Moves: lb.Metric.Count(solve.Recording.Moves), Duration: solve.Duration, Hints: solve.Hints}
+	err := r.update(ctx, func(t kvTx) error {
+		return t.withUser(dailyBoard(day, lb), UserID, func(u *model.User) {
+			u.GamesStarted++
+			if countSolve(u, game) {
+				u.BestGame = &solve.Recording
+			}
+			result = *u
+		})
+	})
+	return result, err
+}
+
+// DailyRanks returns the positions of the player in the daily challenge by every ranking.
+func (r *KVRepo) DailyRanks(ctx context.Context, UserID int, day string, lb model.Leaderboard) ([]model.Rank, error) {
+	return r.ranks(ctx, dailyBoard(day, lb), UserID)
+}
+
+// ranks counts the players indexed before the player by every ranking.
+func (r *KVRepo) ranks(ctx context.Context, b boardKey, UserID int) ([]model.Rank, error) {
+	ranks := make([]model.Rank, len(r.rankings))
+	err := r.view(ctx, func(t kvTx) error {
+		var u model.User
+		ok, err := t.user(b, UserID, &u)
+		if err != nil {
+			return err
+		}
+		for i, rk := range r.rankings {
+			ranks[i] = model.Rank{Ranking: rk.Name, Position: -1}
+			if !ok {
+				continue
+			}
+			before, err := t.Count(rankPrefix(b, i), rankKey(b, i, rk, u))
+			if err != nil {
+				return err
+			}
+			ranks[i].Position = before + 1
+		}
+		return nil
+	})
+	return ranks, err
+}
+
+// WindowStats returns user's results of the games on the leaderboard since the time, a user who has not played any has empty results.
+// The best games are not kept.
+func (r *KVRepo) WindowStats(ctx context.Context, UserID int, lb model.Leaderboard, since time.Time) (model.User, error) {
+	u := model.User{UserID: UserID}
+	err := r.view(ctx, func(t kvTx) error {
+		users, err := t.windowBoard(lb, since)
+		if user, ok := users[UserID]; ok {
+			u = user
+		}
+		return err
+	})
+	return u, err
+}
+
+// WindowRanks returns the positions of the player among the ones who played on the leaderboard since the time.
+func (r *KVRepo) WindowRanks(ctx context.Context, UserID int, lb model.Leaderboard, since time.Time) ([]model.Rank, error) {
+	ranks := make([]model.Rank, len(r.rankings))
+	err := r.view(ctx, func(t kvTx) error {
+		users, err := t.windowBoard(lb, since)
+		for i, rk := range r.rankings {
+			ranks[i] = model.Rank{Ranking: rk.Name, Position: rk.position(users, UserID)}
+		}
+		return err
+	})
+	return ranks, err
+}
+
+// kvTx reads and writes the records of the repository in the transaction.
+type kvTx struct {
+	*kv.Tx
+	r *KVRepo
+}
+
+// getJSON decodes the value of the key, it reports whether the key is found.
+func (t kvTx) getJSON(key []byte, v any) (bool, error) {
+	b, ok, err := t.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("decode %q: %s", key, err)
+	}
+	return true, nil
+}
+
+// putJSON encodes the value of the key, the value is decoded back to be the same as read later.
+func (t kvTx) putJSON(key []byte, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %q: %s", key, err)
+	}
+	if err := t.Put(key, b); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (t kvTx) user(b boardKey, UserID int, u *model.User) (bool, error) {
+	return t.getJSON(userKey(b, UserID), u)
+}
+
+// withUser applies the acceptor to the user of the board, the user is indexed by every ranking.
+// The results on the game boards are counted by the monitoring.
+func (t kvTx) withUser(b boardKey, UserID int, acceptor func(u *model.User)) error {
+	var old model.User
+	found, err := t.user(b, UserID, &old)
+	if err != nil {
+		return err
+	}
+	u := model.User{UserID: UserID}
+	if found {
+		u = old
+	}
+	acceptor(&u)
+	if err := t.putJSON(userKey(b, UserID), &u); err != nil {
+		return err
+	}
+	for i, rk := range t.r.rankings {
+		key := rankKey(b, i, rk, u)
+		if found {
+			if prev := rankKey(b, i, rk, old); bytes.Equal(prev, key) {
+				continue
+			} else if err := t.Delete(prev); err != nil {
+				return err
+			}
+		}
+		if err := t.Put(key, nil); err != nil {
+			return err
+		}
+	}
+	if b.daily {
+		return nil
+	}
+	if !found {
+		if err := t.Put(playerKey(UserID), nil); err != nil {
+			return err
+		}
+	}
+	for name, delta := range map[string]int{
+		"games_started":  u.GamesStarted - old.GamesStarted,
+		"games_solved":   u.GamesSolved - old.GamesSolved,
+		"games_rejected": u.GamesRejected - old.GamesRejected,
+	} {
+		if err := t.count(name, delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t kvTx) count(name string, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	var v int
+	if _, err := t.getJSON(counterKey(name), &v); err != nil {
+		return err
+	}
+	v += delta
+	return t.putJSON(counterKey(name), &v)
+}
+
+// game reads the game of the user by id.
+func (t kvTx) game(UserID, gameID int, g *model.Game) error {
+	ok, err := t.getJSON(gameKey(gameID), g)
+	if err != nil {
+		return err
+	}
+	if !ok || g.UserID != UserID {
+		return fmt.Errorf("%w: user_id=%d game_id=%d", model.ErrGameNotFound, UserID, gameID)
+	}
+	return nil
+}
+
+func (t kvTx) games(ids []int) ([]model.Game, error) {
+	games := make([]model.Game, len(ids))
+	for i, id := range ids {
+		if ok, err := t.getJSON(gameKey(id), &games[i]); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, fmt.Errorf("game_id=%d of the index not found", id)
+		}
+	}
+	return games, nil
+}
+
+// putGame writes the game changed from the old one, nil for the new game, and indexes it by the times and the status.
+// The start of a game is not changed, and a solved game is not changed to other status.
+func (t kvTx) putGame(old, g *model.Game) error {
+	if err := t.putJSON(gameKey(g.ID), g); err != nil {
+		return err
+	}
+	size, err := board.ParseSize(g.Size)
+	if err != nil {
+		return fmt.Errorf("game_id=%d size: %s", g.ID, err)
+	}
+	b := gameBoard(model.Leaderboard{Size: size, Tier: g.Tier, Metric: g.Metric})
+	var keys, stale [][]byte
+	if old == nil {
+		keys = append(keys, userGameKey(g.UserID, g.ID))
+		if g.Start != nil {
+			keys = append(keys, gameTimeKey(boardPrefix(recStart, b), *g.Start, g.ID))
+		}
+	}
+	was := func(status model.GameStatus) bool { return old != nil && old.Status == status }
+	if g.Status == model.GameSolved && !was(model.GameSolved) && g.Finish != nil {
+		keys = append(keys, gameTimeKey(boardPrefix(recFinish, b), *g.Finish, g.ID))
+	}
+	if g.Start != nil {
+		started := gameTimeKey(prefix(recStarted), *g.Start, g.ID)
+		switch {
+		case g.Status == model.GameStarted && !was(model.GameStarted):
+			keys = append(keys, started)
+		case g.Status != model.GameStarted && was(model.GameStarted):
+			stale = append(stale, started)
+		}
+	}
+	switch {
+	case g.Status == model.GameQuarantined && !was(model.GameQuarantined):
+		keys = append(keys, gameKeyOf(recQuarantine, g.ID))
+	case g.Status != model.GameQuarantined && was(model.GameQuarantined):
+		stale = append(stale, gameKeyOf(recQuarantine, g.ID))
+	}
+	for _, key := range stale {
+		if err := t.Delete(key); err != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		if err := t.Put(key, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// windowBoard counts the games of the leaderboard started or solved since the time, the games are read by the time indexes.
+func (t kvTx) windowBoard(lb model.Leaderboard, since time.Time) (map[int]model.User, error) {
+	var ids []int
+	for _, rec := range []byte{recStart, recFinish} {
+		p := boardPrefix(rec, gameBoard(lb))
+		// the times are kept in seconds, the games of the second before are dropped by the count
+		if err := t.Scan(timeKey(p, since), prefixEnd(p), func(k, _ []byte) bool {
+			ids = append(ids, int(binary.BigEndian.Uint64(k[len(k)-8:])))
+			return true
+		}); err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(ids)
+	games, err := t.games(slices.Compact(ids))
+	if err != nil {
+		return nil, err
+	}
+	return windowUsers(games, lb, since), nil
+}
+
+// importData writes the games and the users of the data, they are indexed and counted as written by the repository.
+func (t kvTx) importData(d *model.Data) error {
+	for i := range d.Games {
+		if err := t.putGame(nil, &d.Games[i]); err != nil {
+			return err
+		}
+	}
+	games := len(d.Games)
+	if err := t.putJSON(counterKey("games"), &games); err != nil {
+		return err
+	}
+	for daily, boards := range map[bool]map[string]map[int]model.User{false: d.Boards, true: d.Daily} {
+		for key, users := range boards {
+			for UserID, u := range users {
+				if err := t.withUser(boardKey{daily: daily, key: key}, UserID, func(v *model.User) { *v = u }); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// reindex drops the ranking index and indexes the players of every board anew.
+func (t kvTx) reindex() error {
+	var stale [][]byte
+	if err := t.Scan(prefix(recRank), prefixEnd(prefix(recRank)), func(k, _ []byte) bool {
+		stale = append(stale, bytes.Clone(k))
+		return true
+	}); err != nil {
+		return err
+	}
+	for _, key := range stale {
+		if err := t.Delete(key); err != nil {
+			return err
+		}
+	}
+	var keys [][]byte
+	var failed error
+	if err := t.Scan(prefix(recUser), prefixEnd(prefix(recUser)), func(k, v []byte) bool {
+		var u model.User
+		if failed = json.Unmarshal(v, &u); failed != nil {
+			return false
+		}
+		// the key is of the board with its end and the user id
+		b := boardKey{daily: k[1] == 'd', key: string(k[2 : len(k)-9])}
+		for i, rk := range t.r.rankings {
+			keys = append(keys, rankKey(b, i, rk, u))
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+	if failed != nil {
+		return fmt.Errorf("decode user: %s", failed)
+	}
+	for _, key := range keys {
+		if err := t.Put(key, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func prefix(rec byte) []byte {
+	return []byte{rec}
+}
+
+// prefixEnd returns the first key after the keys of the prefix.
+func prefixEnd(p []byte) []byte {
+	end := bytes.Clone(p)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i]++; end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func boardPrefix(rec byte, b boardKey) []byte {
+	kind := byte('b')
+	if b.daily {
+		kind = 'd'
+	}
+	return append(append([]byte{rec, kind}, b.key...), 0)
+}
+
+func appendID(key []byte, id int) []byte {
+	return binary.BigEndian.AppendUint64(key, uint64(id))
+}
+
+func userKey(b boardKey, UserID int) []byte {
+	return appendID(boardPrefix(recUser, b), UserID)
+}
+
+func playerKey(UserID int) []byte {
+	return appendID(prefix(recPlayer), UserID)
+}
+
+func gameKeyOf(rec byte, gameID int) []byte {
+	return appendID(prefix(rec), gameID)
+}
+
+func gameKey(gameID int) []byte {
+	return gameKeyOf(recGame, gameID)
+}
+
+func userGameKey(UserID, gameID int) []byte {
+	return appendID(appendID(prefix(recUserGame), UserID), gameID)
+}
+
+// timeKey appends the time in seconds, the times before the epoch are ordered before the ones after it.
+func timeKey(p []byte, t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(bytes.Clone(p), uint64(t.Unix())^1<<63)
+}
+
+func gameTimeKey(p []byte, ts model.JSONTimestamp, gameID int) []byte {
+	return appendID(timeKey(p, time.Time(ts)), gameID)
+}
+
+func counterKey(name string) []byte {
+	return append(prefix(recCounter), name...)
+}
+
+func settingKey(name string) []byte {
+	return append(prefix(recSetting), name...)
+}
+
+func rankPrefix(b boardKey, ranking int) []byte {
+	return append(boardPrefix(recRank, b), byte(ranking))
+}
+
+// rankKey is ordered by the ranking: every value is a byte of whether it is lacking and the value when it is known,
+// the values ranked higher encode to lower bytes. The user id orders the players of the same values.
+func rankKey(b boardKey, ranking int, rk Ranking, u model.User) []byte {
+	key := rankPrefix(b, ranking)
+	for _, k := range rk.Keys {
+		v, ok := rankFields[k.Field](u)
+		if !ok {
+			key = append(key, 1)
+			continue
+		}
+		// the sign bit is flipped for the positive values, all the bits for the negative ones
+		bits := math.Float64bits(v)
+		if bits>>63 == 0 {
+			bits ^= 1 << 63
+		} else {
+			bits = ^bits
+		}
+		if k.Desc {
+			bits = ^bits
+		}
+		key = binary.BigEndian.AppendUint64(append(key, 0), bits)
+	}
+	return appendID(key, u.UserID)
+}
@@ -0,0 +1,179 @@
+package repo_test
+
+import (
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/repo"
+	"context"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKVRepoRanks(t *testing.T) {
+	rankings, err := repo.ParseRankings(repo.DefaultRankings)
+	if err != nil {
+		t.Fatalf("ParseRankings: %s", err)
+	}
+	defs := make([]string, len(rankings))
+	for i, rk := range rankings {
+		defs[i] = rk.String()
+	}
+	assert.Equal(t, repo.DefaultRankings, strings.Join(defs, ";"), "rankings of the index")
+	r := openKVRepo(t, filepath.Join(t.TempDir(), "data.json"))
+	rnd := rand.New(rand.NewPCG(1, 2))
+	// players improve and repeat their results in random order
+	for range 500 {
+		UserID := rnd.IntN(100) + 1
+		_, g, err := r.RegisterGameStart(ctx, UserID, classic, "4x4:1")
+		if err != nil {
+			t.Fatalf("RegisterGameStart: %s", err)
+		}
+		if rnd.IntN(3) == 0 {
+			continue
+		}
+		if _, err := r.RegisterGameSolve(ctx, UserID, classic, g.ID, solveOf(recording(rnd.IntN(20)+1))); err != nil {
+			t.Fatalf("RegisterGameSolve: %s", err)
+		}
+	}
+
+	users := make(map[int]model.User)
+	for UserID := range 101 {
+		if u, err := r.Stats(ctx, UserID, classic); err == nil {
+			users[UserID] = u
+		}
+	}
+	top, err := r.Top(ctx, classic, 10)
+	assert.NoError(t, err)
+	all, err := r.Top(ctx, classic, -1)
+	assert.NoError(t, err)
+	for i, rk := range rankings {
+		expected := rk.Sort(users)
+		assert.Equal(t, expected, all[i].UserIDs, "rating %s", rk.Name)
+		assert.Equal(t, expected[:10], top[i].UserIDs, "top of %s", rk.Name)
+		for _, UserID := range []int{expected[0], expected[len(expected)/2], expected[len(expected)-1]} {
+			ranks, err := r.Ranks(ctx, UserID, classic)
+			assert.NoError(t, err)
+			assert.Equal(t, slices.Index(expected, UserID)+1, ranks[i].Position, "rank by %s", rk.Name)
+		}
+	}
+	ranks, err := r.Ranks(ctx, 1000, classic)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, ranks[0].Position, "player not rated")
+}
+
+func TestKVRepoReopen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	r, err := repo.NewKVRepo(context.Background(), file, repo.SyncAlways)
+	if err != nil {
+		t.Fatalf("NewKVRepo: %s", err)
+	}
+	for UserID, moves := range map[int]int{1: 12, 2: 8, 3: 10} {
+		_, g, err := r.RegisterGameStart(ctx, UserID, classic, "4x4:1")
+		if err != nil {
+			t.Fatalf("RegisterGameStart: %s", err)
+		}
+		if _, err := r.RegisterGameSolve(ctx, UserID, classic, g.ID, solveOf(recording(moves))); err != nil {
+			t.Fatalf("RegisterGameSolve: %s", err)
+		}
+	}
+	monitoring, err := r.Monitoring(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+
+	// the players are indexed anew by the rankings changed
+	rankings, err := repo.ParseRankings("moves=best_moves")
+	if err != nil {
+		t.Fatalf("ParseRankings: %s", err)
+	}
+	r = openKVRepo(t, file, rankings...)
+	reopened, err := r.Monitoring(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, monitoring, reopened)
+	top, err := r.Top(ctx, classic, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Rating{{Ranking: "moves", UserIDs: []int{2, 3, 1}}}, top)
+	games, err := r.Games(ctx, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, games, 1) {
+		assert.Equal(t, model.GameSolved, games[0].Status)
+	}
+}
+
+func TestKVRepoImport(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	writeTestFile(t, file, legacyData)
+	journal := `{"seq":1,"boards":{"4x4":{"2":{"user_id":2,"games_started":1,"games_solved":0}}}}` + "\n"
+	writeTestFile(t, file+repo.JournalSuffix, journal)
+
+	r, err := repo.NewKVRepo(context.Background(), file, repo.SyncAlways)
+	if err != nil {
+		t.Fatalf("NewKVRepo: %s", err)
+	}
+	assert.Equal(t, legacyData, readTestFile(t, file+".v0.bak"), "data file should be migrated on import")
+	top, err := r.Top(ctx, classic, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, top[0].UserIDs)
+	ranks, err := r.Ranks(ctx, 2, classic)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ranks[0].Position, "player of the journal should be imported")
+	monitoring, err := r.Monitoring(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, monitoring.Users)
+	games, err := r.Games(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, games, 1)
+	_, g, err := r.RegisterGameStart(ctx, 2, classic, "4x4:1")
+	assert.NoError(t, err)
+	assert.Equal(t, games[0].ID+1, g.ID, "game ids should follow the imported ones")
+	assert.NoError(t, r.Close())
+
+	// the store is imported once, the data file changed later is not
+	writeTestFile(t, file, `{"version":2,"boards":{}}`)
+	r = openKVRepo(t, file)
+	monitoring, err = r.Monitoring(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, monitoring.Users)
+}
+
+// BenchmarkKVRanks looks up the rank of the player by counting the keys of the index before the player.
+func BenchmarkKVRanks(b *testing.B) {
+	const users = 10_000
+	r, err := repo.NewKVRepo(context.Background(), filepath.Join(b.TempDir(), "data.json"), repo.SyncNever)
+	if err != nil {
+		b.Fatalf("NewKVRepo: %s", err)
+	}
+	b.Cleanup(func() { r.Close() })
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for UserID := 1; UserID <= users; UserID++ {
+		_, g, err := r.RegisterGameStart(ctx, UserID, classic, "4x4:1")
+		if err != nil {
+			b.Fatalf("RegisterGameStart: %s", err)
+		}
+		if _, err := r.RegisterGameSolve(ctx, UserID, classic, g.ID, solveOf(recording(rnd.IntN(50)+1))); err != nil {
+			b.Fatalf("RegisterGameSolve: %s", err)
+		}
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := r.Ranks(ctx, i%users+1, classic); err != nil {
+			b.Fatalf("Ranks: %s", err)
+		}
+	}
+}
+
+func openKVRepo(t *testing.T, file string, rankings ...repo.Ranking) *repo.KVRepo {
+	r, err := repo.NewKVRepo(context.Background(), file, repo.SyncNever, rankings...)
+	if err != nil {
+		t.Fatalf("NewKVRepo: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := r.Close(); err != nil {
+			t.Errorf("repo close: %s", err)
+		}
+	})
+	return r
+}
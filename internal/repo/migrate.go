@@ -0,0 +1,289 @@
+package repo
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/model"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SchemaVersion is the version of the data file written, a file of a former version is migrated on load.
+const SchemaVersion = 2
+
+// migration upgrades a data file document of the former version to the version, the journal entries are upgraded alike.
+// A step is applied again to the journal entries migrated already when the data file was not written, so it is idempotent.
+type migration struct {
+	version     int
+	description string
+	migrate     func(doc document) ([]string, error)
+}
+
+// migrations are the steps from the unversioned data file, in order of versions.
+var migrations = []migration{
+	{1, "results of the classic board kept before board sizes are moved to its leaderboard", migrateBoardSizes},
+	{2, "solves logged before games were kept are converted to the games of unknown start", migrateSolveLog},
+}
+
+// MigrationReport is the upgrade of a data file, the changes are reported by the steps.
+type MigrationReport struct {
+	From, To int
+	Steps    []MigrationStep
+	Backups  []string // the files of the former version, none on the dry run
+	DryRun   bool
+}
+
+type MigrationStep struct {
+	Version     int
+	Description string
+	Changes     []string
+}
+
+func (r MigrationReport) String() string {
+	if r.From == r.To {
+		return fmt.Sprintf("schema version %d is up to date", r.To)
+	}
+	var sb strings.Builder
+	if r.DryRun {
+		fmt.Fprintf(&sb, "schema version %d would be migrated to %d", r.From, r.To)
+	} else {
+		fmt.Fprintf(&sb, "schema version %d migrated to %d", r.From, r.To)
+	}
+	for _, s := range r.Steps {
+		fmt.Fprintf(&sb, "\n%d: %s", s.Version, s.Description)
+		for _, c := range s.Changes {
+			fmt.Fprintf(&sb, "\n  - %s", c)
+		}
+	}
+	for _, b := range r.Backups {
+		fmt.Fprintf(&sb, "\nbackup: %s", b)
+	}
+	return sb.String()
+}
+
+// Migrate upgrades the data file and its journal to the SchemaVersion step by step, the files of the former version
+// are kept with the version suffix as the backup. The dry run reports the changes without writing the files.
+func Migrate(dataFile string, dryRun bool) (MigrationReport, error) {
+	b, err := os.ReadFile(dataFile)
+	if err != nil && !os.IsNotExist(err) {
+		return MigrationReport{}, err
+	}
+	_, report, err := migrate(dataFile, b, dryRun)
+	return report, err
+}
+
+// migrate returns the data file content of the SchemaVersion, the files are written unless it is the dry run.
+func migrate(dataFile string, b []byte, dryRun bool) ([]byte, MigrationReport, error) {
+	report := MigrationReport{From: SchemaVersion, To: SchemaVersion, DryRun: dryRun}
+	if len(b) == 0 {
+		return b, report, nil
+	}
+	var v struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, report, err
+	}
+	report.From = v.Version
+	switch {
+	case v.Version == SchemaVersion:
+		return b, report, nil
+	case v.Version > SchemaVersion:
+		return nil, report, fmt.Errorf("schema version %d is newer than %d", v.Version, SchemaVersion)
+	case v.Version < 0:
+		return nil, report, fmt.Errorf("schema version %d is negative", v.Version)
+	}
+
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, report, err
+	}
+	journalFile := dataFile + JournalSuffix
+	jb, err := os.ReadFile(journalFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, report, err
+	}
+	lines := bytes.SplitAfter(jb, []byte("\n"))
+	entries := make([]document, len(lines))
+	for i, line := range lines {
+		// a torn last entry is kept as is to be truncated by the replay
+		if bytes.HasSuffix(line, []byte("\n")) {
+			if err := json.Unmarshal(line, &entries[i]); err != nil {
+				return nil, report, fmt.Errorf("journal entry %d: %s", i+1, err)
+			}
+		}
+	}
+
+	for _, m := range migrations[v.Version:] {
+		changes, err := m.migrate(doc)
+		if err != nil {
+			return nil, report, fmt.Errorf("migrate to version %d: %s", m.version, err)
+		}
+		changed := 0
+		for i, e := range entries {
+			if e == nil {
+				continue
+			}
+			c, err := m.migrate(e)
+			if err != nil {
+				return nil, report, fmt.Errorf("migrate journal entry %d to version %d: %s", i+1, m.version, err)
+			}
+			if len(c) > 0 {
+				changed++
+			}
+		}
+		if changed > 0 {
+			changes = append(changes, fmt.Sprintf("%d journal entries changed", changed))
+		}
+		report.Steps = append(report.Steps, MigrationStep{Version: m.version, Description: m.description, Changes: changes})
+	}
+	if err := doc.encode("version", SchemaVersion); err != nil {
+		return nil, report, err
+	}
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, report, fmt.Errorf("data marshall: %s", err)
+	}
+	if dryRun {
+		return migrated, report, nil
+	}
+
+	backup := fmt.Sprintf(".v%d.bak", v.Version)
+	if err := writeFile(dataFile+backup, b, true); err != nil {
+		return nil, report, err
+	}
+	report.Backups = append(report.Backups, dataFile+backup)
+	if len(jb) > 0 {
+		if err := writeFile(journalFile+backup, jb, true); err != nil {
+			return nil, report, err
+		}
+		report.Backups = append(report.Backups, journalFile+backup)
+		for i, e := range entries {
+			if e == nil {
+				continue
+			}
+			if lines[i], err = json.Marshal(e); err != nil {
+				return nil, report, fmt.Errorf("entry marshall: %s", err)
+			}
+			lines[i] = append(lines[i], '\n')
+		}
+		// the journal is written first, the migration is run again until the data file is written
+		if err := writeFile(journalFile, bytes.Join(lines, nil), true); err != nil {
+			return nil, report, err
+		}
+	}
+	if err := writeFile(dataFile, migrated, true); err != nil {
+		return nil, report, err
+	}
+	return migrated, report, nil
+}
+
+// document is a data file or a journal entry of any version.
+type document map[string]json.RawMessage
+
+// decode reads the value of the key, the value is left unchanged when the key is missing.
+func (doc document) decode(key string, v any) error {
+	if raw, ok := doc[key]; ok {
+		if err := json.Unmarshal(raw, v); err != nil {
+			return fmt.Errorf("decode %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+func (doc document) encode(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s: %s", key, err)
+	}
+	doc[key] = raw
+	return nil
+}
+
+func migrateBoardSizes(doc document) ([]string, error) {
+	if _, ok := doc["users"]; !ok {
+		return nil, nil
+	}
+	var legacy map[string]json.RawMessage
+	if err := doc.decode("users", &legacy); err != nil {
+		return nil, err
+	}
+	delete(doc, "users")
+	var boards map[string]map[string]json.RawMessage
+	if err := doc.decode("boards", &boards); err != nil {
+		return nil, err
+	}
+	if boards == nil {
+		boards = make(map[string]map[string]json.RawMessage)
+	}
+	classic := boards[board.Classic.String()]
+	if classic == nil {
+		classic = make(map[string]json.RawMessage)
+	}
+	moved := 0
+	for id, u := range legacy {
+		if _, ok := classic[id]; !ok {
+			classic[id] = u
+			moved++
+		}
+	}
+	boards[board.Classic.String()] = classic
+	if err := doc.encode("boards", boards); err != nil {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("%d of %d users moved to the %s board", moved, len(legacy), board.Classic)}, nil
+}
+
+// solveEvent is a solved game as it was logged before games were kept.
+type solveEvent struct {
+	UserID   int                 `json:"user_id"`
+	Size     string              `json:"size"`
+	Tier     board.Tier          `json:"tier,omitempty"`
+	Metric   board.Metric        `json:"metric,omitempty"`
+	Optimal  int                 `json:"optimal,omitempty"`
+	Moves    int                 `json:"moves"` // in the metric of the game
+	Duration model.JSONDuration  `json:"duration,omitempty"`
+	Hints    int                 `json:"hints,omitempty"`
+	Time     model.JSONTimestamp `json:"ts"`
+}
+
+func migrateSolveLog(doc document) ([]string, error) {
+	if _, ok := doc["solves"]; !ok {
+		return nil, nil
+	}
+	var solves []solveEvent
+	if err := doc.decode("solves", &solves); err != nil {
+		return nil, err
+	}
+	delete(doc, "solves")
+	var games []json.RawMessage
+	if err := doc.decode("games", &games); err != nil {
+		return nil, err
+	}
+	for _, e := range solves {
+		finish := e.Time
+		g, err := json.Marshal(model.Game{
+			ID:       len(games) + 1,
+			UserID:   e.UserID,
+			Size:     e.Size,
+			Tier:     e.Tier,
+			Metric:   e.Metric,
+			Status:   model.GameSolved,
+			Finish:   &finish,
+			Optimal:  e.Optimal,
+			Moves:    e.Moves,
+			Duration: e.Duration,
+			Hints:    e.Hints,
+		})
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	if err := doc.encode("games", games); err != nil {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("%d solves converted to games", len(solves))}, nil
+}
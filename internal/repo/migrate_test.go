@@ -0,0 +1,94 @@
+package repo_test
+
+import (
+	"15-puzzle/internal/repo"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const legacyData = `{"users":{"1":{"user_id":1,"games_started":3,"games_solved":2}},` +
+	`"solves":[{"user_id":1,"size":"4x4","moves":20,"duration":20000,"ts":1700000000}]}`
+
+func TestMigrate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	writeTestFile(t, file, legacyData)
+	journal := `{"seq":1,"boards":{"4x4":{"2":{"user_id":2,"games_started":1,"games_solved":0}}}}` + "\n" + `{"seq":2,`
+	writeTestFile(t, file+repo.JournalSuffix, journal)
+
+	report, err := repo.Migrate(file, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.From)
+	assert.Equal(t, repo.SchemaVersion, report.To)
+	if assert.Len(t, report.Steps, repo.SchemaVersion) {
+		assert.Equal(t, []string{"1 of 1 users moved to the 4x4 board"}, report.Steps[0].Changes)
+		assert.Equal(t, []string{"1 solves converted to games"}, report.Steps[1].Changes)
+	}
+	assert.Empty(t, report.Backups, "dry run should not write backups")
+	assert.Equal(t, legacyData, readTestFile(t, file), "dry run should not change the data file")
+
+	report, err = repo.Migrate(file, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{file + ".v0.bak", file + repo.JournalSuffix + ".v0.bak"}, report.Backups)
+	assert.Equal(t, legacyData, readTestFile(t, file+".v0.bak"))
+	assert.Equal(t, journal, readTestFile(t, file+repo.JournalSuffix+".v0.bak"))
+	var doc map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal([]byte(readTestFile(t, file)), &doc))
+	assert.Equal(t, "2", string(doc["version"]))
+	assert.NotContains(t, doc, "users")
+	assert.NotContains(t, doc, "solves")
+
+	report, err = repo.Migrate(file, false)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.SchemaVersion, report.From, "migrated file should be up to date")
+
+	testWithRepo(t, file, func(t *testing.T, r *repo.FileRepo) {
+		assert.Equal(t, []int{1, 2}, r.Rating(classic), "journal entry should be replayed after the migration")
+		games, err := r.Games(ctx, 1)
+		assert.NoError(t, err)
+		assert.Len(t, games, 1)
+	})
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	writeTestFile(t, file, `{"version":1000,"boards":{}}`)
+	_, err := repo.Migrate(file, true)
+	assert.Error(t, err)
+	_, err = repo.NewFileRepo(context.Background(), file, repo.SyncNever)
+	assert.Error(t, err)
+}
+
+func TestMigrateNegativeVersion(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	writeTestFile(t, file, `{"version":-1,"boards":{}}`)
+	_, err := repo.Migrate(file, true)
+	assert.Error(t, err)
+	_, err = repo.NewFileRepo(context.Background(), file, repo.SyncNever)
+	assert.Error(t, err)
+}
+
+func TestUnknownField(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	writeTestFile(t, file, `{"version":2,"boards":{"4x4":{"1":{"user_id":1,"best_score":1}}}}`)
+	_, err := repo.NewFileRepo(context.Background(), file, repo.SyncNever)
+	assert.Error(t, err, "unknown field should fail the load instead of being dropped")
+}
+
+func writeTestFile(t *testing.T, name, s string) {
+	if err := os.WriteFile(name, []byte(s), 0o644); err != nil {
+		t.Fatalf("write %s: %s", name, err)
+	}
+}
+
+func readTestFile(t *testing.T, name string) string {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("read %s: %s", name, err)
+	}
+	return string(b)
+}
@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"15-puzzle/internal/model"
+	"math/rand/v2"
+)
+
+// rankIndex orders the players of a board by a ranking as they are written, a treap of the subtree sizes
+// for the rank of a player and the top players in logarithmic time.
+type rankIndex struct {
+	ranking Ranking
+	root    *rankNode
+	users   map[int]model.User // the values the players are ordered by
+}
+
+type rankNode struct {
+	user        model.User
+	priority    uint32
+	size        int
+	left, right *rankNode
+}
+
+func newRankIndex(rk Ranking, users map[int]model.User) *rankIndex {
+	idx := &rankIndex{ranking: rk, users: make(map[int]model.User, len(users))}
+	for _, u := range users {
+		idx.set(u)
+	}
+	return idx
+}
+
+// set orders the player by the values of the user, the player is moved when it is ordered already.
+func (idx *rankIndex) set(u model.User) {
+	if old, ok := idx.users[u.UserID]; ok {
+		if idx.ranking.equal(old, u) {
+			idx.users[u.UserID] = u
+			return
+		}
+		less, rest := idx.split(idx.root, old, false)
+		_, greater := idx.split(rest, old, true)
+		idx.root = merge(less, greater)
+	}
+	idx.users[u.UserID] = u
+	less, greater := idx.split(idx.root, u, false)
+	idx.root = merge(merge(less, &rankNode{user: u, priority: rand.Uint32(), size: 1}), greater)
+}
+
+// remove takes the player out of the order, it is not rated anymore.
+func (idx *rankIndex) remove(UserID int) {
+	old, ok := idx.users[UserID]
+	if !ok {
+		return
+	}
+	delete(idx.users, UserID)
+	less, rest := idx.split(idx.root, old, false)
+	_, greater := idx.split(rest, old, true)
+	idx.root = merge(less, greater)
+}
+
+// rank returns the position of the player from 1, or -1 when the player is not rated.
+func (idx *rankIndex) rank(UserID int) int {
+	u, ok := idx.users[UserID]
+	if !ok {
+		return -1
+	}
+	rank := 0
+	for n := idx.root; n != nil; {
+		switch c := idx.ranking.Compare(u, n.user); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			rank += size(n.left) + 1
+			n = n.right
+		default:
+			return rank + size(n.left) + 1
+		}
+	}
+	return -1
+}
+
+// top returns the user ids of the first players, all of them when the limit is negative.
+func (idx *rankIndex) top(limit int) []int {
+	if limit < 0 || limit > size(idx.root) {
+		limit = size(idx.root)
+	}
+	ids := make([]int, 0, limit)
+	var path []*rankNode
+	for n := idx.root; len(ids) < limit && (n != nil || len(path) > 0); {
+		if n != nil {
+			path = append(path, n)
+			n = n.left
+			continue
+		}
+		n = path[len(path)-1]
+		path = path[:len(path)-1]
+		ids = append(ids, n.user.UserID)
+		n = n.right
+	}
+	return ids
+}
+
+// split divides the tree into the players ranked higher than the user and the rest,
+// the user itself is put to the higher ones when inclusive.
+func (idx *rankIndex) split(n *rankNode, u model.User, inclusive bool) (*rankNode, *rankNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if c := idx.ranking.Compare(n.user, u); c < 0 || inclusive && c == 0 {
+		right, greater := idx.split(n.right, u, inclusive)
+		n.right = right
+		n.update()
+		return n, greater
+	}
+	less, left := idx.split(n.left, u, inclusive)
+	n.left = left
+	n.update()
+	return less, n
+}
+
+// merge joins the trees, all the players of the first one are ranked higher.
+func merge(a, b *rankNode) *rankNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.priority > b.priority:
+		a.right = merge(a.right, b)
+		a.update()
+		return a
+	default:
+		b.left = merge(a, b.left)
+		b.update()
+		return b
+	}
+}
+
+func (n *rankNode) update() {
+	n.size = size(n.left) + size(n.right) + 1
+}
+
+func size(n *rankNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
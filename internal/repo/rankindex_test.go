@@ -0,0 +1,155 @@
+package repo_test
+
+import (
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/repo"
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const benchmarkUsers = 100_000
+
+func TestRankIndex(t *testing.T) {
+	rankings, err := repo.ParseRankings(repo.DefaultRankings)
+	if err != nil {
+		t.Fatalf("ParseRankings: %s", err)
+	}
+	rnd := rand.New(rand.NewPCG(1, 2))
+	testWithNewRepo(t, func(t *testing.T, r *repo.FileRepo) {
+		// players improve and repeat their results in random order
+		for range 500 {
+			UserID := rnd.IntN(100) + 1
+			_, g, err := r.RegisterGameStart(ctx, UserID, classic, "4x4:1")
+			if err != nil {
+				t.Fatalf("RegisterGameStart: %s", err)
+			}
+			if rnd.IntN(3) == 0 {
+				continue
+			}
+			if _, err := r.RegisterGameSolve(ctx, UserID, classic, g.ID, solveOf(recording(rnd.IntN(20)+1))); err != nil {
+				t.Fatalf("RegisterGameSolve: %s", err)
+			}
+		}
+
+		users := make(map[int]model.User)
+		for UserID := range 101 {
+			if u, err := r.Stats(ctx, UserID, classic); err == nil && u.UserID != 0 {
+				users[UserID] = u
+			}
+		}
+		ratings := r.Ratings(classic)
+		top, err := r.Top(ctx, classic, 10)
+		assert.NoError(t, err)
+		for i, rk := range rankings {
+			expected := rk.Sort(users)
+			assert.Equal(t, expected, ratings[i].UserIDs, "rating %s", rk.Name)
+			assert.Equal(t, expected[:10], top[i].UserIDs, "top of %s", rk.Name)
+			for _, UserID := range []int{expected[0], expected[len(expected)/2], expected[len(expected)-1]} {
+				ranks, err := r.Ranks(ctx, UserID, classic)
+				assert.NoError(t, err)
+				assert.Equal(t, slices.Index(expected, UserID)+1, ranks[i].Position, "rank by %s", rk.Name)
+			}
+		}
+		ranks, err := r.Ranks(ctx, 1000, classic)
+		assert.NoError(t, err)
+		assert.Equal(t, -1, ranks[0].Position, "player not rated")
+	})
+}
+
+func BenchmarkRanks(b *testing.B) {
+	r, _ := benchmarkRepo(b)
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := r.Ranks(ctx, i%benchmarkUsers+1, classic); err != nil {
+			b.Fatalf("Ranks: %s", err)
+		}
+	}
+}
+
+// BenchmarkSortRanks ranks the player by sorting all the players of every ranking, as the ranks were looked up before the index.
+func BenchmarkSortRanks(b *testing.B) {
+	_, users := benchmarkRepo(b)
+	rankings, err := repo.ParseRankings(repo.DefaultRankings)
+	if err != nil {
+		b.Fatalf("ParseRankings: %s", err)
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		for _, rk := range rankings {
+			if slices.Index(rk.Sort(users), i%benchmarkUsers+1) < 0 {
+				b.Fatalf("player %d not rated", i%benchmarkUsers+1)
+			}
+		}
+	}
+}
+
+func BenchmarkTop(b *testing.B) {
+	r, _ := benchmarkRepo(b)
+	b.ResetTimer()
+	for range b.N {
+		if _, err := r.Top(ctx, classic, 10); err != nil {
+			b.Fatalf("Top: %s", err)
+		}
+	}
+}
+
+// BenchmarkRegisterGameSolve includes the update of the index, the journal is not synced.
+func BenchmarkRegisterGameSolve(b *testing.B) {
+	r, _ := benchmarkRepo(b)
+	solve := solveOf(recording(10))
+	b.ResetTimer()
+	for i := range b.N {
+		UserID := i%benchmarkUsers + 1
+		_, g, err := r.RegisterGameStart(ctx, UserID, classic, "4x4:1")
+		if err != nil {
+			b.Fatalf("RegisterGameStart: %s", err)
+		}
+		if _, err := r.RegisterGameSolve(ctx, UserID, classic, g.ID, solve); err != nil {
+			b.Fatalf("RegisterGameSolve: %s", err)
+		}
+	}
+}
+
+// benchmarkRepo opens the repository of the synthetic players of the classic board, a tenth of them has not solved a game.
+func benchmarkRepo(b *testing.B) (*repo.FileRepo, map[int]model.User) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	users := make(map[int]model.User, benchmarkUsers)
+	for UserID := 1; UserID <= benchmarkUsers; UserID++ {
+		u := model.User{UserID: UserID, GamesStarted: rnd.IntN(100) + 1}
+		if rnd.IntN(10) > 0 {
+			moves := rnd.IntN(200) + 40
+			duration := model.JSONDuration(time.Duration(rnd.IntN(600_000)+10_000) * time.Millisecond)
+			result := float32(time.Duration(duration).Seconds() / float64(moves))
+			ts := model.JSONTimestamp(time.Unix(1700000000+rnd.Int64N(1e7), 0))
+			u.GamesSolved = rnd.IntN(u.GamesStarted) + 1
+			u.BestMoves, u.BestTime, u.BestResult, u.BestSolveTime = &moves, &duration, &result, &ts
+		}
+		users[UserID] = u
+	}
+	data, err := json.Marshal(model.Data{Boards: map[string]map[int]model.User{classic.String(): users}})
+	if err != nil {
+		b.Fatalf("data marshall: %s", err)
+	}
+	file := filepath.Join(b.TempDir(), "data.json")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		b.Fatalf("data write: %s", err)
+	}
+	r, err := repo.NewFileRepo(context.Background(), file, repo.SyncNever)
+	if err != nil {
+		b.Fatalf("repo init: %s", err)
+	}
+	b.Cleanup(func() {
+		if err := r.Close(); err != nil {
+			b.Errorf("repo close: %s", err)
+		}
+	})
+	return r, users
+}
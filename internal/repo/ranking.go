@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"15-puzzle/internal/model"
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultRankings order the players of every leaderboard by the fastest time, the fewest moves,
+// the best time per move and the most games solved. The first ranking is the main one.
+const DefaultRankings = "time=best_time,best_result,best_solve_ts,-games_solved;" +
+	"moves=best_moves,best_time,-games_solved;" +
+	"per_move=best_result,best_solve_ts,-games_solved;" +
+	"solves=-games_solved,best_time"
+
+// rankFields are the user values players can be ranked by, a user lacks the value when it is unknown.
+var rankFields = map[string]func(u model.User) (float64, bool){
+	"best_time": func(u model.User) (float64, bool) {
+		if u.BestTime == nil {
+			return 0, false
+		}
+		return float64(*u.BestTime), true
+	},
+	"best_moves": func(u model.User) (float64, bool) {
+		if u.BestMoves == nil {
+			return 0, false
+		}
+		return float64(*u.BestMoves), true
+	},
+	"best_result": func(u model.User) (float64, bool) {
+		if u.BestResult == nil {
+			return 0, false
+		}
+		return float64(*u.BestResult), true
+	},
+	"best_solve_ts": func(u model.User) (float64, bool) {
+		if u.BestSolveTime == nil {
+			return 0, false
+		}
+		return float64(time.Time(*u.BestSolveTime).UnixMilli()), true
+	},
+	"games_solved":  func(u model.User) (float64, bool) { return float64(u.GamesSolved), true },
+	"games_started": func(u model.User) (float64, bool) { return float64(u.GamesStarted), true },
+}
+
+// Ranking orders players by the keys in turn, then by user id.
+type Ranking struct {
+	Name string
+	Keys []RankKey
+}
+
+// RankKey is a user value ranking lower values higher, or greater ones when Desc.
+// Players lacking the value are ranked after the ones having it either way.
+type RankKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseRankings reads rankings separated by semicolons, a ranking is the name followed by '=' and comma separated fields,
+// a field prefixed with '-' ranks greater values higher, e.g. "moves=best_moves,-games_solved;solves=-games_solved".
+func ParseRankings(s string) ([]Ranking, error) {
+	var rankings []Ranking
+	for _, def := range strings.Split(s, ";") {
+		name, fields, ok := strings.Cut(strings.TrimSpace(def), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("parse ranking %q: no name", def)
+		}
+		if slices.ContainsFunc(rankings, func(rk Ranking) bool { return rk.Name == name }) {
+			return nil, fmt.Errorf("parse ranking %q: duplicate name", def)
+		}
+		rk := Ranking{Name: name}
+		for _, f := range strings.Split(fields, ",") {
+			key := RankKey{Field: strings.TrimPrefix(f, "-"), Desc: strings.HasPrefix(f, "-")}
+			if _, ok := rankFields[key.Field]; !ok {
+				return nil, fmt.Errorf("parse ranking %q: unknown field %q", def, key.Field)
+			}
+			rk.Keys = append(rk.Keys, key)
+		}
+		rankings = append(rankings, rk)
+	}
+	return rankings, nil
+}
+
+// String is the definition of the ranking read by ParseRankings.
+func (rk Ranking) String() string {
+	fields := make([]string, len(rk.Keys))
+	for i, key := range rk.Keys {
+		fields[i] = key.Field
+		if key.Desc {
+			fields[i] = "-" + key.Field
+		}
+	}
+	return rk.Name + "=" + strings.Join(fields, ",")
+}
+
+func defaultRankings() []Ranking {
+	rankings, err := ParseRankings(DefaultRankings)
+	if err != nil {
+		panic(err)
+	}
+	return rankings
+}
+
+// Compare returns a negative number when the player a is ranked higher than b, a positive one when lower.
+func (rk Ranking) Compare(a, b model.User) int {
+	for _, key := range rk.Keys {
+		if c := key.compare(a, b); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.UserID, b.UserID)
+}
+
+// equal reports whether the players have the same values of the keys.
+func (rk Ranking) equal(a, b model.User) bool {
+	for _, key := range rk.Keys {
+		if key.compare(a, b) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// position returns the position of the player from 1 without sorting the players, or -1 when the player is not rated.
+func (rk Ranking) position(users map[int]model.User, UserID int) int {
+	u, ok := users[UserID]
+	if !ok {
+		return -1
+	}
+	position := 1
+	for _, other := range users {
+		if rk.Compare(other, u) < 0 {
+			position++
+		}
+	}
+	return position
+}
+
+func (key RankKey) compare(a, b model.User) int {
+	value := rankFields[key.Field]
+	va, okA := value(a)
+	vb, okB := value(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return +1
+	case !okB:
+		return -1
+	case key.Desc:
+		return cmp.Compare(vb, va)
+	default:
+		return cmp.Compare(va, vb)
+	}
+}
+
+// Sort returns the user ids of the players in the order of the ranking.
+func (rk Ranking) Sort(users map[int]model.User) []int {
+	return slices.SortedFunc(maps.Keys(users), func(a, b int) int { return rk.Compare(users[a], users[b]) })
+}
@@ -1,88 +1,200 @@
 package repo
 
 import (
+	"15-puzzle/internal/board"
 	"15-puzzle/internal/model"
-	"cmp"
 	"context"
-	"encoding/json"
 	"fmt"
-	"maps"
-	"os"
-	"path"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type FileRepo struct {
-	dataFile string
+// MemRepo keeps the data in memory only, the writes are lost with the process unless they are persisted by the FileRepo.
+type MemRepo struct {
 	latch    sync.RWMutex
 	data     *model.Data
+	rankings []Ranking
+	indexes  map[boardKey][]*rankIndex // the players of every board in the order of every ranking
+	// persist keeps the entry of a write under the latch, the write returns once the commit does
+	persist func(e entry) (commit func() error, err error)
+	failed  atomic.Pointer[error] // the commit failed after the data is changed, the requests fail since
 }
 
-func NewFileRepo(ctx context.Context, dataFile string) (*FileRepo, error) {
-	dataFile = path.Clean(dataFile)
-	r := &FileRepo{
-		dataFile: dataFile,
-		data:     &model.Data{Users: make(map[int]model.User)},
+// NewMemRepo returns the empty repository, players are ranked by DefaultRankings unless other rankings are given.
+func NewMemRepo(rankings ...Ranking) *MemRepo {
+	if len(rankings) == 0 {
+		rankings = defaultRankings()
 	}
+	return &MemRepo{
+		data:     &model.Data{Version: SchemaVersion, Boards: make(map[string]map[int]model.User)},
+		rankings: rankings,
+		indexes:  make(map[boardKey][]*rankIndex),
+	}
+}
 
-	b, err := os.ReadFile(dataFile)
-	if err != nil && !os.IsNotExist(err) {
-		return nil, err
+func (r *MemRepo) Monitoring(ctx context.Context) (model.Monitoring, error) {
+	if err := r.check(ctx); err != nil {
+		return model.Monitoring{}, err
 	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
 
-	if len(b) == 0 {
-		// init with empty writeable file
-		if err := r.withData(func(d *model.Data) {}); err != nil {
-			return nil, err
+	m := &model.Monitoring{}
+	now := time.Now()
+	for _, g := range r.data.Games {
+		switch g.StatusAt(now) {
+		case model.GameAbandoned:
+			m.GamesAbandoned++
+		case model.GameQuarantined:
+			m.GamesQuarantined++
+		}
+	}
+	users := make(map[int]struct{})
+	for _, b := range r.data.Boards {
+		for i := range b {
+			users[i] = struct{}{}
+			m.GamesStarted += b[i].GamesStarted
+			m.GamesSolved += b[i].GamesSolved
+			m.GamesRejected += b[i].GamesRejected
 		}
-		return r, nil
 	}
+	m.Users = len(users)
+	return *m, nil
+}
 
-	if err := json.Unmarshal(b, &r.data); err != nil {
-		return nil, err
+// Stats returns user's results on the leaderboard. A user known by results on other leaderboards has empty results.
+func (r *MemRepo) Stats(ctx context.Context, UserID int, lb model.Leaderboard) (model.User, error) {
+	if err := r.check(ctx); err != nil {
+		return model.User{}, err
 	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
 
-	return r, nil
+	if user, ok := r.data.Boards[lb.String()][UserID]; ok {
+		return user, nil
+	}
+	for _, b := range r.data.Boards {
+		if _, ok := b[UserID]; ok {
+			return model.User{UserID: UserID}, nil
+		}
+	}
+	return model.User{}, fmt.Errorf("%w: user_id=%d", model.ErrUserNotFound, UserID)
 }
 
-func (r *FileRepo) Monitoring() (model.Monitoring, error) {
+func (r *MemRepo) AddUser(ctx context.Context, UserID int) error {
+	return r.withUserOf(ctx, UserID, func(*tx) (boardKey, error) { return gameBoard(model.Leaderboard{Size: board.Classic}), nil }, func(*model.User) {})
+}
+
+// RegisterGameStart keeps the new game of the scramble and counts it on the leaderboard.
+func (r *MemRepo) RegisterGameStart(ctx context.Context, UserID int, lb model.Leaderboard, scramble string) (model.User, model.Game, error) {
+	var result model.User
+	var game model.Game
+	if err := r.withUserOf(ctx, UserID, func(t *tx) (boardKey, error) {
+		ts := model.JSONTimestamp(time.Now().UTC())
+		game = model.Game{
+			ID:       len(t.Games) + 1,
+			UserID:   UserID,
+			Size:     lb.Size.String(),
+			Tier:     lb.Tier,
+			Metric:   lb.Metric,
+			Scramble: scramble,
+			Status:   model.GameStarted,
+			Start:    &ts,
+		}
+		t.addGame(game)
+		return gameBoard(lb), nil
+	}, func(u *model.User) {
+		u.GamesStarted++
+		u.LastStartTime = game.Start
+		result = *u
+	}); err != nil {
+		return result, game, err
+	}
+	return result, game, nil
+}
+
+// Game returns the game of the user by id.
+func (r *MemRepo) Game(ctx context.Context, UserID, gameID int) (model.Game, error) {
+	if err := r.check(ctx); err != nil {
+		return model.Game{}, err
+	}
 	r.latch.RLock()
 	defer r.latch.RUnlock()
 
-	m := &model.Monitoring{Users: len(r.data.Users)}
-	for i := range r.data.Users {
-		m.GamesStarted += r.data.Users[i].GamesStarted
-		m.GamesSolved += r.data.Users[i].GamesSolved
+	if g := gameOf(r.data, UserID, gameID); g != nil {
+		return *g, nil
 	}
-	return *m, nil
+	return model.Game{}, fmt.Errorf("%w: user_id=%d game_id=%d", model.ErrGameNotFound, UserID, gameID)
 }
 
-func (r *FileRepo) Stats(UserID int) (model.User, error) {
+// gameOf returns the game in the data for an update, nil when the user has no game of the id.
+func gameOf(d *model.Data, UserID, gameID int) *model.Game {
+	if gameID < 1 || gameID > len(d.Games) || d.Games[gameID-1].UserID != UserID {
+		return nil
+	}
+	return &d.Games[gameID-1]
+}
+
+// Games returns the games of the user in order of start.
+func (r *MemRepo) Games(ctx context.Context, UserID int) ([]model.Game, error) {
+	if err := r.check(ctx); err != nil {
+		return nil, err
+	}
 	r.latch.RLock()
 	defer r.latch.RUnlock()
 
-	user, ok := r.data.Users[UserID]
-	if !ok {
-		return model.User{}, fmt.Errorf("user not found: user_id=%d", UserID)
+	now := time.Now()
+	var games []model.Game
+	for _, g := range r.data.Games {
+		if g.UserID == UserID {
+			g.Status = g.StatusAt(now)
+			games = append(games, g)
+		}
 	}
-	return user, nil
+	return games, nil
 }
 
-func (r *FileRepo) AddUser(UserID int) error {
-	if err := r.withUser(UserID, func(*model.User) {}); err != nil {
-		return err
-	}
-	return nil
+// RegisterGameSolve finishes the started game of the user on the leaderboard and counts it, a game is solved once.
+func (r *MemRepo) RegisterGameSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int, solve model.Solve) (model.User, error) {
+	return r.registerSolve(ctx, UserID, lb, gameID, solve, nil)
+}
+
+// RegisterQuarantinedSolve finishes the started game as quarantined with the recording for its review, the game is not counted.
+func (r *MemRepo) RegisterQuarantinedSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int, solve model.Solve, suspicion model.Suspicion) (model.User, error) {
+	return r.registerSolve(ctx, UserID, lb, gameID, solve, &suspicion)
 }
 
-func (r *FileRepo) RegisterGameStart(UserID int) (model.User, error) {
+func (r *MemRepo) registerSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int, solve model.Solve, suspicion *model.Suspicion) (model.User, error) {
 	var result model.User
-	if err := r.withUser(UserID, func(u *model.User) {
-		u.GamesStarted++
-		ts := model.JSONTimestamp(time.Now().UTC())
-		u.LastStartTime = &ts
+	var game model.Game
+	if err := r.withUserOf(ctx, UserID, func(t *tx) (boardKey, error) {
+		g := t.game(UserID, gameID)
+		switch {
+		case g == nil:
+			return boardKey{}, fmt.Errorf("%w: user_id=%d game_id=%d", model.ErrGameNotFound, UserID, gameID)
+		case g.Status != model.GameStarted:
+			return boardKey{}, fmt.Errorf("%w: %s user_id=%d game_id=%d", model.ErrGameNotStarted, g.Status, UserID, gameID)
+		case g.Size != lb.Size.String() || g.Tier != lb.Tier || g.Metric != lb.Metric:
+			return boardKey{}, fmt.Errorf("%w than %s: user_id=%d game_id=%d", model.ErrOtherLeaderboard, lb, UserID, gameID)
+		case suspicion != nil:
+			finish(g, model.GameQuarantined)
+			g.Suspicion, g.Recording = suspicion, &solve.Recording
+		default:
+			finish(g, model.GameSolved)
+		}
+		g.Optimal = solve.Optimal
+		g.Moves = lb.Metric.Count(solve.Recording.Moves)
+		g.Duration = solve.Duration
+		g.Hints = solve.Hints
+		game = *g
+		return gameBoard(lb), nil
+	}, func(u *model.User) {
+		u.LastStartTime = nil
+		if suspicion == nil && countSolve(u, game) {
+			u.BestGame = &solve.Recording
+		}
 		result = *u
 	}); err != nil {
 		return result, err
@@ -90,18 +202,138 @@ func (r *FileRepo) RegisterGameStart(UserID int) (model.User, error) {
 	return result, nil
 }
 
-func (r *FileRepo) RegisterGameSolve(UserID, moves int) (model.User, error) {
-	var result model.User
-	if err := r.withUser(UserID, func(u *model.User) {
-		u.GamesSolved++
-		if u.LastStartTime != nil {
-			moveAverage := float32(time.Since(time.Time(*u.LastStartTime)).Seconds() / float64(moves))
-			if u.BestResult == nil || moveAverage < *u.BestResult {
-				ts := model.JSONTimestamp(time.Now().UTC())
-				u.BestSolveTime = &ts
-				u.BestResult = &moveAverage
-				u.LastStartTime = nil
+// RegisterRejectedSolve counts the solve which failed the verification, the started game of the id is rejected.
+func (r *MemRepo) RegisterRejectedSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int) error {
+	return r.withUserOf(ctx, UserID, func(t *tx) (boardKey, error) {
+		if g := t.game(UserID, gameID); g != nil && g.Status == model.GameStarted {
+			finish(g, model.GameRejected)
+		}
+		return gameBoard(lb), nil
+	}, func(u *model.User) { u.GamesRejected++ })
+}
+
+// Quarantine returns the quarantined games in order of start.
+func (r *MemRepo) Quarantine(ctx context.Context) ([]model.Game, error) {
+	if err := r.check(ctx); err != nil {
+		return nil, err
+	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	var games []model.Game
+	for _, g := range r.data.Games {
+		if g.Status == model.GameQuarantined {
+			games = append(games, g)
+		}
+	}
+	return games, nil
+}
+
+// ReviewGame counts the quarantined game as solved when it is approved, otherwise the game is rejected.
+// The daily challenge board does not count approved games.
+func (r *MemRepo) ReviewGame(ctx context.Context, gameID int, approve bool) (model.Game, error) {
+	var game model.Game
+	if err := r.withData(ctx, func(t *tx) error {
+		g := t.gameByID(gameID)
+		switch {
+		case g == nil:
+			return fmt.Errorf("%w: game_id=%d", model.ErrGameNotFound, gameID)
+		case g.Status != model.GameQuarantined:
+			return fmt.Errorf("%w: %s game_id=%d", model.ErrNotQuarantined, g.Status, gameID)
+		}
+		size, err := board.ParseSize(g.Size)
+		if err != nil {
+			return fmt.Errorf("game_id=%d size: %s", gameID, err)
+		}
+		b := gameBoard(model.Leaderboard{Size: size, Tier: g.Tier, Metric: g.Metric})
+		users := b.users(t.Data)
+		u, ok := users[g.UserID]
+		if !ok {
+			u = model.User{UserID: g.UserID}
+		}
+		if approve {
+			g.Status = model.GameSolved
+			if countSolve(&u, *g) {
+				u.BestGame = g.Recording
 			}
+		} else {
+			g.Status = model.GameRejected
+			u.GamesRejected++
+		}
+		t.user(b, g.UserID)
+		users[g.UserID] = u
+		game = *g
+		return nil
+	}); err != nil {
+		return game, err
+	}
+	return game, nil
+}
+
+func finish(g *model.Game, status model.GameStatus) {
+	ts := model.JSONTimestamp(time.Now().UTC())
+	g.Status = status
+	g.Finish = &ts
+}
+
+// Rating returns the players of the leaderboard in the order of the main ranking.
+func (r *MemRepo) Rating(lb model.Leaderboard) []int {
+	return r.Ratings(lb)[0].UserIDs
+}
+
+// Ratings returns the players of the leaderboard in the order of every ranking, the main one first.
+func (r *MemRepo) Ratings(lb model.Leaderboard) []model.Rating {
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	return r.top(gameBoard(lb), -1)
+}
+
+// Top returns the first players of the leaderboard in the order of every ranking, all of them when the limit is negative.
+func (r *MemRepo) Top(ctx context.Context, lb model.Leaderboard, limit int) ([]model.Rating, error) {
+	if err := r.check(ctx); err != nil {
+		return nil, err
+	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	return r.top(gameBoard(lb), limit), nil
+}
+
+// Ranks returns the positions of the player on the leaderboard by every ranking, the main one first.
+func (r *MemRepo) Ranks(ctx context.Context, UserID int, lb model.Leaderboard) ([]model.Rank, error) {
+	if err := r.check(ctx); err != nil {
+		return nil, err
+	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	return r.ranks(gameBoard(lb), UserID), nil
+}
+
+// DailyStats returns user's results of the daily challenge, a user who has not solved it yet has empty results.
+func (r *MemRepo) DailyStats(ctx context.Context, UserID int, day string, lb model.Leaderboard) (model.User, error) {
+	if err := r.check(ctx); err != nil {
+		return model.User{}, err
+	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	if user, ok := r.data.Daily[dailyKey(day, lb)][UserID]; ok {
+		return user, nil
+	}
+	return model.User{UserID: UserID}, nil
+}
+
+// RegisterDailySolve counts the solved daily challenge.
+func (r *MemRepo) RegisterDailySolve(ctx context.Context, UserID int, day string, lb model.Leaderboard, solve model.Solve) (model.User, error) {
+	var result model.User
+	ts := model.JSONTimestamp(time.Now().UTC())
+	game := model.Game{Status: model.GameSolved, Finish: &ts, Moves: lb.Metric.Count(solve.Recording.Moves), Duration: solve.Duration, Hints: solve.Hints}
+	if err := r.withUserOf(ctx, UserID, func(*tx) (boardKey, error) { return dailyBoard(day, lb), nil }, func(u *model.User) {
+		u.GamesStarted++
+		if countSolve(u, game) {
+			u.BestGame = &solve.Recording
 		}
 		result = *u
 	}); err != nil {
@@ -110,86 +342,414 @@ func (r *FileRepo) RegisterGameSolve(UserID, moves int) (model.User, error) {
 	return result, nil
 }
 
-func (r *FileRepo) Rating() []int {
+// countSolve adds the solved game to the results of the user, it reports a new best time.
+// A game solved with hints does not affect the best results.
+func countSolve(u *model.User, g model.Game) bool {
+	u.GamesSolved++
+	if g.Hints > 0 {
+		u.GamesHinted++
+		return false
+	}
+	return improveBest(u, g.Moves, g.Duration, *g.Finish)
+}
+
+// improveBest keeps the best time and the best moves count of the user separately, it reports a new best time.
+// A solve of unknown duration only counts for the moves.
+func improveBest(u *model.User, moves int, duration model.JSONDuration, ts model.JSONTimestamp) bool {
+	if moves > 0 && (u.BestMoves == nil || moves < *u.BestMoves) {
+		u.BestMoves = &moves
+	}
+	if duration > 0 && (u.BestTime == nil || duration < *u.BestTime) {
+		moveAverage := float32(time.Duration(duration).Seconds() / float64(moves))
+		u.BestTime = &duration
+		u.BestResult = &moveAverage
+		u.BestSolveTime = &ts
+		return true
+	}
+	return false
+}
+
+func (r *MemRepo) DailyRating(day string, lb model.Leaderboard) []int {
+	return r.DailyRatings(day, lb)[0].UserIDs
+}
+
+func (r *MemRepo) DailyRatings(day string, lb model.Leaderboard) []model.Rating {
 	r.latch.RLock()
 	defer r.latch.RUnlock()
 
-	return slices.SortedFunc(maps.Keys(r.data.Users), func(a, b int) int { return SortRating(r.data.Users[a], r.data.Users[b]) })
+	return r.top(dailyBoard(day, lb), -1)
 }
 
-func SortRating(a, b model.User) int {
-	return cmp.Or(
-		compareBest(a.BestResult, b.BestResult),     // lower value is higher
-		compareTs(a.BestSolveTime, b.BestSolveTime), // earlier value is higher
-		cmp.Compare(b.GamesSolved, a.GamesSolved),   // greater value is higher
-		cmp.Compare(a.UserID, b.UserID),             // sadly, compare user ids when nobody has solved just once
-	)
+// DailyRanks returns the positions of the player in the daily challenge by every ranking.
+func (r *MemRepo) DailyRanks(ctx context.Context, UserID int, day string, lb model.Leaderboard) ([]model.Rank, error) {
+	if err := r.check(ctx); err != nil {
+		return nil, err
+	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	return r.ranks(dailyBoard(day, lb), UserID), nil
 }
 
-func compareBest(a, b *float32) int {
-	switch {
-	case a == nil && b == nil:
-		return 0
-	case a == nil:
-		return +1
-	case b == nil:
-		return -1
-	default:
-		return cmp.Compare(*a, *b)
+func (r *MemRepo) top(b boardKey, limit int) []model.Rating {
+	ratings := make([]model.Rating, len(r.rankings))
+	for i, rk := range r.rankings {
+		ratings[i] = model.Rating{Ranking: rk.Name, UserIDs: []int{}}
+		if idx, ok := r.indexes[b]; ok {
+			ratings[i].UserIDs = idx[i].top(limit)
+		}
 	}
+	return ratings
 }
 
-func compareTs(a, b *model.JSONTimestamp) int {
-	switch {
-	case a == nil && b == nil:
-		return 0
-	case a == nil:
-		return +1
-	case b == nil:
-		return -1
-	default:
-		return time.Time(*a).Compare(time.Time(*b))
+func (r *MemRepo) ranks(b boardKey, UserID int) []model.Rank {
+	ranks := make([]model.Rank, len(r.rankings))
+	for i, rk := range r.rankings {
+		ranks[i] = model.Rank{Ranking: rk.Name, Position: -1}
+		if idx, ok := r.indexes[b]; ok {
+			ranks[i].Position = idx[i].rank(UserID)
+		}
+	}
+	return ranks
+}
+
+// indexData orders the players of every board of the data.
+func (r *MemRepo) indexData() {
+	r.indexes = make(map[boardKey][]*rankIndex)
+	for key := range r.data.Boards {
+		r.index(boardKey{key: key})
+	}
+	for key := range r.data.Daily {
+		r.index(boardKey{daily: true, key: key})
+	}
+}
+
+// index returns the indexes of the board, the players are ordered when the board is indexed the first time.
+func (r *MemRepo) index(b boardKey) []*rankIndex {
+	idx, ok := r.indexes[b]
+	if !ok {
+		users := b.users(r.data)
+		idx = make([]*rankIndex, len(r.rankings))
+		for i, rk := range r.rankings {
+			idx[i] = newRankIndex(rk, users)
+		}
+		r.indexes[b] = idx
+	}
+	return idx
+}
+
+// WindowStats returns user's results of the games on the leaderboard since the time, a user who has not played any has empty results.
+// The best games are not kept.
+func (r *MemRepo) WindowStats(ctx context.Context, UserID int, lb model.Leaderboard, since time.Time) (model.User, error) {
+	if err := r.check(ctx); err != nil {
+		return model.User{}, err
+	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	if user, ok := r.windowBoard(lb, since)[UserID]; ok {
+		return user, nil
+	}
+	return model.User{UserID: UserID}, nil
+}
+
+// WindowRatings returns the players who played on the leaderboard since the time in the order of every ranking.
+func (r *MemRepo) WindowRatings(lb model.Leaderboard, since time.Time) []model.Rating {
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	users := r.windowBoard(lb, since)
+	ratings := make([]model.Rating, len(r.rankings))
+	for i, rk := range r.rankings {
+		ratings[i] = model.Rating{Ranking: rk.Name, UserIDs: rk.Sort(users)}
+	}
+	return ratings
+}
+
+// WindowRanks returns the positions of the player among the ones who played on the leaderboard since the time.
+func (r *MemRepo) WindowRanks(ctx context.Context, UserID int, lb model.Leaderboard, since time.Time) ([]model.Rank, error) {
+	if err := r.check(ctx); err != nil {
+		return nil, err
+	}
+	r.latch.RLock()
+	defer r.latch.RUnlock()
+
+	users := r.windowBoard(lb, since)
+	ranks := make([]model.Rank, len(r.rankings))
+	for i, rk := range r.rankings {
+		ranks[i] = model.Rank{Ranking: rk.Name, Position: rk.position(users, UserID)}
+	}
+	return ranks, nil
+}
+
+// windowBoard counts the games started and solved on the leaderboard since the time.
+func (r *MemRepo) windowBoard(lb model.Leaderboard, since time.Time) map[int]model.User {
+	return windowUsers(r.data.Games, lb, since)
+}
+
+// windowUsers counts the games of the leaderboard started and solved since the time, the games are in order of start.
+func windowUsers(games []model.Game, lb model.Leaderboard, since time.Time) map[int]model.User {
+	users := make(map[int]model.User)
+	size := lb.Size.String()
+	for _, g := range games {
+		if g.Size != size || g.Tier != lb.Tier || g.Metric != lb.Metric {
+			continue
+		}
+		started := g.Start != nil && !time.Time(*g.Start).Before(since)
+		solved := g.Status == model.GameSolved && !time.Time(*g.Finish).Before(since)
+		if !started && !solved {
+			continue
+		}
+		u, ok := users[g.UserID]
+		if !ok {
+			u = model.User{UserID: g.UserID}
+		}
+		if started {
+			u.GamesStarted++
+		}
+		if solved && !countSolve(&u, g) && g.Hints == 0 && u.BestTime != nil && g.Duration == *u.BestTime &&
+			time.Time(*g.Finish).Before(time.Time(*u.BestSolveTime)) {
+			// games are in order of start, the one of the same time finished first is the best one
+			u.BestSolveTime = g.Finish
+		}
+		users[g.UserID] = u
+	}
+	return users
+}
+
+// boardKey is a leaderboard of the data, or a daily challenge board.
+type boardKey struct {
+	daily bool
+	key   string
+}
+
+func gameBoard(lb model.Leaderboard) boardKey {
+	return boardKey{key: lb.String()}
+}
+
+// dailyKey is the day with the leaderboard, the daily challenge is a random position so the tier is always expert.
+func dailyKey(day string, lb model.Leaderboard) string {
+	lb.Tier = board.Expert
+	return day + "/" + lb.String()
+}
+
+func dailyBoard(day string, lb model.Leaderboard) boardKey {
+	return boardKey{daily: true, key: dailyKey(day, lb)}
+}
+
+// remove deletes the board from the data.
+func (b boardKey) remove(d *model.Data) {
+	if b.daily {
+		delete(d.Daily, b.key)
+	} else {
+		delete(d.Boards, b.key)
+	}
+}
+
+// users returns the users of the board in the data, the board is added when it is missing.
+func (b boardKey) users(d *model.Data) map[int]model.User {
+	boards := &d.Boards
+	if b.daily {
+		boards = &d.Daily
 	}
+	if *boards == nil {
+		*boards = make(map[string]map[int]model.User)
+	}
+	users, ok := (*boards)[b.key]
+	if !ok {
+		users = make(map[int]model.User)
+		(*boards)[b.key] = users
+	}
+	return users
+}
+
+// tx is a write of the data, the users and the games it changes are journaled.
+// The former values are kept to undo the write.
+type tx struct {
+	*model.Data
+	users       map[boardKey][]int
+	games       []int
+	former      map[boardKey]map[int]*model.User // nil for the user added by the write
+	formerGames map[int]model.Game
+	started     int // the games count before the write
+}
+
+// user marks the user of the board changed, it is marked before the change.
+func (t *tx) user(b boardKey, userID int) {
+	if t.users == nil {
+		t.users = make(map[boardKey][]int)
+		t.former = make(map[boardKey]map[int]*model.User)
+	}
+	t.users[b] = append(t.users[b], userID)
+	former, ok := t.former[b]
+	if !ok {
+		former = make(map[int]*model.User)
+		t.former[b] = former
+	}
+	if _, ok := former[userID]; !ok {
+		if u, ok := b.users(t.Data)[userID]; ok {
+			former[userID] = &u
+		} else {
+			former[userID] = nil
+		}
+	}
+}
+
+// game returns the game of the user for an update, nil when the user has no game of the id.
+func (t *tx) game(UserID, gameID int) *model.Game {
+	if gameOf(t.Data, UserID, gameID) == nil {
+		return nil
+	}
+	return t.gameByID(gameID)
+}
+
+// gameByID returns the game for an update, nil when there is no game of the id.
+func (t *tx) gameByID(gameID int) *model.Game {
+	if gameID < 1 || gameID > len(t.Games) {
+		return nil
+	}
+	t.games = append(t.games, gameID)
+	if t.formerGames == nil {
+		t.formerGames = make(map[int]model.Game)
+	}
+	if _, ok := t.formerGames[gameID]; !ok && gameID <= t.started {
+		t.formerGames[gameID] = t.Games[gameID-1]
+	}
+	return &t.Games[gameID-1]
+}
+
+func (t *tx) addGame(g model.Game) {
+	t.Games = append(t.Games, g)
+	t.games = append(t.games, g.ID)
+}
+
+// entry returns the journal entry of the changed users and games.
+func (t *tx) entry() entry {
+	e := entry{Seq: t.Seq}
+	for b, ids := range t.users {
+		boards := &e.Boards
+		if b.daily {
+			boards = &e.Daily
+		}
+		if *boards == nil {
+			*boards = make(map[string]map[int]model.User)
+		}
+		users := b.users(t.Data)
+		changed := make(map[int]model.User, len(ids))
+		for _, id := range ids {
+			changed[id] = users[id]
+		}
+		(*boards)[b.key] = changed
+	}
+	slices.Sort(t.games)
+	for _, id := range slices.Compact(t.games) {
+		e.Games = append(e.Games, t.Games[id-1])
+	}
+	return e
 }
 
-func (r *FileRepo) withUser(userID int, acceptor func(d *model.User)) error {
-	return r.withData(func(d *model.Data) {
-		user, ok := d.Users[userID]
+// withUserOf applies the acceptor to the user of the board selected by the write, the user is not written when the selection fails.
+func (r *MemRepo) withUserOf(ctx context.Context, userID int, usersOf func(t *tx) (boardKey, error), acceptor func(d *model.User)) error {
+	return r.withData(ctx, func(t *tx) error {
+		b, err := usersOf(t)
+		if err != nil {
+			return err
+		}
+		users := b.users(t.Data)
+		user, ok := users[userID]
 		if !ok {
 			user = model.User{UserID: userID}
 		}
 		acceptor(&user)
-		d.Users[userID] = user
+		t.user(b, userID)
+		users[userID] = user
+		return nil
 	})
 }
 
-func (r *FileRepo) withData(acceptor func(d *model.Data)) error {
+// withData applies the write to the data and persists its entry, the write returns once the entry is committed.
+// A failed write is undone, as well as the one failed to persist, and the repository stops once a write fails to commit.
+func (r *MemRepo) withData(ctx context.Context, acceptor func(t *tx) error) error {
+	if err := r.check(ctx); err != nil {
+		return err
+	}
 	r.latch.Lock()
-	defer r.latch.Unlock()
-
-	acceptor(r.data)
-
-	b, err := json.Marshal(r.data)
-	if err != nil {
-		return fmt.Errorf("data marshall: %s", err)
+	t := &tx{Data: r.data, started: len(r.data.Games)}
+	if err := acceptor(t); err != nil {
+		r.rollback(t)
+		r.latch.Unlock()
+		return err
+	}
+	if len(t.users) == 0 && len(t.games) == 0 {
+		r.latch.Unlock()
+		return nil
 	}
-	repoDir := path.Dir(r.dataFile)
-	tf, err := os.CreateTemp(repoDir, "15-puzzle")
+	for b, ids := range t.users {
+		users, idx := b.users(r.data), r.index(b)
+		for _, id := range ids {
+			for _, ri := range idx {
+				ri.set(users[id])
+			}
+		}
+	}
+	if r.persist == nil {
+		r.latch.Unlock()
+		return nil
+	}
+	r.data.Seq++
+	commit, err := r.persist(t.entry())
 	if err != nil {
-		return fmt.Errorf("create temp file at %s: %s", repoDir, err)
+		r.data.Seq--
+		r.rollback(t)
+		r.latch.Unlock()
+		return err
 	}
+	r.latch.Unlock()
 
-	if _, err := tf.Write(b); err != nil {
-		return fmt.Errorf("write %s: %s", tf.Name(), err)
+	if err := commit(); err != nil {
+		r.failed.CompareAndSwap(nil, &err)
+		return err
 	}
+	return nil
+}
 
-	if err := tf.Close(); err != nil {
-		return fmt.Errorf("close %s to %s: %s", tf.Name(), r.dataFile, err)
+// check fails the request of the done context, and every request once a write failed to commit,
+// the data changed by the write is not served as it is not persisted.
+func (r *MemRepo) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-
-	if err := os.Rename(tf.Name(), r.dataFile); err != nil {
-		return fmt.Errorf("rename %s to %s: %s", tf.Name(), r.dataFile, err)
+	if err := r.failed.Load(); err != nil {
+		return fmt.Errorf("repository stopped by the write failed to commit: %s", *err)
 	}
-
 	return nil
 }
+
+// rollback restores the users and the games changed by the write, the players are ordered by their former values.
+func (r *MemRepo) rollback(t *tx) {
+	for b, former := range t.former {
+		users, idx := b.users(r.data), r.index(b)
+		for id, u := range former {
+			if u == nil {
+				delete(users, id)
+			} else {
+				users[id] = *u
+			}
+			for _, ri := range idx {
+				if u == nil {
+					ri.remove(id)
+				} else {
+					ri.set(*u)
+				}
+			}
+		}
+		if len(users) == 0 {
+			b.remove(r.data)
+			delete(r.indexes, b)
+		}
+	}
+	for id, g := range t.formerGames {
+		r.data.Games[id-1] = g
+	}
+	r.data.Games = r.data.Games[:t.started]
+}
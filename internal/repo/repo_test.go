@@ -1,12 +1,17 @@
 package repo_test
 
 import (
+	"15-puzzle/internal/board"
 	"15-puzzle/internal/model"
 	"15-puzzle/internal/repo"
+	"15-puzzle/internal/web-service/handler"
+	"15-puzzle/internal/web-service/handler/repotest"
 	"context"
+	"errors"
 	"maps"
 	"math/rand/v2"
 	"os"
+	"path/filepath"
 	"slices"
 	"testing"
 	"time"
@@ -20,9 +25,31 @@ func ref(t time.Time) *model.JSONTimestamp {
 	return &r
 }
 
+// recording returns a game of the given number of moves sliding the last tile back and forth
+func recording(moves int) board.Recording {
+	r := board.Recording{Start: board.Solved(board.Classic)}
+	for i := range moves {
+		r.Moves = append(r.Moves, []board.Direction{board.Right, board.Left}[i%2])
+		r.Times = append(r.Times, time.Duration(i)*time.Second)
+	}
+	return r
+}
+
+// solveOf returns the solve of the recording measured by the client
+func solveOf(r board.Recording) model.Solve {
+	return model.Solve{Duration: model.JSONDuration(r.Duration()), Recording: r}
+}
+
 func TestSortRating(t *testing.T) {
+	rankings, err := repo.ParseRankings(repo.DefaultRankings)
+	if err != nil {
+		t.Fatalf("ParseRankings: %s", err)
+	}
 	fp := func(v float32) *float32 { return &v }
+	dp := func(v time.Duration) *model.JSONDuration { d := model.JSONDuration(v); return &d }
 	users := map[int]model.User{
+		8: {UserID: 8, BestTime: dp(time.Second * 10), BestResult: fp(9), GamesSolved: 1, BestSolveTime: ref(time.Now())},
+		9: {UserID: 9, BestTime: dp(time.Second * 20), BestResult: fp(1), GamesSolved: 1, BestSolveTime: ref(time.Now())},
 		1: {UserID: 1, BestResult: fp(5), GamesStarted: 7, GamesSolved: 5, BestSolveTime: ref(time.Now().Add(-time.Second * 3))},
 		2: {UserID: 2, BestResult: fp(5), GamesStarted: 7, GamesSolved: 4, BestSolveTime: ref(time.Now().Add(-time.Second * 3))},
 		3: {UserID: 3, BestResult: fp(5), GamesStarted: 7, GamesSolved: 3, BestSolveTime: ref(time.Now().Add(-time.Second * 3))},
@@ -33,16 +60,158 @@ func TestSortRating(t *testing.T) {
 	}
 	keys := slices.Collect(maps.Keys(users))
 	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
-	result := slices.SortedFunc(slices.Values(keys), func(a, b int) int { return repo.SortRating(users[a], users[b]) })
-	expected := []int{1, 2, 3, 4, 5, 6, 7}
+	result := slices.SortedFunc(slices.Values(keys), func(a, b int) int { return rankings[0].Compare(users[a], users[b]) })
+	expected := []int{8, 9, 1, 2, 3, 4, 5, 6, 7}
 	if slices.Compare(expected, result) != 0 {
 		t.Errorf("rating not correct\nexpected: %v\nactual: %v", expected, result)
 	}
 }
 
+func TestRankings(t *testing.T) {
+	rankings, err := repo.ParseRankings("moves=best_moves,-games_solved;solves=-games_solved")
+	if err != nil {
+		t.Fatalf("ParseRankings: %s", err)
+	}
+	ip := func(v int) *int { return &v }
+	users := map[int]model.User{
+		1: {UserID: 1, BestMoves: ip(40), GamesSolved: 9},
+		2: {UserID: 2, BestMoves: ip(30), GamesSolved: 1},
+		3: {UserID: 3, BestMoves: ip(30), GamesSolved: 2},
+		4: {UserID: 4, GamesSolved: 0},
+	}
+	for i, expected := range [][]int{{3, 2, 1, 4}, {1, 3, 2, 4}} {
+		if actual := rankings[i].Sort(users); slices.Compare(expected, actual) != 0 {
+			t.Errorf("%s rating: expected %v, actual: %v", rankings[i].Name, expected, actual)
+		}
+	}
+	for _, v := range []string{"", "moves", "=best_moves", "moves=fewest", "moves=best_moves;moves=best_time"} {
+		if _, err := repo.ParseRankings(v); err == nil {
+			t.Errorf("ParseRankings %q: expected error", v)
+		}
+	}
+}
+
 func TestRepo(t *testing.T) {
 	testWithNewRepo(t, func(t *testing.T, r *repo.FileRepo) { assertRating(t, []int{}, r) })
 	testWithNewRepo(t, testPlayersAndRatings)
+	testWithNewRepo(t, testBoardSizes)
+	testWithNewRepo(t, testGames)
+	testWithNewRepo(t, testQuarantine)
+	testWithNewRepo(t, testHints)
+	testWithNewRepo(t, testDaily)
+	testWithNewRepo(t, testTiers)
+	testWithNewRepo(t, testMetrics)
+}
+
+func TestConformance(t *testing.T) {
+	t.Run("FileRepo", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) handler.Repository {
+			r, err := repo.NewFileRepo(context.Background(), filepath.Join(t.TempDir(), "data.json"), repo.SyncNever)
+			if err != nil {
+				t.Fatalf("repo init: %s", err)
+			}
+			t.Cleanup(func() {
+				if err := r.Close(); err != nil {
+					t.Errorf("repo close: %s", err)
+				}
+			})
+			return r
+		})
+	})
+	t.Run("MemRepo", func(t *testing.T) {
+		repotest.Run(t, func(*testing.T) handler.Repository { return repo.NewMemRepo() })
+	})
+	t.Run("KVRepo", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) handler.Repository { return openKVRepo(t, filepath.Join(t.TempDir(), "data.json")) })
+	})
+	t.Run("KVRepoImport", func(t *testing.T) {
+		// the data is kept in the journal of the FileRepo, the store missing is imported from it
+		file := filepath.Join(t.TempDir(), "data.json")
+		var written *repo.FileRepo
+		repotest.RunImport(t, func(t *testing.T) handler.Repository {
+			r, err := repo.NewFileRepo(context.Background(), file, repo.SyncAlways)
+			if err != nil {
+				t.Fatalf("repo init: %s", err)
+			}
+			written = r
+			return r
+		}, func(t *testing.T) handler.Repository {
+			if err := written.Close(); err != nil {
+				t.Fatalf("repo close: %s", err)
+			}
+			return openKVRepo(t, file)
+		})
+	})
+}
+
+func TestLegacyDataFile(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "puzzle15-repo-test")
+	if err != nil {
+		t.Fatalf("temporary file create: %s", err)
+	}
+	if _, err := f.WriteString(`{"users":{"1":{"user_id":1,"games_started":3,"games_solved":2,"best_result":1.5,"best_solve_ts":1700000000}}}`); err != nil {
+		t.Fatalf("temporary file write: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("temporary file close: %s", err)
+	}
+
+	testWithRepo(t, f.Name(), func(t *testing.T, r *repo.FileRepo) {
+		u, err := r.Stats(ctx, 1, model.Leaderboard{Size: board.Classic})
+		if err != nil {
+			t.Fatalf("Stats: %s", err)
+		}
+		assertUserHaveValues(t, model.User{UserID: 1, GamesStarted: 3, GamesSolved: 2, BestSolveTime: ref(time.Unix(1700000000, 0))}, u)
+		assertRating(t, []int{1}, r)
+	})
+}
+
+func TestWindows(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "puzzle15-repo-test")
+	if err != nil {
+		t.Fatalf("temporary file create: %s", err)
+	}
+	// user 1 solved fast a day before, user 2 solved slower twice on the day and once with hints, user 3 solved a 3x3 board
+	if _, err := f.WriteString(`{"solves":[` +
+		`{"user_id":1,"size":"4x4","moves":20,"duration":20000,"ts":1700000000},` +
+		`{"user_id":2,"size":"4x4","moves":30,"duration":40000,"ts":1700086400},` +
+		`{"user_id":2,"size":"4x4","moves":40,"duration":30000,"ts":1700090000},` +
+		`{"user_id":2,"size":"4x4","moves":10,"duration":10000,"hints":1,"ts":1700090100},` +
+		`{"user_id":3,"size":"3x3","moves":10,"duration":10000,"ts":1700090200}]}`); err != nil {
+		t.Fatalf("temporary file write: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("temporary file close: %s", err)
+	}
+
+	testWithRepo(t, f.Name(), func(t *testing.T, r *repo.FileRepo) {
+		classic, day := model.Leaderboard{Size: board.Classic}, time.Unix(1700086400, 0)
+		if actual := r.WindowRatings(classic, time.Time{})[0].UserIDs; slices.Compare([]int{1, 2}, actual) != 0 {
+			t.Errorf("all time rating: expected [1 2], actual: %v", actual)
+		}
+		if actual := r.WindowRatings(classic, day)[0].UserIDs; slices.Compare([]int{2}, actual) != 0 {
+			t.Errorf("day rating: expected [2], actual: %v", actual)
+		}
+		if actual := r.WindowRatings(classic, day.Add(24*time.Hour))[0].UserIDs; len(actual) != 0 {
+			t.Errorf("next day rating: expected empty, actual: %v", actual)
+		}
+		u, err := r.WindowStats(ctx, 2, classic, day)
+		if err != nil {
+			t.Fatalf("WindowStats: %s", err)
+		}
+		assertUserHaveValues(t, model.User{UserID: 2, GamesSolved: 3, BestSolveTime: ref(time.Unix(1700090000, 0))}, u)
+		if u.BestTime == nil || *u.BestTime != model.JSONDuration(30*time.Second) {
+			t.Errorf("best time: expected 30s, actual: %v", u.BestTime)
+		}
+		if u.BestMoves == nil || *u.BestMoves != 30 {
+			t.Errorf("best moves: expected 30, actual: %v", u.BestMoves)
+		}
+		u, err = r.WindowStats(ctx, 1, classic, day)
+		if err != nil {
+			t.Fatalf("WindowStats: %s", err)
+		}
+		assertUserHaveValues(t, model.User{UserID: 1}, u)
+	})
 }
 
 func testWithNewRepo(t *testing.T, test func(t *testing.T, r *repo.FileRepo)) {
@@ -66,10 +235,18 @@ func testWithNewRepo(t *testing.T, test func(t *testing.T, r *repo.FileRepo)) {
 }
 
 func testWithRepo(t *testing.T, file string, test func(t *testing.T, r *repo.FileRepo)) {
-	r, err := repo.NewFileRepo(context.Background(), file)
+	r, err := repo.NewFileRepo(context.Background(), file, repo.SyncAlways)
 	if err != nil {
 		t.Fatalf("repo init: %s", err)
 	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			t.Errorf("repo close: %s", err)
+		}
+		if err := os.Remove(file + repo.JournalSuffix); err != nil {
+			t.Errorf("journal remove: %s", err)
+		}
+	}()
 
 	test(t, r)
 }
@@ -94,15 +271,293 @@ func testPlayersAndRatings(t *testing.T, r *repo.FileRepo) {
 		model.User{UserID: testUserThree, GamesStarted: 1, GamesSolved: 1, BestSolveTime: ref(time.Now())})
 	assertRating(t, []int{testUserThree, testUserOne, testUserTwo}, r)
 
+	// the game of fewer moves takes less time
 	assertRegisterGameSolve(t, testUserTwo, 20, r,
 		model.User{UserID: testUserTwo, GamesStarted: 1, GamesSolved: 1, BestSolveTime: ref(time.Now())})
-	assertRating(t, []int{testUserThree, testUserTwo, testUserOne}, r)
+	assertRating(t, []int{testUserTwo, testUserThree, testUserOne}, r)
 
 	assertRegisterGameStart(t, testUserTwo, r,
 		model.User{UserID: testUserTwo, GamesStarted: 2, GamesSolved: 1, BestSolveTime: ref(time.Now())})
 	assertRegisterGameSolve(t, testUserTwo, 10, r,
 		model.User{UserID: testUserTwo, GamesStarted: 2, GamesSolved: 2, BestSolveTime: ref(time.Now())})
-	assertRating(t, []int{testUserThree, testUserTwo, testUserOne}, r)
+	assertRating(t, []int{testUserTwo, testUserThree, testUserOne}, r)
+
+	ratings := r.Ratings(model.Leaderboard{Size: board.Classic})
+	names := make([]string, len(ratings))
+	for i := range ratings {
+		names[i] = ratings[i].Ranking
+	}
+	if expected := []string{"time", "moves", "per_move", "solves"}; slices.Compare(expected, names) != 0 {
+		t.Errorf("rankings: expected %v, actual: %v", expected, names)
+	}
+	if expected := []int{testUserTwo, testUserThree, testUserOne}; slices.Compare(expected, ratings[3].UserIDs) != 0 {
+		t.Errorf("solves rating: expected %v, actual: %v", expected, ratings[3].UserIDs)
+	}
+}
+
+func testBoardSizes(t *testing.T, r *repo.FileRepo) {
+	small, large := board.Size{W: 3, H: 3}, board.Size{W: 5, H: 4}
+
+	assertRegisterGameStart(t, 1, r, model.User{UserID: 1, GamesStarted: 1})
+	if _, _, err := r.RegisterGameStart(ctx, 2, model.Leaderboard{Size: small}, ""); err != nil {
+		t.Fatalf("RegisterGameStart: %s", err)
+	}
+	if _, err := r.RegisterGameSolve(ctx, 2, model.Leaderboard{Size: small}, lastGame(t, r, 2), solveOf(recording(30))); err != nil {
+		t.Fatalf("RegisterGameSolve: %s", err)
+	}
+	assertRating(t, []int{1}, r)
+	if actual := r.Rating(model.Leaderboard{Size: small}); slices.Compare([]int{2}, actual) != 0 {
+		t.Errorf("small board rating: expected [2], actual: %v", actual)
+	}
+	if actual := r.Rating(model.Leaderboard{Size: large}); len(actual) != 0 {
+		t.Errorf("large board rating: expected empty, actual: %v", actual)
+	}
+
+	u, err := r.Stats(ctx, 1, model.Leaderboard{Size: small})
+	if err != nil {
+		t.Fatalf("Stats: %s", err)
+	}
+	assertUserHaveValues(t, model.User{UserID: 1}, u)
+	if _, err := r.Stats(ctx, 3, model.Leaderboard{Size: small}); err == nil {
+		t.Errorf("Stats: unknown user should not be found")
+	}
+
+	if err := r.RegisterRejectedSolve(ctx, 2, model.Leaderboard{Size: large}, 0); err != nil {
+		t.Fatalf("RegisterRejectedSolve: %s", err)
+	}
+
+	m, err := r.Monitoring(ctx)
+	if err != nil {
+		t.Fatalf("Monitoring: %s", err)
+	}
+	if m != (model.Monitoring{Users: 2, GamesStarted: 2, GamesSolved: 1, GamesRejected: 1}) {
+		t.Errorf("Monitoring: unexpected value: %#v", m)
+	}
+}
+
+func testGames(t *testing.T, r *repo.FileRepo) {
+	classic := model.Leaderboard{Size: board.Classic}
+	var ids []int
+	for _, scramble := range []string{"4x4:1", "4x4:2", "4x4:3"} {
+		_, g, err := r.RegisterGameStart(ctx, 1, classic, scramble)
+		if err != nil {
+			t.Fatalf("RegisterGameStart: %s", err)
+		}
+		ids = append(ids, g.ID)
+	}
+	if _, err := r.Game(ctx, 2, ids[0]); !errors.Is(err, model.ErrGameNotFound) {
+		t.Errorf("Game: game of other user should not be found, actual: %v", err)
+	}
+	g, err := r.Game(ctx, 1, ids[0])
+	if err != nil || g.Scramble != "4x4:1" || g.Status != model.GameStarted || g.Start == nil {
+		t.Errorf("Game: expected the first game started, actual: %#v", g)
+	}
+
+	// games are finished in other order than started, each one by its id
+	for _, solve := range []struct {
+		id    int
+		moves int
+	}{{ids[1], 10}, {ids[0], 20}} {
+		if _, err := r.RegisterGameSolve(ctx, 1, classic, solve.id, solveOf(recording(solve.moves))); err != nil {
+			t.Fatalf("RegisterGameSolve: %s", err)
+		}
+	}
+	if _, err := r.RegisterGameSolve(ctx, 1, classic, ids[1], solveOf(recording(10))); !errors.Is(err, model.ErrGameNotStarted) {
+		t.Errorf("RegisterGameSolve: solved game should not be solved again, actual: %v", err)
+	}
+	if _, err := r.RegisterGameSolve(ctx, 2, classic, ids[2], solveOf(recording(10))); !errors.Is(err, model.ErrGameNotFound) {
+		t.Errorf("RegisterGameSolve: game of other user should not be solved, actual: %v", err)
+	}
+	if _, err := r.RegisterGameSolve(ctx, 1, model.Leaderboard{Size: board.Classic, Tier: board.Easy}, ids[2], solveOf(recording(10))); !errors.Is(err, model.ErrOtherLeaderboard) {
+		t.Errorf("RegisterGameSolve: game of other leaderboard should not be solved, actual: %v", err)
+	}
+	if err := r.RegisterRejectedSolve(ctx, 1, classic, ids[2]); err != nil {
+		t.Fatalf("RegisterRejectedSolve: %s", err)
+	}
+
+	games, err := r.Games(ctx, 1)
+	if err != nil || len(games) != 3 {
+		t.Fatalf("Games: expected 3, actual: %#v", games)
+	}
+	for i, expected := range []struct {
+		status model.GameStatus
+		moves  int
+	}{{model.GameSolved, 20}, {model.GameSolved, 10}, {model.GameRejected, 0}} {
+		if games[i].Status != expected.status || games[i].Moves != expected.moves || games[i].Finish == nil {
+			t.Errorf("game %d: expected %s of %d moves, actual: %#v", i+1, expected.status, expected.moves, games[i])
+		}
+	}
+	u, err := r.Stats(ctx, 1, classic)
+	if err != nil {
+		t.Fatalf("Stats: %s", err)
+	}
+	if u.GamesStarted != 3 || u.GamesSolved != 2 || u.GamesRejected != 1 || u.BestMoves == nil || *u.BestMoves != 10 {
+		t.Errorf("Stats: unexpected value: %#v", u)
+	}
+}
+
+func testQuarantine(t *testing.T, r *repo.FileRepo) {
+	classic := model.Leaderboard{Size: board.Classic}
+	suspicion := model.Suspicion{Score: 1, Reasons: []string{"too fast"}}
+	var ids []int
+	for _, scramble := range []string{"4x4:1", "4x4:2"} {
+		_, g, err := r.RegisterGameStart(ctx, 1, classic, scramble)
+		if err != nil {
+			t.Fatalf("RegisterGameStart: %s", err)
+		}
+		u, err := r.RegisterQuarantinedSolve(ctx, 1, classic, g.ID, solveOf(recording(10)), suspicion)
+		if err != nil {
+			t.Fatalf("RegisterQuarantinedSolve: %s", err)
+		}
+		if u.GamesSolved != 0 || u.BestMoves != nil {
+			t.Errorf("RegisterQuarantinedSolve: quarantined game should not be counted: %#v", u)
+		}
+		ids = append(ids, g.ID)
+	}
+	if games, err := r.Quarantine(ctx); err != nil || len(games) != 2 || games[0].Recording == nil || games[0].Suspicion == nil {
+		t.Fatalf("Quarantine: expected 2 games with recordings, actual: %#v", games)
+	}
+
+	if g, err := r.ReviewGame(ctx, ids[0], true); err != nil || g.Status != model.GameSolved {
+		t.Errorf("ReviewGame: expected approved game solved, actual: %#v, %v", g, err)
+	}
+	if g, err := r.ReviewGame(ctx, ids[1], false); err != nil || g.Status != model.GameRejected {
+		t.Errorf("ReviewGame: expected game rejected, actual: %#v, %v", g, err)
+	}
+	if _, err := r.ReviewGame(ctx, ids[0], false); !errors.Is(err, model.ErrNotQuarantined) {
+		t.Errorf("ReviewGame: reviewed game should not be reviewed again, actual: %v", err)
+	}
+	if games, err := r.Quarantine(ctx); err != nil || len(games) != 0 {
+		t.Errorf("Quarantine: expected no games, actual: %#v", games)
+	}
+	u, err := r.Stats(ctx, 1, classic)
+	if err != nil {
+		t.Fatalf("Stats: %s", err)
+	}
+	if u.GamesSolved != 1 || u.GamesRejected != 1 || u.BestMoves == nil || *u.BestMoves != 10 || u.BestGame == nil {
+		t.Errorf("Stats: unexpected value: %#v", u)
+	}
+}
+
+func testHints(t *testing.T, r *repo.FileRepo) {
+	assertRegisterGameStart(t, 1, r, model.User{UserID: 1, GamesStarted: 1})
+	assertRegisterGameStart(t, 2, r, model.User{UserID: 2, GamesStarted: 1})
+	u, err := r.RegisterGameSolve(ctx, 1, model.Leaderboard{Size: board.Classic}, lastGame(t, r, 1), model.Solve{Recording: recording(10), Hints: 2})
+	if err != nil {
+		t.Fatalf("RegisterGameSolve: %s", err)
+	}
+	assertUserHaveValues(t, model.User{UserID: 1, GamesStarted: 1, GamesSolved: 1}, u)
+	if u.GamesHinted != 1 || u.BestTime != nil || u.BestMoves != nil {
+		t.Errorf("hinted game should not be ranked: %#v", u)
+	}
+	assertRegisterGameSolve(t, 2, 50, r,
+		model.User{UserID: 2, GamesStarted: 1, GamesSolved: 1, BestSolveTime: ref(time.Now())})
+	assertRating(t, []int{2, 1}, r)
+}
+
+func testDaily(t *testing.T, r *repo.FileRepo) {
+	day, classic := "2024-12-31", model.Leaderboard{Size: board.Classic}
+	u, err := r.DailyStats(ctx, 1, day, classic)
+	if err != nil {
+		t.Fatalf("DailyStats: %s", err)
+	}
+	assertUserHaveValues(t, model.User{UserID: 1}, u)
+
+	slow := recording(10)
+	slow.Times[9] = time.Minute
+	if _, err := r.RegisterDailySolve(ctx, 1, day, classic, solveOf(slow)); err != nil {
+		t.Fatalf("RegisterDailySolve: %s", err)
+	}
+	if _, err := r.RegisterDailySolve(ctx, 2, day, classic, solveOf(recording(10))); err != nil {
+		t.Fatalf("RegisterDailySolve: %s", err)
+	}
+	if _, err := r.RegisterDailySolve(ctx, 3, day, classic, model.Solve{Recording: recording(10), Hints: 1}); err != nil {
+		t.Fatalf("RegisterDailySolve: %s", err)
+	}
+	if actual := r.DailyRating(day, classic); slices.Compare([]int{2, 1, 3}, actual) != 0 {
+		t.Errorf("daily rating: expected [2 1 3], actual: %v", actual)
+	}
+	if actual := r.DailyRating("2025-01-01", classic); len(actual) != 0 {
+		t.Errorf("next day rating: expected empty, actual: %v", actual)
+	}
+	if actual := r.DailyRating(day, model.Leaderboard{Size: board.Size{W: 3, H: 3}}); len(actual) != 0 {
+		t.Errorf("daily rating of other size: expected empty, actual: %v", actual)
+	}
+	u, err = r.DailyStats(ctx, 1, day, classic)
+	if err != nil {
+		t.Fatalf("DailyStats: %s", err)
+	}
+	assertUserHaveValues(t, model.User{UserID: 1, GamesStarted: 1, GamesSolved: 1, BestSolveTime: ref(time.Now())}, u)
+	assertRating(t, []int{}, r)
+}
+
+func testTiers(t *testing.T, r *repo.FileRepo) {
+	easy := model.Leaderboard{Size: board.Classic, Tier: board.Easy}
+	assertRegisterGameStart(t, 1, r, model.User{UserID: 1, GamesStarted: 1})
+	if _, _, err := r.RegisterGameStart(ctx, 2, easy, ""); err != nil {
+		t.Fatalf("RegisterGameStart: %s", err)
+	}
+	solve := solveOf(recording(20))
+	solve.Tier, solve.Optimal = board.Easy, 12
+	u, err := r.RegisterGameSolve(ctx, 2, easy, lastGame(t, r, 2), solve)
+	if err != nil {
+		t.Fatalf("RegisterGameSolve: %s", err)
+	}
+	assertUserHaveValues(t, model.User{UserID: 2, GamesStarted: 1, GamesSolved: 1, BestSolveTime: ref(time.Now())}, u)
+	assertRating(t, []int{1}, r)
+	if actual := r.Rating(easy); slices.Compare([]int{2}, actual) != 0 {
+		t.Errorf("easy tier rating: expected [2], actual: %v", actual)
+	}
+	if actual := r.Rating(model.Leaderboard{Size: board.Classic, Tier: board.Hard}); len(actual) != 0 {
+		t.Errorf("hard tier rating: expected empty, actual: %v", actual)
+	}
+	u, err = r.Stats(ctx, 2, model.Leaderboard{Size: board.Classic})
+	if err != nil {
+		t.Fatalf("Stats: %s", err)
+	}
+	assertUserHaveValues(t, model.User{UserID: 2}, u)
+}
+
+func testMetrics(t *testing.T, r *repo.FileRepo) {
+	day := "2024-12-31"
+	single, multi := model.Leaderboard{Size: board.Classic}, model.Leaderboard{Size: board.Classic, Metric: board.MultiTile}
+	// three tiles slide right and back in 5 seconds: 6 single-tile moves or 2 multi-tile ones
+	rec := board.Recording{Start: board.Solved(board.Classic)}
+	for i, d := range []board.Direction{board.Right, board.Right, board.Right, board.Left, board.Left, board.Left} {
+		rec.Moves = append(rec.Moves, d)
+		rec.Times = append(rec.Times, time.Duration(i+1)*time.Second)
+	}
+	for _, tc := range []struct {
+		lb       model.Leaderboard
+		expected int
+	}{{single, 6}, {multi, 2}} {
+		solve := solveOf(rec)
+		solve.Metric = tc.lb.Metric
+		u, err := r.RegisterDailySolve(ctx, 1, day, tc.lb, solve)
+		if err != nil {
+			t.Fatalf("RegisterDailySolve: %s", err)
+		}
+		if u.BestMoves == nil || *u.BestMoves != tc.expected {
+			t.Errorf("%s best moves: expected %v, actual: %v", tc.lb, tc.expected, u.BestMoves)
+		}
+		if u.BestTime == nil || *u.BestTime != model.JSONDuration(5*time.Second) {
+			t.Errorf("%s best time: expected 5s, actual: %v", tc.lb, u.BestTime)
+		}
+	}
+	solve := solveOf(rec)
+	solve.Metric = board.MultiTile
+	if _, _, err := r.RegisterGameStart(ctx, 2, multi, ""); err != nil {
+		t.Fatalf("RegisterGameStart: %s", err)
+	}
+	if _, err := r.RegisterGameSolve(ctx, 2, multi, lastGame(t, r, 2), solve); err != nil {
+		t.Fatalf("RegisterGameSolve: %s", err)
+	}
+	if actual := r.Rating(multi); slices.Compare([]int{2}, actual) != 0 {
+		t.Errorf("multi-tile rating: expected [2], actual: %v", actual)
+	}
+	if actual := r.Rating(single); len(actual) != 0 {
+		t.Errorf("single-tile rating: expected empty, actual: %v", actual)
+	}
 }
 
 func assertUserHaveValues(t *testing.T, expected, actual model.User) {
@@ -131,7 +586,7 @@ func assertUserHaveValues(t *testing.T, expected, actual model.User) {
 }
 
 func assertRegisterGameStart(t *testing.T, UserID int, r *repo.FileRepo, expected model.User) {
-	u, err := r.RegisterGameStart(UserID)
+	u, _, err := r.RegisterGameStart(ctx, UserID, model.Leaderboard{Size: board.Classic}, "")
 	if err != nil {
 		t.Fatalf("RegisterGameStart: %s", err)
 	}
@@ -139,15 +594,30 @@ func assertRegisterGameStart(t *testing.T, UserID int, r *repo.FileRepo, expecte
 }
 
 func assertRegisterGameSolve(t *testing.T, UserID, moves int, r *repo.FileRepo, expected model.User) {
-	u, err := r.RegisterGameSolve(UserID, moves)
+	u, err := r.RegisterGameSolve(ctx, UserID, model.Leaderboard{Size: board.Classic}, lastGame(t, r, UserID), solveOf(recording(moves)))
 	if err != nil {
 		t.Fatalf("RegisterGameSolve: %s", err)
 	}
 	assertUserHaveValues(t, expected, u)
 }
 
+// lastGame returns the id of the last game the user started
+func lastGame(t *testing.T, r *repo.FileRepo, UserID int) int {
+	games, err := r.Games(ctx, UserID)
+	if err != nil {
+		t.Fatalf("Games: %s", err)
+	}
+	for i := len(games) - 1; i >= 0; i-- {
+		if games[i].Status == model.GameStarted {
+			return games[i].ID
+		}
+	}
+	t.Fatalf("user_id=%d has no started game", UserID)
+	return 0
+}
+
 func assertRating(t *testing.T, expected []int, r *repo.FileRepo) {
-	actual := r.Rating()
+	actual := r.Rating(model.Leaderboard{Size: board.Classic})
 	if slices.Compare(expected, actual) != 0 {
 		t.Errorf("get rating: wrong value\nexpected: %#v\nactual: %#v", expected, actual)
 	}
@@ -0,0 +1,89 @@
+package solver
+
+import "15-puzzle/internal/board"
+
+// Heuristic estimates the number of moves to solve a position.
+// It must never overestimate to keep the found solutions optimal.
+type Heuristic interface {
+	Estimate(b board.Board) int
+}
+
+// Manhattan sums the grid distances of every tile to its goal position.
+type Manhattan struct{}
+
+func (Manhattan) Estimate(b board.Board) int {
+	s := b.Size()
+	d := 0
+	for pos, t := range b.Cells() {
+		if t == 0 {
+			continue
+		}
+		goal := int(t) - 1
+		d += abs(s.Col(pos)-s.Col(goal)) + abs(s.Row(pos)-s.Row(goal))
+	}
+	return d
+}
+
+// LinearConflict adds two moves to the Manhattan distance for every tile which has to leave its goal row or column
+// to let another tile of the same line pass by. The number of such tiles in a line is the line length
+// minus the longest sequence of the line's tiles already placed in the goal order.
+type LinearConflict struct{}
+
+func (LinearConflict) Estimate(b board.Board) int {
+	s := b.Size()
+	cells := b.Cells()
+	d := Manhattan{}.Estimate(b)
+	line := make([]int, 0, board.MaxSize)
+	for row := range s.H {
+		line = line[:0]
+		for col := range s.W {
+			if t := cells[s.Pos(col, row)]; t > 0 && s.Row(int(t)-1) == row {
+				line = append(line, s.Col(int(t)-1))
+			}
+		}
+		d += 2 * (len(line) - longestIncreasing(line))
+	}
+	for col := range s.W {
+		line = line[:0]
+		for row := range s.H {
+			if t := cells[s.Pos(col, row)]; t > 0 && s.Col(int(t)-1) == col {
+				line = append(line, s.Row(int(t)-1))
+			}
+		}
+		d += 2 * (len(line) - longestIncreasing(line))
+	}
+	return d
+}
+
+// Max takes the largest estimate of the heuristics, e.g. a pattern database and LinearConflict.
+type Max []Heuristic
+
+func (m Max) Estimate(b board.Board) int {
+	h := 0
+	for i := range m {
+		h = max(h, m[i].Estimate(b))
+	}
+	return h
+}
+
+func longestIncreasing(seq []int) int {
+	var length [board.MaxSize]int
+	longest := 0
+	for i := range seq {
+		length[i] = 1
+		for j := range i {
+			if seq[j] < seq[i] && length[j]+1 > length[i] {
+				length[i] = length[j] + 1
+			}
+		}
+		longest = max(longest, length[i])
+	}
+	return longest
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,196 @@
+package pdb
+
+import (
+	"15-puzzle/internal/board"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+)
+
+// File layout, integers are little-endian:
+//
+//	magic    [5]byte "15PDB"
+//	version  uint16
+//	width    uint8
+//	height   uint8
+//	patterns uint8
+//	pattern headers: tiles count uint8, tiles [count]uint8, table length uint32
+//	pattern tables
+//	crc32    uint32 IEEE checksum of all the preceding bytes
+const (
+	magic   = "15PDB"
+	Version = 1
+)
+
+var (
+	ErrCorrupted = errors.New("pattern database corrupted")
+	ErrVersion   = errors.New("pattern database version not supported")
+)
+
+func (d *Database) WriteTo(w io.Writer) (int64, error) {
+	h := crc32.NewIEEE()
+	cw := &countWriter{w: io.MultiWriter(w, h)}
+	header := []byte(magic)
+	header = binary.LittleEndian.AppendUint16(header, Version)
+	header = append(header, byte(d.size.W), byte(d.size.H), byte(len(d.patterns)))
+	for _, p := range d.patterns {
+		header = append(header, byte(len(p.tiles)))
+		header = append(header, p.tiles...)
+		header = binary.LittleEndian.AppendUint32(header, uint32(len(p.table)))
+	}
+	if _, err := cw.Write(header); err != nil {
+		return cw.n, err
+	}
+	for _, p := range d.patterns {
+		if _, err := cw.Write(p.table); err != nil {
+			return cw.n, err
+		}
+	}
+	_, err := w.Write(binary.LittleEndian.AppendUint32(nil, h.Sum32()))
+	return cw.n + 4, err
+}
+
+// Save writes the database to a temporary file next to the target and renames it then.
+func (d *Database) Save(file string) error {
+	dir := path.Dir(path.Clean(file))
+	tf, err := os.CreateTemp(dir, "15-puzzle-pdb")
+	if err != nil {
+		return fmt.Errorf("create temp file at %s: %s", dir, err)
+	}
+	defer os.Remove(tf.Name())
+
+	bw := bufio.NewWriter(tf)
+	if _, err := d.WriteTo(bw); err != nil {
+		return fmt.Errorf("write %s: %s", tf.Name(), err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write %s: %s", tf.Name(), err)
+	}
+	if err := tf.Close(); err != nil {
+		return fmt.Errorf("close %s: %s", tf.Name(), err)
+	}
+	if err := os.Rename(tf.Name(), file); err != nil {
+		return fmt.Errorf("rename %s to %s: %s", tf.Name(), file, err)
+	}
+	return nil
+}
+
+// Read loads the whole database into memory.
+func Read(r io.Reader) (*Database, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parse(b)
+}
+
+// Load maps the database file into memory where supported and reads it otherwise.
+// The checksum is verified in both cases, so the whole file is read once anyway.
+func Load(file string) (*Database, error) {
+	b, unmap, err := mapFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %s", file, err)
+	}
+	d, err := parse(b)
+	if err != nil {
+		if unmap != nil {
+			_ = unmap()
+		}
+		return nil, fmt.Errorf("load %s: %w", file, err)
+	}
+	d.unmap = unmap
+	return d, nil
+}
+
+// parse makes the database referencing tables within b without copying.
+func parse(b []byte) (*Database, error) {
+	if len(b) < len(magic)+2 || string(b[:len(magic)]) != magic {
+		return nil, ErrCorrupted
+	}
+	if v := binary.LittleEndian.Uint16(b[len(magic):]); v != Version {
+		return nil, fmt.Errorf("%w: %d", ErrVersion, v)
+	}
+	if len(b) < 4 || crc32.ChecksumIEEE(b[:len(b)-4]) != binary.LittleEndian.Uint32(b[len(b)-4:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupted)
+	}
+	body := b[:len(b)-4]
+	r := &reader{b: body, off: len(magic) + 2}
+	d := &Database{size: board.Size{W: int(r.byte()), H: int(r.byte())}}
+	if err := d.size.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorrupted, err)
+	}
+	count := int(r.byte())
+	partition := make([][]byte, count)
+	lengths := make([]int, count)
+	for i := range count {
+		partition[i] = r.bytes(int(r.byte()))
+		lengths[i] = int(r.uint32())
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorrupted, r.err)
+	}
+	if err := validatePartition(d.size, partition); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorrupted, err)
+	}
+	for i := range count {
+		p := newPattern(d.size, partition[i])
+		if expected := perms(d.size.Tiles(), len(p.tiles)); lengths[i] != expected {
+			return nil, fmt.Errorf("%w: pattern %v table length %d, expected %d", ErrCorrupted, p.tiles, lengths[i], expected)
+		}
+		p.table = r.bytes(lengths[i])
+		d.patterns = append(d.patterns, p)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorrupted, r.err)
+	}
+	if r.off != len(body) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrCorrupted, len(body)-r.off)
+	}
+	return d, nil
+}
+
+type reader struct {
+	b   []byte
+	off int
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || r.off+n > len(r.b) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := r.b[r.off : r.off+n : r.off+n]
+	r.off += n
+	return v
+}
+
+func (r *reader) byte() byte {
+	if v := r.bytes(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if v := r.bytes(4); v != nil {
+		return binary.LittleEndian.Uint32(v)
+	}
+	return 0
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
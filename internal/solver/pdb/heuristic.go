@@ -0,0 +1,37 @@
+package pdb
+
+import (
+	"15-puzzle/internal/solver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LoadHeuristic loads the comma separated database files, every database estimates the positions of its board size.
+// The heuristic takes the largest of their estimates and LinearConflict, close releases the databases.
+func LoadHeuristic(files string) (solver.Heuristic, func() error, error) {
+	h := solver.Max{solver.LinearConflict{}}
+	var dbs []*Database
+	closeAll := func() error {
+		var errs []error
+		for _, db := range dbs {
+			errs = append(errs, db.Close())
+		}
+		return errors.Join(errs...)
+	}
+	sizes := make(map[string]string)
+	for _, file := range strings.Split(files, ",") {
+		file = strings.TrimSpace(file)
+		db, err := Load(file)
+		if err != nil {
+			return nil, nil, errors.Join(err, closeAll())
+		}
+		dbs = append(dbs, db)
+		if other, ok := sizes[db.Size().String()]; ok {
+			return nil, nil, errors.Join(fmt.Errorf("databases %s and %s of the same size %s", other, file, db.Size()), closeAll())
+		}
+		sizes[db.Size().String()] = file
+		h = append(h, db)
+	}
+	return h, closeAll, nil
+}
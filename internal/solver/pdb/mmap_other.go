@@ -0,0 +1,10 @@
+//go:build !unix
+
+package pdb
+
+import "os"
+
+func mapFile(file string) ([]byte, func() error, error) {
+	b, err := os.ReadFile(file)
+	return b, nil, err
+}
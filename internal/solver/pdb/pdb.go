@@ -0,0 +1,334 @@
+package pdb
+
+import (
+	"15-puzzle/internal/board"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	unknown    = 0xFF
+	ctxCheckOp = 1 << 16
+)
+
+// Database is a set of disjoint additive pattern databases.
+// Every pattern table holds the number of the pattern's tiles moves to bring them to their goal positions,
+// indexed by the tiles placement. Only the pattern's tiles moves are counted, so the tables values can be summed up
+// staying an admissible heuristic.
+type Database struct {
+	size     board.Size
+	patterns []pattern
+	unmap    func() error
+}
+
+type pattern struct {
+	tiles   []byte
+	table   []byte
+	weights []int
+}
+
+func newPattern(s board.Size, tiles []byte) pattern {
+	p := pattern{tiles: tiles, weights: make([]int, len(tiles))}
+	for i := range tiles {
+		p.weights[i] = perms(s.Tiles()-1-i, len(tiles)-1-i)
+	}
+	return p
+}
+
+// rank returns the index of the tiles placement among all the k-permutations of the board cells.
+func (p *pattern) rank(pos []int) int {
+	r := 0
+	for i := range pos {
+		c := pos[i]
+		for j := range i {
+			if pos[j] < pos[i] {
+				c--
+			}
+		}
+		r += c * p.weights[i]
+	}
+	return r
+}
+
+func (p *pattern) unrank(r int, pos []int) {
+	var used [board.MaxTiles]bool
+	for i := range pos {
+		c := r / p.weights[i]
+		r %= p.weights[i]
+		for cell := 0; ; cell++ {
+			if used[cell] {
+				continue
+			}
+			if c == 0 {
+				pos[i] = cell
+				used[cell] = true
+				break
+			}
+			c--
+		}
+	}
+}
+
+// perms is the number of k-permutations of n: n!/(n-k)!
+func perms(n, k int) int {
+	p := 1
+	for i := range k {
+		p *= n - i
+	}
+	return p
+}
+
+func (d *Database) Size() board.Size {
+	return d.size
+}
+
+// Partition returns the tiles of every pattern.
+func (d *Database) Partition() [][]byte {
+	result := make([][]byte, len(d.patterns))
+	for i := range d.patterns {
+		result[i] = slices.Clone(d.patterns[i].tiles)
+	}
+	return result
+}
+
+// Estimate sums up the patterns costs of the position, it is zero for a board of another size.
+func (d *Database) Estimate(b board.Board) int {
+	if b.Size() != d.size {
+		return 0
+	}
+	var at [board.MaxTiles]int
+	for pos, t := range b.Cells() {
+		at[t] = pos
+	}
+	var pos [board.MaxTiles]int
+	h := 0
+	for i := range d.patterns {
+		p := &d.patterns[i]
+		for j, t := range p.tiles {
+			pos[j] = at[t]
+		}
+		h += int(p.table[p.rank(pos[:len(p.tiles)])])
+	}
+	return h
+}
+
+// Close releases the memory mapped database file, if any.
+func (d *Database) Close() error {
+	if d.unmap == nil {
+		return nil
+	}
+	unmap := d.unmap
+	d.unmap, d.patterns = nil, nil
+	return unmap()
+}
+
+// DefaultPartition is the well-known 6-6-3 split for the classic board, and consecutive tiles groups of at most five otherwise.
+func DefaultPartition(s board.Size) [][]byte {
+	if s == board.Classic {
+		return [][]byte{{1, 5, 6, 9, 10, 13}, {7, 8, 11, 12, 14, 15}, {2, 3, 4}}
+	}
+	groups := make([]int, 0)
+	for rest := s.Tiles() - 1; rest > 0; rest -= 5 {
+		groups = append(groups, min(rest, 5))
+	}
+	return consecutive(groups)
+}
+
+// ParsePartition reads either group sizes of consecutive tiles, e.g. "6-6-3",
+// or explicit tiles of every group, e.g. "1,5,6,9,10,13/7,8,11,12,14,15/2,3,4".
+func ParsePartition(s board.Size, v string) ([][]byte, error) {
+	var partition [][]byte
+	if strings.Contains(v, ",") || strings.Contains(v, "/") {
+		for _, group := range strings.Split(v, "/") {
+			tiles := make([]byte, 0)
+			for _, t := range strings.Split(group, ",") {
+				n, err := strconv.ParseUint(strings.TrimSpace(t), 10, 8)
+				if err != nil {
+					return nil, fmt.Errorf("parse partition %q: %s", v, err)
+				}
+				tiles = append(tiles, byte(n))
+			}
+			partition = append(partition, tiles)
+		}
+	} else {
+		groups := make([]int, 0)
+		for _, g := range strings.Split(v, "-") {
+			n, err := strconv.Atoi(strings.TrimSpace(g))
+			if err != nil {
+				return nil, fmt.Errorf("parse partition %q: %s", v, err)
+			}
+			if n <= 0 {
+				return nil, fmt.Errorf("parse partition %q: group size must be positive", v)
+			}
+			groups = append(groups, n)
+		}
+		partition = consecutive(groups)
+	}
+	if err := validatePartition(s, partition); err != nil {
+		return nil, fmt.Errorf("parse partition %q: %s", v, err)
+	}
+	return partition, nil
+}
+
+func consecutive(groups []int) [][]byte {
+	partition := make([][]byte, len(groups))
+	t := byte(1)
+	for i, n := range groups {
+		for range n {
+			partition[i] = append(partition[i], t)
+			t++
+		}
+	}
+	return partition
+}
+
+func validatePartition(s board.Size, partition [][]byte) error {
+	var seen [board.MaxTiles]bool
+	count := 0
+	for _, group := range partition {
+		if len(group) == 0 {
+			return fmt.Errorf("empty group")
+		}
+		for _, t := range group {
+			if t == 0 || int(t) >= s.Tiles() {
+				return fmt.Errorf("tile %d out of range for size %s", t, s)
+			}
+			if seen[t] {
+				return fmt.Errorf("tile %d in more than one group", t)
+			}
+			seen[t] = true
+			count++
+		}
+	}
+	if count != s.Tiles()-1 {
+		return fmt.Errorf("%d tiles of %d are covered", count, s.Tiles()-1)
+	}
+	return nil
+}
+
+// Build generates tables of the partition patterns with a breadth-first search from the solved position.
+func Build(ctx context.Context, s board.Size, partition [][]byte) (*Database, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validatePartition(s, partition); err != nil {
+		return nil, err
+	}
+	d := &Database{size: s}
+	for _, tiles := range partition {
+		p := newPattern(s, slices.Clone(tiles))
+		if err := p.build(ctx, s); err != nil {
+			return nil, err
+		}
+		d.patterns = append(d.patterns, p)
+	}
+	return d, nil
+}
+
+// build runs a 0-1 breadth-first search over the pattern tiles placements and the blank position.
+// The blank moves over cells of other tiles cost nothing, so a whole region reachable by the blank
+// is visited at once, and moves of the pattern tiles bordering the region make the next level.
+func (p *pattern) build(ctx context.Context, s board.Size) error {
+	n, k := s.Tiles(), len(p.tiles)
+	p.table = make([]byte, perms(n, k))
+	for i := range p.table {
+		p.table[i] = unknown
+	}
+	visited := newBitset(len(p.table) * n)
+	queued := newBitset(len(p.table) * n)
+
+	pos := make([]int, k)
+	for i, t := range p.tiles {
+		pos[i] = int(t) - 1
+	}
+	frontier := []uint64{uint64(p.rank(pos)*n + n - 1)}
+	queued.set(int(frontier[0]))
+
+	var occupied [board.MaxTiles]int
+	region := make([]int, 0, n)
+	var nbs [4]int
+	for depth, ops := 0, 0; len(frontier) > 0; depth++ {
+		if depth >= unknown {
+			return fmt.Errorf("pattern %v: depth overflow", p.tiles)
+		}
+		next := make([]uint64, 0, len(frontier))
+		for _, st := range frontier {
+			if visited.get(int(st)) {
+				continue
+			}
+			if ops++; ops%ctxCheckOp == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			r, blank := int(st)/n, int(st)%n
+			p.unrank(r, pos)
+			for i := range occupied[:n] {
+				occupied[i] = -1
+			}
+			for i := range pos {
+				occupied[pos[i]] = i
+			}
+			if p.table[r] == unknown {
+				p.table[r] = byte(depth)
+			}
+
+			region = append(region[:0], blank)
+			visited.set(r*n + blank)
+			for i := 0; i < len(region); i++ {
+				c := region[i]
+				for _, nb := range neighbours(s, c, &nbs) {
+					if tile := occupied[nb]; tile >= 0 {
+						pos[tile] = c
+						nst := p.rank(pos)*n + nb
+						pos[tile] = nb
+						if !visited.get(nst) && !queued.get(nst) {
+							queued.set(nst)
+							next = append(next, uint64(nst))
+						}
+					} else if !visited.get(r*n + nb) {
+						visited.set(r*n + nb)
+						region = append(region, nb)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+func neighbours(s board.Size, c int, buf *[4]int) []int {
+	result := buf[:0]
+	col, row := s.Col(c), s.Row(c)
+	if row > 0 {
+		result = append(result, c-s.W)
+	}
+	if row < s.H-1 {
+		result = append(result, c+s.W)
+	}
+	if col > 0 {
+		result = append(result, c-1)
+	}
+	if col < s.W-1 {
+		result = append(result, c+1)
+	}
+	return result
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) get(i int) bool {
+	return b[i/64]&(1<<(i%64)) != 0
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (i % 64)
+}
@@ -0,0 +1,171 @@
+package pdb_test
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/solver"
+	"15-puzzle/internal/solver/pdb"
+	"bytes"
+	"context"
+	"math/rand/v2"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var small = board.Size{W: 3, H: 3}
+
+func TestBuild(t *testing.T) {
+	partition, err := pdb.ParsePartition(small, "4-4")
+	assert.NoError(t, err)
+	db, err := pdb.Build(context.Background(), small, partition)
+	assert.NoError(t, err)
+	assert.Equal(t, small, db.Size())
+	assert.Equal(t, [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}}, db.Partition())
+
+	distance := bfs(small)
+	assert.Len(t, distance, 181440)
+	for b, d := range distance {
+		h := db.Estimate(b)
+		if h > d {
+			t.Fatalf("estimate %d of %s exceeds distance %d", h, b, d)
+		}
+		if m := (solver.Manhattan{}).Estimate(b); h < m {
+			t.Fatalf("estimate %d of %s is below manhattan distance %d", h, b, m)
+		}
+	}
+
+	for range 20 {
+		b := board.Shuffled(small)
+		res, err := solver.Solve(context.Background(), b, solver.WithHeuristic(solver.Max{db, solver.LinearConflict{}}))
+		assert.NoError(t, err)
+		assert.Equal(t, distance[b], len(res.Moves))
+	}
+}
+
+func TestBuildClassic(t *testing.T) {
+	partition, err := pdb.ParsePartition(board.Classic, "1,2,3/4,5,6/7,8,9/10,11,12/13,14,15")
+	assert.NoError(t, err)
+	db, err := pdb.Build(context.Background(), board.Classic, partition)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(7, 8))
+	for range 5 {
+		b := board.Solved(board.Classic)
+		for range 40 {
+			moves := b.Moves()
+			_ = b.Move(moves[r.IntN(len(moves))])
+		}
+		expected, err := solver.Solve(context.Background(), b)
+		assert.NoError(t, err)
+		actual, err := solver.Solve(context.Background(), b, solver.WithHeuristic(db))
+		assert.NoError(t, err)
+		assert.Equal(t, len(expected.Moves), len(actual.Moves), b.String())
+		assert.GreaterOrEqual(t, db.Estimate(b), solver.Manhattan{}.Estimate(b))
+	}
+}
+
+func TestParsePartition(t *testing.T) {
+	p, err := pdb.ParsePartition(board.Classic, "6-6-3")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{1, 2, 3, 4, 5, 6}, {7, 8, 9, 10, 11, 12}, {13, 14, 15}}, p)
+	p, err = pdb.ParsePartition(board.Classic, "1,5,6,9,10,13/7,8,11,12,14,15/2,3,4")
+	assert.NoError(t, err)
+	assert.Equal(t, pdb.DefaultPartition(board.Classic), p)
+
+	for _, v := range []string{"6-6-2", "6-6-4", "6-0-9", "a-b", "1,2/2,3,4,5,6,7,8", "0,1,2,3/4,5,6,7,8", "1,2,3,4/5,6,7,9"} {
+		_, err := pdb.ParsePartition(small, v)
+		assert.Error(t, err, v)
+	}
+	assert.Equal(t, [][]byte{{1, 2, 3, 4, 5}, {6, 7, 8, 9, 10}, {11, 12, 13, 14, 15}, {16, 17, 18, 19}}, pdb.DefaultPartition(board.Size{W: 5, H: 4}))
+}
+
+func TestFormat(t *testing.T) {
+	db, err := pdb.Build(context.Background(), small, pdb.DefaultPartition(small))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := db.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	data := buf.Bytes()
+
+	read, err := pdb.Read(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, db.Partition(), read.Partition())
+
+	file := path.Join(t.TempDir(), "3x3.pdb")
+	assert.NoError(t, db.Save(file))
+	loaded, err := pdb.Load(file)
+	assert.NoError(t, err)
+	for range 100 {
+		b := board.Shuffled(small)
+		assert.Equal(t, db.Estimate(b), read.Estimate(b))
+		assert.Equal(t, db.Estimate(b), loaded.Estimate(b))
+	}
+	assert.NoError(t, loaded.Close())
+
+	corrupted := bytes.Clone(data)
+	corrupted[len(corrupted)/2] ^= 0x01
+	_, err = pdb.Read(bytes.NewReader(corrupted))
+	assert.ErrorIs(t, err, pdb.ErrCorrupted)
+	assert.NoError(t, os.WriteFile(file, corrupted, 0o600))
+	_, err = pdb.Load(file)
+	assert.ErrorIs(t, err, pdb.ErrCorrupted)
+
+	_, err = pdb.Read(bytes.NewReader(data[:len(data)-1]))
+	assert.ErrorIs(t, err, pdb.ErrCorrupted)
+
+	versioned := bytes.Clone(data)
+	versioned[5] = pdb.Version + 1
+	_, err = pdb.Read(bytes.NewReader(versioned))
+	assert.ErrorIs(t, err, pdb.ErrVersion)
+
+	_, err = pdb.Read(bytes.NewReader([]byte("not a database")))
+	assert.ErrorIs(t, err, pdb.ErrCorrupted)
+}
+
+func bfs(s board.Size) map[board.Board]int {
+	distance := map[board.Board]int{board.Solved(s): 0}
+	queue := []board.Board{board.Solved(s)}
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		for _, d := range b.Moves() {
+			next := b
+			_ = next.Move(d)
+			if _, ok := distance[next]; !ok {
+				distance[next] = distance[b] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return distance
+}
+
+func TestLoadHeuristic(t *testing.T) {
+	db, err := pdb.Build(context.Background(), small, pdb.DefaultPartition(small))
+	assert.NoError(t, err)
+	file := path.Join(t.TempDir(), "3x3.pdb")
+	assert.NoError(t, db.Save(file))
+
+	h, closeAll, err := pdb.LoadHeuristic(file)
+	assert.NoError(t, err)
+	for range 100 {
+		b := board.Shuffled(small)
+		assert.Equal(t, max(db.Estimate(b), solver.LinearConflict{}.Estimate(b)), h.Estimate(b))
+	}
+	b := board.Shuffled(board.Classic)
+	assert.Equal(t, solver.LinearConflict{}.Estimate(b), h.Estimate(b), "board of other size")
+	assert.NoError(t, closeAll())
+
+	_, _, err = pdb.LoadHeuristic(file + "," + file)
+	assert.Error(t, err, "same size")
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	data[len(data)/2] ^= 0x01
+	assert.NoError(t, os.WriteFile(file, data, 0o600))
+	_, _, err = pdb.LoadHeuristic(file)
+	assert.ErrorIs(t, err, pdb.ErrCorrupted)
+}
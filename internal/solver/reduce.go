@@ -0,0 +1,185 @@
+package solver
+
+import (
+	"15-puzzle/internal/board"
+	"context"
+	"fmt"
+	"math"
+)
+
+// reduceRegion is the size of the last region solved at once.
+const reduceRegion = 3
+
+// Reduce finds a solution, which is not the shortest one, for a board of any size quickly.
+// It places tiles of the top row or the left column of the unsolved region one by one
+// and freezes the line then, until a small region is left, which is solved at once.
+func Reduce(ctx context.Context, b board.Board, opts ...Option) (Result, error) {
+	o := &options{}
+	for i := range opts {
+		opts[i](o)
+	}
+	if !b.IsSolvable() {
+		return Result{}, ErrUnsolvable
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	s := b.Size()
+	g := &subgoal{search: search{ctx: ctx, opts: o, board: b}}
+	top, left := 0, 0
+	for s.H-top > reduceRegion || s.W-left > reduceRegion {
+		var line []int
+		rows := s.H-top >= s.W-left
+		if rows {
+			for col := left; col < s.W; col++ {
+				line = append(line, s.Pos(col, top))
+			}
+			top++
+		} else {
+			for row := top; row < s.H; row++ {
+				line = append(line, s.Pos(left, row))
+			}
+			left++
+		}
+		for _, goal := range line {
+			if err := g.place(byte(goal+1), rows); err != nil {
+				return Result{Nodes: g.nodes}, err
+			}
+		}
+		for _, pos := range line {
+			g.frozen[pos] = true
+		}
+	}
+	for row := top; row < s.H; row++ {
+		for col := left; col < s.W; col++ {
+			if pos := s.Pos(col, row); pos < s.Tiles()-1 {
+				g.tiles = append(g.tiles, byte(pos+1))
+			}
+		}
+	}
+	if err := g.solve(); err != nil {
+		return Result{Nodes: g.nodes}, err
+	}
+	if !g.board.IsSolved() {
+		return Result{Nodes: g.nodes}, fmt.Errorf("reduction left unsolved position %s", g.board)
+	}
+	return Result{Moves: g.path, Nodes: g.nodes, Bound: LinearConflict{}.Estimate(b)}, nil
+}
+
+// subgoal is a search for the shortest sequence placing the tiles at their goal positions
+// and the moving tile at the target, the blank never enters the frozen cells.
+type subgoal struct {
+	search
+	tiles  []byte
+	frozen [board.MaxTiles]bool
+	moving byte
+	target int
+}
+
+// place brings the tile to its goal position a cell at a time keeping the already placed tiles.
+// A tile of a row moves horizontally first, a tile of a column moves vertically first,
+// so it never passes through the placed tiles of the same line.
+func (g *subgoal) place(tile byte, horizontal bool) error {
+	s := g.board.Size()
+	goal := int(tile) - 1
+	defer func() { g.moving = 0 }()
+	for pos := g.board.Pos(tile); pos != goal; pos = g.board.Pos(tile) {
+		col, row := s.Col(pos), s.Row(pos)
+		dc, dr := sign(s.Col(goal)-col), sign(s.Row(goal)-row)
+		if dc != 0 && dr != 0 {
+			if horizontal {
+				dr = 0
+			} else {
+				dc = 0
+			}
+		}
+		g.moving, g.target = tile, s.Pos(col+dc, row+dr)
+		if err := g.solve(); err != nil {
+			return err
+		}
+	}
+	g.tiles = append(g.tiles, tile)
+	return nil
+}
+
+func (g *subgoal) solve() error {
+	bound := g.estimate()
+	for {
+		next, found, err := g.dfs(0, bound, 0, false)
+		switch {
+		case err != nil:
+			return err
+		case found:
+			return nil
+		case next == math.MaxInt:
+			return ErrUnsolvable
+		}
+		bound = next
+	}
+}
+
+func (g *subgoal) estimate() int {
+	s := g.board.Size()
+	d := 0
+	for _, t := range g.tiles {
+		pos, goal := g.board.Pos(t), int(t)-1
+		d += abs(s.Col(pos)-s.Col(goal)) + abs(s.Row(pos)-s.Row(goal))
+	}
+	if g.moving == 0 {
+		return d
+	}
+	// the blank has to come next to the moving tile first
+	pos, blank := g.board.Pos(g.moving), g.board.Blank()
+	if pos == g.target {
+		return d
+	}
+	return d + abs(s.Col(pos)-s.Col(g.target)) + abs(s.Row(pos)-s.Row(g.target)) +
+		abs(s.Col(pos)-s.Col(blank)) + abs(s.Row(pos)-s.Row(blank)) - 1
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func (g *subgoal) dfs(depth, bound int, prev board.Direction, hasPrev bool) (int, bool, error) {
+	if err := g.expand(); err != nil {
+		return 0, false, err
+	}
+
+	h := g.estimate()
+	if f := depth + h; f > bound {
+		return f, false, nil
+	}
+	if h == 0 {
+		return 0, true, nil
+	}
+
+	next := math.MaxInt
+	for _, d := range board.Directions {
+		if hasPrev && d == prev.Opposite() {
+			continue
+		}
+		if pos, ok := g.board.Source(d); !ok || g.frozen[pos] {
+			continue
+		}
+		_ = g.board.Move(d)
+		g.path = append(g.path, d)
+		f, found, err := g.dfs(depth+1, bound, d, true)
+		if err != nil || found {
+			return 0, found, err
+		}
+		g.path = g.path[:len(g.path)-1]
+		_ = g.board.Move(d.Opposite())
+		next = min(next, f)
+	}
+	return next, false, nil
+}
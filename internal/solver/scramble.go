@@ -0,0 +1,58 @@
+package solver
+
+import (
+	"15-puzzle/internal/board"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+const (
+	scrambleNodeLimit = 100000
+	scrambleAttempts  = 100
+)
+
+// Scramble returns a scramble of the tier with its optimal solution length searched with the options.
+// Tier scrambles are random walks, the walk gets longer after a scramble short of the tier range and shorter after one beyond it,
+// until the optimal length of one falls in the range.
+// The optimal length of an expert scramble is zero unless the search proves it within the node limit.
+func Scramble(ctx context.Context, s board.Size, t board.Tier, opts ...Option) (board.Scramble, int, error) {
+	if t == board.Expert {
+		sc := board.NewScramble(s)
+		n, err := Optimal(ctx, sc.Board(), opts...)
+		return sc, n, err
+	}
+	lo, hi := t.Range()
+	walk := hi
+	for range scrambleAttempts {
+		sc := board.Scramble{Size: s, Seed: rand.Uint64(), Walk: walk}
+		n, err := Optimal(ctx, sc.Board(), opts...)
+		switch {
+		case err != nil:
+			return board.Scramble{}, 0, err
+		case n >= lo && n <= hi:
+			return sc, n, nil
+		case n > 0 && n < lo:
+			walk++
+		default:
+			// beyond the range or the node limit
+			walk = max(walk-1, lo)
+		}
+	}
+	return board.Scramble{}, 0, fmt.Errorf("no %s scramble of %s in %d attempts", t, s, scrambleAttempts)
+}
+
+// Optimal returns the optimal solution length of the position when the search proves it within the node limit of scrambles,
+// zero otherwise. Tier scrambles are always proven, so the length is the same for the client and the server,
+// a stronger heuristic of the options proves more expert scrambles.
+func Optimal(ctx context.Context, b board.Board, opts ...Option) (int, error) {
+	res, err := Solve(ctx, b, append(opts, WithNodeLimit(scrambleNodeLimit))...)
+	switch {
+	case errors.Is(err, ErrNodeLimit):
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	return len(res.Moves), nil
+}
@@ -0,0 +1,173 @@
+package solver
+
+import (
+	"15-puzzle/internal/board"
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"time"
+)
+
+const ctxCheckNodes = 1 << 12
+
+var (
+	ErrUnsolvable = errors.New("position is not solvable")
+	ErrNodeLimit  = errors.New("node limit exceeded")
+)
+
+type options struct {
+	heuristic Heuristic
+	weight    float64
+	maxNodes  int64
+	timeout   time.Duration
+}
+
+type Option func(*options)
+
+// WithHeuristic replaces the default LinearConflict heuristic.
+func WithHeuristic(h Heuristic) Option {
+	return func(o *options) { o.heuristic = h }
+}
+
+// WithWeight multiplies the heuristic estimate by w > 1 trading the solution optimality for speed,
+// a found solution is at most w times longer than the optimal one.
+func WithWeight(w float64) Option {
+	return func(o *options) { o.weight = w }
+}
+
+// WithNodeLimit stops the search with ErrNodeLimit after expanding n positions.
+func WithNodeLimit(n int64) Option {
+	return func(o *options) { o.maxNodes = n }
+}
+
+// WithTimeout stops the search with context.DeadlineExceeded after d.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// Result of a search.
+// Bound is the proven lower bound of the solution length, which is exactly the length of Moves for a solved position.
+// When the search was interrupted Bound is still valid while Moves are empty.
+type Result struct {
+	Moves []board.Direction
+	Nodes int64
+	Bound int
+}
+
+// Solve finds the shortest sequence of moves bringing the position to the solved one with IDA* search.
+func Solve(ctx context.Context, b board.Board, opts ...Option) (Result, error) {
+	o := &options{heuristic: LinearConflict{}, weight: 1}
+	for i := range opts {
+		opts[i](o)
+	}
+	if !b.IsSolvable() {
+		return Result{}, ErrUnsolvable
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	s := &search{ctx: ctx, opts: o, board: b}
+	bound := s.estimate()
+	for {
+		next, found, err := s.dfs(0, bound, 0, false)
+		switch {
+		case err != nil && o.weight > 1:
+			return Result{Nodes: s.nodes, Bound: o.heuristic.Estimate(b)}, err
+		case err != nil:
+			return Result{Nodes: s.nodes, Bound: bound}, err
+		case found && o.weight > 1:
+			return Result{Moves: s.path, Nodes: s.nodes, Bound: o.heuristic.Estimate(b)}, nil
+		case found:
+			return Result{Moves: s.path, Nodes: s.nodes, Bound: len(s.path)}, nil
+		case next == math.MaxInt:
+			return Result{Nodes: s.nodes, Bound: bound}, ErrUnsolvable
+		}
+		bound = next
+	}
+}
+
+// NextMove returns the first move of the shortest solution when it is found within the node limit,
+// the first move of a weighted search solution or of the Reduce solution otherwise.
+func NextMove(ctx context.Context, b board.Board, limit int64, opts ...Option) (board.Direction, error) {
+	res, err := Solve(ctx, b, append(opts, WithNodeLimit(limit))...)
+	if errors.Is(err, ErrNodeLimit) {
+		res, err = Solve(ctx, b, append(opts, WithNodeLimit(limit), WithWeight(2))...)
+	}
+	if errors.Is(err, ErrNodeLimit) {
+		res, err = Reduce(ctx, b)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(res.Moves) == 0 {
+		return 0, errors.New("position is already solved")
+	}
+	return res.Moves[0], nil
+}
+
+type search struct {
+	ctx   context.Context
+	opts  *options
+	board board.Board
+	path  []board.Direction
+	nodes int64
+}
+
+// dfs returns the smallest estimate exceeding the bound, or true when the solution is found at s.path.
+func (s *search) dfs(depth, bound int, prev board.Direction, hasPrev bool) (int, bool, error) {
+	if err := s.expand(); err != nil {
+		return 0, false, err
+	}
+
+	h := s.estimate()
+	if f := depth + h; f > bound {
+		return f, false, nil
+	}
+	if h == 0 && s.board.IsSolved() {
+		return 0, true, nil
+	}
+
+	next := math.MaxInt
+	for _, d := range board.Directions {
+		if hasPrev && d == prev.Opposite() {
+			continue
+		}
+		if err := s.board.Move(d); err != nil {
+			continue
+		}
+		s.path = append(s.path, d)
+		f, found, err := s.dfs(depth+1, bound, d, true)
+		if err != nil || found {
+			return 0, found, err
+		}
+		s.path = s.path[:len(s.path)-1]
+		_ = s.board.Move(d.Opposite())
+		next = min(next, f)
+	}
+	return next, false, nil
+}
+
+// expand counts the expanded node and tells whether the search must stop.
+func (s *search) expand() error {
+	s.nodes++
+	if s.opts.maxNodes > 0 && s.nodes > s.opts.maxNodes {
+		return ErrNodeLimit
+	}
+	if s.nodes%ctxCheckNodes == 0 {
+		// let other goroutines run on a single threaded platform like wasm
+		runtime.Gosched()
+		return s.ctx.Err()
+	}
+	return nil
+}
+
+func (s *search) estimate() int {
+	if s.opts.weight > 1 {
+		return int(float64(s.opts.heuristic.Estimate(s.board)) * s.opts.weight)
+	}
+	return s.opts.heuristic.Estimate(s.board)
+}
@@ -0,0 +1,192 @@
+package solver_test
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/solver"
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolveOptimal(t *testing.T) {
+	s := board.Size{W: 3, H: 3}
+	distance := bfs(s)
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 30 {
+		b := randomWalk(r, board.Solved(s), 40)
+		for _, h := range []solver.Heuristic{solver.Manhattan{}, solver.LinearConflict{}} {
+			res, err := solver.Solve(context.Background(), b, solver.WithHeuristic(h))
+			assert.NoError(t, err)
+			assert.Equal(t, distance[b], len(res.Moves), "%T: %s", h, b)
+			assert.Equal(t, len(res.Moves), res.Bound)
+			assertSolves(t, b, res.Moves)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	for _, s := range []board.Size{board.Classic, {W: 3, H: 5}, {W: 5, H: 3}} {
+		for range 5 {
+			b := randomWalk(r, board.Solved(s), 30)
+			manhattan, err := solver.Solve(context.Background(), b, solver.WithHeuristic(solver.Manhattan{}))
+			assert.NoError(t, err)
+			conflict, err := solver.Solve(context.Background(), b)
+			assert.NoError(t, err)
+			assert.Equal(t, len(manhattan.Moves), len(conflict.Moves), b.String())
+			assert.LessOrEqual(t, conflict.Nodes, manhattan.Nodes, b.String())
+			assert.LessOrEqual(t, len(conflict.Moves), 30)
+			assertSolves(t, b, conflict.Moves)
+		}
+	}
+
+	res, err := solver.Solve(context.Background(), board.Solved(board.Classic))
+	assert.NoError(t, err)
+	assert.Empty(t, res.Moves)
+
+	b, _ := board.Parse("1,2,3,4/5,6,7,8/9,10,11,12/13,14,0,15")
+	res, err = solver.Solve(context.Background(), b)
+	assert.NoError(t, err)
+	assert.Equal(t, []board.Direction{board.Left}, res.Moves)
+}
+
+func TestSolveErrors(t *testing.T) {
+	b, _ := board.Parse("1,2,3,4/5,6,7,8/9,10,11,12/13,15,14,0")
+	_, err := solver.Solve(context.Background(), b)
+	assert.ErrorIs(t, err, solver.ErrUnsolvable)
+
+	b, _ = board.Parse("13,14,15,7/11,12,9,5/6,0,2,1/4,8,10,3")
+	res, err := solver.Solve(context.Background(), b, solver.WithNodeLimit(10000))
+	assert.ErrorIs(t, err, solver.ErrNodeLimit)
+	assert.Empty(t, res.Moves)
+	assert.GreaterOrEqual(t, res.Bound, solver.LinearConflict{}.Estimate(b))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = solver.Solve(ctx, b)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = solver.Solve(context.Background(), b, solver.WithTimeout(1))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLinearConflict(t *testing.T) {
+	table := []struct {
+		board    string
+		expected int
+	}{
+		{"1,2,3/4,5,6/7,8,0", 0},
+		{"2,1,3/4,5,6/7,8,0", 4},
+		{"3,2,1/4,5,6/7,8,0", 4 + 4},
+		{"3,1,2/4,5,6/7,8,0", 4 + 2},
+		{"1,2,3/4,5,6/7,0,8", 1},
+		{"7,2,3/4,5,6/1,8,0", 4 + 4},
+	}
+	for i := range table {
+		b, err := board.Parse(table[i].board)
+		assert.NoError(t, err)
+		assert.Equal(t, table[i].expected, solver.LinearConflict{}.Estimate(b), table[i].board)
+	}
+}
+
+func bfs(s board.Size) map[board.Board]int {
+	distance := map[board.Board]int{board.Solved(s): 0}
+	queue := []board.Board{board.Solved(s)}
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		for _, d := range b.Moves() {
+			next := b
+			_ = next.Move(d)
+			if _, ok := distance[next]; !ok {
+				distance[next] = distance[b] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return distance
+}
+
+func randomWalk(r *rand.Rand, b board.Board, n int) board.Board {
+	for range n {
+		moves := b.Moves()
+		_ = b.Move(moves[r.IntN(len(moves))])
+	}
+	return b
+}
+
+func assertSolves(t *testing.T, b board.Board, moves []board.Direction) {
+	for _, d := range moves {
+		assert.NoError(t, b.Move(d))
+	}
+	assert.True(t, b.IsSolved(), b.String())
+}
+
+func TestWeighted(t *testing.T) {
+	r := rand.New(rand.NewPCG(9, 10))
+	for range 5 {
+		b := randomWalk(r, board.Solved(board.Classic), 60)
+		optimal, err := solver.Solve(context.Background(), b)
+		assert.NoError(t, err)
+		weighted, err := solver.Solve(context.Background(), b, solver.WithWeight(2))
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, len(weighted.Moves), len(optimal.Moves))
+		assert.LessOrEqual(t, len(weighted.Moves), 2*len(optimal.Moves))
+		assert.LessOrEqual(t, weighted.Bound, len(optimal.Moves))
+		assertSolves(t, b, weighted.Moves)
+	}
+}
+
+func TestNextMove(t *testing.T) {
+	b, _ := board.Parse("1,2,3,4/5,6,7,8/9,10,0,11/13,14,15,12")
+	d, err := solver.NextMove(context.Background(), b, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, board.Left, d)
+
+	_, err = solver.NextMove(context.Background(), board.Solved(board.Classic), 1000)
+	assert.Error(t, err)
+
+	b = randomWalk(rand.New(rand.NewPCG(11, 12)), board.Solved(board.Size{W: 6, H: 6}), 200)
+	d, err = solver.NextMove(context.Background(), b, 100000)
+	assert.NoError(t, err)
+	assert.Contains(t, b.Moves(), d)
+}
+
+func TestReduce(t *testing.T) {
+	for _, s := range []board.Size{{W: 3, H: 3}, {W: 4, H: 4}, {W: 5, H: 3}, {W: 3, H: 7}, {W: 6, H: 6}, {W: 8, H: 8}} {
+		for range 3 {
+			b := board.Shuffled(s)
+			res, err := solver.Reduce(context.Background(), b, solver.WithTimeout(10*time.Second))
+			assert.NoError(t, err, b.String())
+			assertSolves(t, b, res.Moves)
+		}
+	}
+}
+
+func TestScramble(t *testing.T) {
+	for _, s := range []board.Size{{W: 3, H: 3}, board.Classic, {W: 8, H: 3}} {
+		for _, tier := range board.Tiers {
+			sc, optimal, err := solver.Scramble(context.Background(), s, tier)
+			if !assert.NoError(t, err, "%s %s", s, tier) {
+				continue
+			}
+			assert.Equal(t, s, sc.Size)
+			lo, hi := tier.Range()
+			if tier == board.Expert {
+				assert.Zero(t, sc.Walk)
+				if s == (board.Size{W: 3, H: 3}) {
+					assert.Positive(t, optimal, "3x3 optimal solution should be found within the node limit")
+				}
+				continue
+			}
+			assert.GreaterOrEqual(t, optimal, lo)
+			assert.LessOrEqual(t, optimal, hi)
+			res, err := solver.Solve(context.Background(), sc.Board())
+			assert.NoError(t, err)
+			assert.Len(t, res.Moves, optimal)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignSession returns the session token of the user's game of the scramble valid until the expiry time, the token is the game id,
+// the scramble, the expiry Unix time and the signature of them with the user id, separated by dots.
+func SignSession(key []byte, gameID, userID int, scramble string, expires time.Time) string {
+	payload := strconv.Itoa(gameID) + "." + scramble + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + hex.EncodeToString(EncodeHmacSha256([]byte(payload+"."+strconv.Itoa(userID)), key))
+}
+
+// ValidSession returns the game id and the scramble of the session token signed for the user, the token is valid until it expires.
+func ValidSession(key []byte, token string, userID int, now time.Time) (int, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return 0, "", fmt.Errorf("parse session %q: expected game.scramble.expiry.signature", token)
+	}
+	sig, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return 0, "", fmt.Errorf("decode session signature %q: %s", parts[3], err)
+	}
+	if !hmac.Equal(EncodeHmacSha256([]byte(strings.Join(parts[:3], ".")+"."+strconv.Itoa(userID)), key), sig) {
+		return 0, "", fmt.Errorf("session %q: invalid signature", token)
+	}
+	gameID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("parse session game %q: %s", parts[0], err)
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("parse session expiry %q: %s", parts[2], err)
+	}
+	if now.After(time.Unix(expires, 0)) {
+		return 0, "", fmt.Errorf("session %q: expired at %s", token, time.Unix(expires, 0).UTC())
+	}
+	return gameID, parts[1], nil
+}
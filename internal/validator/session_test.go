@@ -0,0 +1,34 @@
+package validator_test
+
+import (
+	"15-puzzle/internal/validator"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	key := validator.EncodeHmacSha256([]byte(botToken), []byte("GameSession"))
+	now := time.Unix(1700000000, 0)
+	token := validator.SignSession(key, 42, userId, "4x4:1", now.Add(time.Hour))
+
+	gameID, scramble, err := validator.ValidSession(key, token, userId, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, gameID)
+	assert.Equal(t, "4x4:1", scramble)
+
+	_, _, err = validator.ValidSession(key, token, userId+1, now)
+	assert.Error(t, err, "session of other user")
+	_, _, err = validator.ValidSession(validator.EncodeHmacSha256([]byte(botToken), []byte("WebAppData")), token, userId, now)
+	assert.Error(t, err, "session of other key")
+	_, _, err = validator.ValidSession(key, token, userId, now.Add(time.Hour+time.Second))
+	assert.Error(t, err, "expired session")
+	_, _, err = validator.ValidSession(key, "43"+token[2:], userId, now)
+	assert.Error(t, err, "session of other game")
+	_, _, err = validator.ValidSession(key, strings.Replace(token, "4x4:1", "4x4:2", 1), userId, now)
+	assert.Error(t, err, "session of other scramble")
+	_, _, err = validator.ValidSession(key, "", userId, now)
+	assert.Error(t, err)
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"15-puzzle/internal/board"
 	"15-puzzle/internal/model"
+	"15-puzzle/internal/solver"
 	"15-puzzle/internal/validator"
+	"15-puzzle/internal/web-service/plausibility"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -12,6 +16,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type ctxUserID string
@@ -20,18 +25,38 @@ const (
 	WebAppInitDataHeader            = "Web-App-Init-Data"
 	WebAppExtraCodeHeader           = "Web-App-Extra-Code"
 	WebAppHtmlFile                  = "tgwebapp.html"
+	maxSolveBodySize                = 1 << 20
 	ctxDataUserID         ctxUserID = "user_id"
+	// ReplayTopRanks are the ranks of a leaderboard whose best games every player plays back
+	ReplayTopRanks = 10
 )
 
+// Repository keeps the users and their games. The errors of a missing user or game wrap model.ErrUserNotFound
+// and the other errors of the model, any other error is a failure of the storage or the context.
 type Repository interface {
-	RegisterGameStart(UserID int) (model.User, error)
-	RegisterGameSolve(UserID, moves int) (model.User, error)
-	Stats(UserID int) (model.User, error)
-	Monitoring() (model.Monitoring, error)
-	Rating() []int
+	RegisterGameStart(ctx context.Context, UserID int, lb model.Leaderboard, scramble string) (model.User, model.Game, error)
+	Game(ctx context.Context, UserID, gameID int) (model.Game, error)
+	Games(ctx context.Context, UserID int) ([]model.Game, error)
+	RegisterGameSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int, solve model.Solve) (model.User, error)
+	RegisterQuarantinedSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int, solve model.Solve, suspicion model.Suspicion) (model.User, error)
+	RegisterRejectedSolve(ctx context.Context, UserID int, lb model.Leaderboard, gameID int) error
+	Stats(ctx context.Context, UserID int, lb model.Leaderboard) (model.User, error)
+	Monitoring(ctx context.Context) (model.Monitoring, error)
+	Ranks(ctx context.Context, UserID int, lb model.Leaderboard) ([]model.Rank, error)
+	Top(ctx context.Context, lb model.Leaderboard, limit int) ([]model.Rating, error)
+	DailyStats(ctx context.Context, UserID int, day string, lb model.Leaderboard) (model.User, error)
+	RegisterDailySolve(ctx context.Context, UserID int, day string, lb model.Leaderboard, solve model.Solve) (model.User, error)
+	DailyRanks(ctx context.Context, UserID int, day string, lb model.Leaderboard) ([]model.Rank, error)
+	WindowStats(ctx context.Context, UserID int, lb model.Leaderboard, since time.Time) (model.User, error)
+	WindowRanks(ctx context.Context, UserID int, lb model.Leaderboard, since time.Time) ([]model.Rank, error)
+	Quarantine(ctx context.Context) ([]model.Game, error)
+	ReviewGame(ctx context.Context, gameID int, approve bool) (model.Game, error)
 }
 
-func NewHandler(repo Repository, token, code, ctxRoot, staticDir, projectLink string) http.Handler {
+// NewHandler serves the web app and its API, the day, week and month ratings roll over at midnight in the location.
+// Solves out of the limits are quarantined until reviewed through the admin API.
+// The optimal solution length of the solved scrambles is searched with the solver options, e.g. of a pattern database heuristic.
+func NewHandler(repo Repository, token, code, ctxRoot, staticDir, projectLink string, loc *time.Location, limits plausibility.Limits, opts ...solver.Option) http.Handler {
 	mux := http.NewServeMux()
 
 	if abs, err := filepath.Abs(staticDir); err == nil {
@@ -43,10 +68,17 @@ func NewHandler(repo Repository, token, code, ctxRoot, staticDir, projectLink st
 
 	apiMux := http.NewServeMux()
 	apiMux.Handle(http.MethodGet+" /info", apiInfoHandler(model.Info{ProjectLink: projectLink}))
-	apiMux.Handle(http.MethodPut+" /start", apiStartHandler(repo))
-	apiMux.Handle(http.MethodPut+" /solve", apiSolveHandler(repo))
+	sessionKey := validator.EncodeHmacSha256([]byte(token), []byte("GameSession"))
+	apiMux.Handle(http.MethodPut+" /start", apiStartHandler(repo, sessionKey, opts))
+	apiMux.Handle(http.MethodPut+" /solve", apiSolveHandler(repo, sessionKey, limits, opts))
 	apiMux.Handle(http.MethodGet+" /stats", apiStatsHandler(repo))
-	apiMux.Handle(http.MethodGet+" /monitoring", apiMonitoringHandler(repo, code))
+	apiMux.Handle(http.MethodGet+" /games", apiGamesHandler(repo))
+	apiMux.Handle(http.MethodGet+" /daily", apiDailyHandler(repo))
+	apiMux.Handle(http.MethodGet+" /rating", apiRatingHandler(repo, loc))
+	apiMux.Handle(http.MethodGet+" /replay", apiReplayHandler(repo, code))
+	apiMux.Handle(http.MethodGet+" /monitoring", adminHandler(code, apiMonitoringHandler(repo)))
+	apiMux.Handle(http.MethodGet+" /admin/quarantine", adminHandler(code, apiQuarantineHandler(repo)))
+	apiMux.Handle(http.MethodPut+" /admin/games/{id}/{verdict}", adminHandler(code, apiReviewHandler(repo)))
 	apiKey := validator.EncodeHmacSha256([]byte(token), []byte("WebAppData"))
 	mux.Handle("/api/", authHandler(apiKey, http.StripPrefix("/api", apiMux)))
 
@@ -87,36 +119,283 @@ func apiInfoHandler(i model.Info) http.Handler {
 	})
 }
 
-func apiStartHandler(repo Repository) http.Handler {
+// apiStartHandler keeps the game of the scramble chosen by the server, or of the challenge of the day by the daily query,
+// and responds with the game and its session. The session is signed for the user with the scramble and expires when the game is abandoned.
+// The game of a tier is responded with the optimal solution length of the scramble searched with the solver options.
+func apiStartHandler(repo Repository, sessionKey []byte, opts []solver.Option) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		respond(w, r, repo.Rating, repo.RegisterGameStart)
+		lb, err := queryLeaderboard(r)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid value: %s", err))
+			return
+		}
+		daily := false
+		if v := r.URL.Query().Get("daily"); v != "" {
+			if daily, err = strconv.ParseBool(v); err != nil {
+				errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid daily: %q", v))
+				return
+			}
+		}
+		var sc board.Scramble
+		optimal := 0
+		switch {
+		case daily && lb.Tier != board.Expert:
+			errorResponse(w, http.StatusBadRequest, fmt.Errorf("daily challenge of %s tier", lb.Tier))
+			return
+		case daily:
+			sc = board.Daily(lb.Size, time.Now())
+		case lb.Tier == board.Expert:
+			// the optimal length of a random position is rarely proven, it is searched by the verification of the solve
+			sc = board.NewScramble(lb.Size)
+		default:
+			if sc, optimal, err = solver.Scramble(r.Context(), lb.Size, lb.Tier, opts...); err != nil {
+				errorResponse(w, http.StatusInternalServerError, fmt.Errorf("%s scramble: %s", lb, err))
+				return
+			}
+		}
+		var game model.Game
+		respond(w, r, repo.Ranks,
+			func(ctx context.Context, u int, lb model.Leaderboard) (user model.User, err error) {
+				user, game, err = repo.RegisterGameStart(ctx, u, lb, sc.String())
+				return user, err
+			},
+			func(resp *model.ApiResponse, lb model.Leaderboard) {
+				session := validator.SignSession(sessionKey, game.ID, game.UserID, game.Scramble, time.Time(*game.Start).Add(model.AbandonAfter))
+				game.Optimal = optimal
+				resp.Game, resp.Session = &game, &session
+			})
 	})
 }
 
-func apiSolveHandler(repo Repository) http.Handler {
+// apiSolveHandler registers the solve of the started game of the session after the game is replayed on the server,
+// rejected solves are logged and counted. The game is solved once, its duration is bound by the time since its start.
+// An implausible solve is not counted, the game is quarantined.
+func apiSolveHandler(repo Repository, sessionKey []byte, limits plausibility.Limits, opts []solver.Option) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		v := r.URL.Query().Get("moves")
-		if moves, err := strconv.Atoi(r.URL.Query().Get("moves")); err != nil {
-			errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid value: %q", v))
-		} else {
-			respond(w, r, repo.Rating, func(u int) (model.User, error) { return repo.RegisterGameSolve(u, moves) })
+		lb, err := queryLeaderboard(r)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid value: %s", err))
+			return
+		}
+		var solve model.Solve
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSolveBodySize)).Decode(&solve); err != nil {
+			rejectSolve(w, r, repo, lb, 0, err)
+			return
+		}
+		now := time.Now()
+		userID, _ := r.Context().Value(ctxDataUserID).(int)
+		gameID, scramble, err := validator.ValidSession(sessionKey, solve.Session, userID, now)
+		if err != nil {
+			rejectSolve(w, r, repo, lb, 0, err)
+			return
+		}
+		if solve.Scramble != scramble {
+			rejectSolve(w, r, repo, lb, gameID, fmt.Errorf("game_id=%d of signed scramble %s, solved %s", gameID, scramble, solve.Scramble))
+			return
+		}
+		game, err := repo.Game(r.Context(), userID, gameID)
+		switch {
+		case errors.Is(err, model.ErrGameNotFound):
+		case err != nil:
+			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("user_id=%d fetch game: %s", userID, err))
+			return
+		case game.Status != model.GameStarted:
+			err = fmt.Errorf("game_id=%d %s", gameID, game.Status)
+		case game.Size != lb.Size.String() || game.Tier != lb.Tier || game.Metric != lb.Metric:
+			err = fmt.Errorf("game_id=%d of other leaderboard than %s", gameID, lb)
+		case game.Scramble != solve.Scramble:
+			err = fmt.Errorf("game_id=%d of scramble %s, solved %s", gameID, game.Scramble, solve.Scramble)
+		default:
+			err = verifySolve(r.Context(), &solve, lb, opts...)
+		}
+		if err != nil {
+			rejectSolve(w, r, repo, lb, gameID, err)
+			return
 		}
+		boundDuration(&solve, game.Start, now)
+		games, err := repo.Games(r.Context(), userID)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("user_id=%d fetch games: %s", userID, err))
+			return
+		}
+		if s := limits.Check(solve, games, now); s.Quarantined() {
+			slog.Warn(fmt.Sprintf("user_id=%d game_id=%d solve quarantined: %s", userID, gameID, strings.Join(s.Reasons, "; ")))
+			respond(w, r, repo.Ranks,
+				func(ctx context.Context, u int, lb model.Leaderboard) (model.User, error) {
+					return repo.RegisterQuarantinedSolve(ctx, u, lb, gameID, solve, s)
+				},
+				func(resp *model.ApiResponse, lb model.Leaderboard) {
+					if g, err := repo.Game(r.Context(), userID, gameID); err == nil {
+						resp.Game = &g
+					}
+				})
+			return
+		}
+		// the daily challenge is the one of the day the game started, the solve may be past midnight
+		started := now
+		if game.Start != nil {
+			started = time.Time(*game.Start)
+		}
+		if solve.Scramble != board.Daily(lb.Size, started).String() {
+			respond(w, r, repo.Ranks, func(ctx context.Context, u int, lb model.Leaderboard) (model.User, error) {
+				return repo.RegisterGameSolve(ctx, u, lb, gameID, solve)
+			})
+			return
+		}
+		// the daily challenge game is counted on both boards, the stats of the challenge are responded
+		day := board.DayOf(started)
+		respond(w, r,
+			func(ctx context.Context, u int, lb model.Leaderboard) ([]model.Rank, error) {
+				return repo.DailyRanks(ctx, u, day, lb)
+			},
+			func(ctx context.Context, u int, lb model.Leaderboard) (model.User, error) {
+				if user, err := repo.RegisterGameSolve(ctx, u, lb, gameID, solve); err != nil {
+					return user, err
+				}
+				return repo.RegisterDailySolve(ctx, u, day, lb, solve)
+			},
+			dailyResponse(started))
 	})
 }
 
+func rejectSolve(w http.ResponseWriter, r *http.Request, repo Repository, lb model.Leaderboard, gameID int, err error) {
+	userID, _ := r.Context().Value(ctxDataUserID).(int)
+	if err := repo.RegisterRejectedSolve(r.Context(), userID, lb, gameID); err != nil {
+		slog.Error(fmt.Sprintf("user_id=%d register rejected solve: %s", userID, err))
+	}
+	errorResponse(w, http.StatusBadRequest, fmt.Errorf("user_id=%d solve rejected: %s", userID, err))
+}
+
 func apiStatsHandler(repo Repository) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		respond(w, r, repo.Rating, repo.Stats)
+		respond(w, r, repo.Ranks, repo.Stats)
+	})
+}
+
+// apiGamesHandler responds with the games of the user.
+func apiGamesHandler(repo Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(ctxDataUserID).(int)
+		if !ok {
+			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("unsupported context value type: %T", userID))
+			return
+		}
+		games, err := repo.Games(r.Context(), userID)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("user_id=%d fetch games: %s", userID, err))
+			return
+		}
+		writeResponse(w, model.ApiResponse{Games: games})
+	})
+}
+
+// apiDailyHandler responds with the challenge of the day and the user's stats of it.
+func apiDailyHandler(repo Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		day := board.DayOf(now)
+		respond(w, r,
+			func(ctx context.Context, u int, lb model.Leaderboard) ([]model.Rank, error) {
+				return repo.DailyRanks(ctx, u, day, lb)
+			},
+			func(ctx context.Context, u int, lb model.Leaderboard) (model.User, error) {
+				return repo.DailyStats(ctx, u, day, lb)
+			},
+			dailyResponse(now))
+	})
+}
+
+// apiRatingHandler responds with the user's stats of the window given by the query, all time by default.
+func apiRatingHandler(repo Repository, loc *time.Location) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		win := model.AllTime
+		if v := r.URL.Query().Get("window"); v != "" {
+			var err error
+			if win, err = model.ParseWindow(v); err != nil {
+				errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid value: %s", err))
+				return
+			}
+		}
+		if win == model.AllTime {
+			respond(w, r, repo.Ranks, repo.Stats)
+			return
+		}
+		since := win.Start(time.Now().In(loc))
+		respond(w, r,
+			func(ctx context.Context, u int, lb model.Leaderboard) ([]model.Rank, error) {
+				return repo.WindowRanks(ctx, u, lb, since)
+			},
+			func(ctx context.Context, u int, lb model.Leaderboard) (model.User, error) {
+				return repo.WindowStats(ctx, u, lb, since)
+			},
+			func(resp *model.ApiResponse, lb model.Leaderboard) { resp.Stats.Window = win })
+	})
+}
+
+// dailyResponse sets the challenge of the day to the response.
+func dailyResponse(day time.Time) func(*model.ApiResponse, model.Leaderboard) {
+	return func(resp *model.ApiResponse, lb model.Leaderboard) {
+		resp.Stats.Daily = board.DayOf(day)
+		resp.Stats.Tier = board.Expert
+		resp.Daily = &model.Daily{Date: board.DayOf(day), Scramble: board.Daily(lb.Size, day).String()}
+	}
+}
+
+// apiReplayHandler responds with the best game of the player at the rank of the query on the leaderboard's main ranking.
+// The games of the top ranks are played back by every player, the ones of any rank by the admin.
+func apiReplayHandler(repo Repository, code string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lb, err := queryLeaderboard(r)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid value: %s", err))
+			return
+		}
+		rank, err := strconv.Atoi(r.URL.Query().Get("rank"))
+		if err != nil || rank < 1 {
+			errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid rank: %q", r.URL.Query().Get("rank")))
+			return
+		}
+		if rank > ReplayTopRanks && (code == "" || code != r.Header.Get(WebAppExtraCodeHeader)) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		top, err := repo.Top(r.Context(), lb, rank)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("fetch top of %s: %s", lb, err))
+			return
+		}
+		if len(top) == 0 || len(top[0].UserIDs) < rank {
+			errorResponse(w, http.StatusNotFound, fmt.Errorf("no rank %d on %s", rank, lb))
+			return
+		}
+		userID := top[0].UserIDs[rank-1]
+		u, err := repo.Stats(r.Context(), userID, lb)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("user_id=%d fetch stats: %s", userID, err))
+			return
+		}
+		if u.BestGame == nil {
+			errorResponse(w, http.StatusNotFound, fmt.Errorf("user_id=%d has no best game on %s", userID, lb))
+			return
+		}
+		writeResponse(w, model.ApiResponse{Replay: &model.Replay{Rank: rank, UserID: userID, Recording: *u.BestGame}})
 	})
 }
 
-func apiMonitoringHandler(repo Repository, code string) http.Handler {
+// adminHandler forbids the requests without the access code.
+func adminHandler(code string, h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if code == "" || code != r.Header.Get(WebAppExtraCodeHeader) {
 			w.WriteHeader(http.StatusForbidden)
 			return
 		}
-		m, err := repo.Monitoring()
+		h.ServeHTTP(w, r)
+	})
+}
+
+func apiMonitoringHandler(repo Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, err := repo.Monitoring(r.Context())
 		if err != nil {
 			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("fetch monitoring: %s", err))
 			return
@@ -125,39 +404,132 @@ func apiMonitoringHandler(repo Repository, code string) http.Handler {
 	})
 }
 
-func respond(w http.ResponseWriter, r *http.Request, rating func() []int, action func(int) (model.User, error)) {
+// apiQuarantineHandler responds with the quarantined games and their recordings.
+func apiQuarantineHandler(repo Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		games, err := repo.Quarantine(r.Context())
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("fetch quarantine: %s", err))
+			return
+		}
+		writeResponse(w, model.ApiResponse{Games: games})
+	})
+}
+
+// apiReviewHandler approves or rejects the quarantined game, an approved game is counted as solved.
+func apiReviewHandler(repo Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gameID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid game id: %s", err))
+			return
+		}
+		var approve bool
+		switch v := r.PathValue("verdict"); v {
+		case "approve":
+			approve = true
+		case "reject":
+		default:
+			errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid verdict: %q", v))
+			return
+		}
+		g, err := repo.ReviewGame(r.Context(), gameID, approve)
+		switch {
+		case errors.Is(err, model.ErrGameNotFound):
+			errorResponse(w, http.StatusNotFound, fmt.Errorf("review game: %s", err))
+			return
+		case errors.Is(err, model.ErrNotQuarantined):
+			errorResponse(w, http.StatusBadRequest, fmt.Errorf("review game: %s", err))
+			return
+		case err != nil:
+			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("review game: %s", err))
+			return
+		}
+		writeResponse(w, model.ApiResponse{Game: &g})
+	})
+}
+
+// respond writes the user's stats after the action, the rank is of the main rating and the ranks are of all of them.
+// A missing user is not found, a game of the action which is missing, finished or of other leaderboard is a conflict.
+func respond(w http.ResponseWriter, r *http.Request, ranks func(context.Context, int, model.Leaderboard) ([]model.Rank, error), action func(context.Context, int, model.Leaderboard) (model.User, error), decorators ...func(*model.ApiResponse, model.Leaderboard)) {
 	userID, ok := r.Context().Value(ctxDataUserID).(int)
 	if !ok {
 		errorResponse(w, http.StatusInternalServerError, fmt.Errorf("unsupported context value type: %T", userID))
 		return
 	}
 
-	u, err := action(userID)
+	lb, err := queryLeaderboard(r)
 	if err != nil {
-		if u.UserID == 0 {
-			errorResponse(w, http.StatusNotFound, fmt.Errorf("user_id=%d not found", userID))
-		} else {
-			errorResponse(w, http.StatusInternalServerError, fmt.Errorf("user_id=%d game action: %s", userID, err))
-		}
+		errorResponse(w, http.StatusBadRequest, fmt.Errorf("invalid value: %s", err))
+		return
+	}
+
+	u, err := action(r.Context(), userID, lb)
+	switch {
+	case errors.Is(err, model.ErrUserNotFound):
+		errorResponse(w, http.StatusNotFound, fmt.Errorf("user_id=%d not found", userID))
+		return
+	case errors.Is(err, model.ErrGameNotFound), errors.Is(err, model.ErrGameNotStarted), errors.Is(err, model.ErrOtherLeaderboard):
+		errorResponse(w, http.StatusConflict, fmt.Errorf("user_id=%d game action: %s", userID, err))
+		return
+	case err != nil:
+		errorResponse(w, http.StatusInternalServerError, fmt.Errorf("user_id=%d game action: %s", userID, err))
+		return
+	}
+	rks, err := ranks(r.Context(), userID, lb)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, fmt.Errorf("user_id=%d fetch ranks: %s", userID, err))
 		return
 	}
 
 	stats := &model.Stats{
+		Size:         lb.Size.String(),
+		Tier:         lb.Tier,
+		Metric:       lb.Metric,
 		GamesStarted: u.GamesStarted,
 		GamesSolved:  u.GamesSolved,
-		Rank:         rankPosition(userID, rating()),
+		Ranks:        make(map[string]int),
+	}
+	for i, rank := range rks {
+		if i == 0 {
+			stats.Rank = rank.Position
+		}
+		stats.Ranks[rank.Ranking] = rank.Position
+	}
+	if u.BestTime != nil {
+		stats.BestTime = *u.BestTime
+	}
+	if u.BestMoves != nil {
+		stats.BestMoves = *u.BestMoves
 	}
 
-	writeResponse(w, model.ApiResponse{Stats: stats, Monitoring: u.Monitoring})
+	resp := model.ApiResponse{Stats: stats, Monitoring: u.Monitoring}
+	for _, decorate := range decorators {
+		decorate(&resp, lb)
+	}
+	writeResponse(w, resp)
 }
 
-func rankPosition(UserID int, rating []int) int {
-	for i, uid := range rating {
-		if uid == UserID {
-			return i + 1
+// queryLeaderboard returns the leaderboard of the request, the classic board of the expert tier and the single-tile metric by default.
+func queryLeaderboard(r *http.Request) (model.Leaderboard, error) {
+	lb := model.Leaderboard{Size: board.Classic}
+	var err error
+	if v := r.URL.Query().Get("size"); v != "" {
+		if lb.Size, err = board.ParseSize(v); err != nil {
+			return lb, err
+		}
+	}
+	if v := r.URL.Query().Get("tier"); v != "" {
+		if lb.Tier, err = board.ParseTier(v); err != nil {
+			return lb, err
+		}
+	}
+	if v := r.URL.Query().Get("metric"); v != "" {
+		if lb.Metric, err = board.ParseMetric(v); err != nil {
+			return lb, err
 		}
 	}
-	return -1
+	return lb, nil
 }
 
 func errorResponse(w http.ResponseWriter, code int, err error) {
@@ -1,17 +1,23 @@
 package handler_test
 
 import (
+	"15-puzzle/internal/board"
 	"15-puzzle/internal/model"
 	"15-puzzle/internal/repo"
+	"15-puzzle/internal/solver"
+	"15-puzzle/internal/validator"
 	"15-puzzle/internal/web-service/handler"
+	"15-puzzle/internal/web-service/plausibility"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -22,17 +28,40 @@ const (
 	userId   = 303133707
 )
 
+var sessionKey = validator.EncodeHmacSha256([]byte(botToken), []byte("GameSession"))
+
+// backends open the repositories the handler is tested with, the user of the init data is known to them.
+var backends = map[string]func(t *testing.T) handler.Repository{
+	"FileRepo": openFileRepo,
+	"MemRepo":  openMemRepo,
+	"KVRepo":   openKVRepo,
+}
+
 func TestHandler(t *testing.T) {
-	// static
-	testCase(t, testStaticExistingFile)
-	testCase(t, testStaticWebAppIndex)
-	// api
-	testCase(t, testApiInfo)
-	testCase(t, testApiAuth)
-	testCase(t, testApiStart)
-	testCase(t, testApiSolve)
-	testCase(t, testApiStats)
-	testCase(t, testApiMonitoring)
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			// static
+			testCase(t, open, testStaticExistingFile)
+			testCase(t, open, testStaticWebAppIndex)
+			// api
+			testCase(t, open, testApiInfo)
+			testCase(t, open, testApiAuth)
+			testCase(t, open, testApiStart)
+			testCase(t, open, testApiSolve)
+			testCase(t, open, testApiStats)
+			testCase(t, open, testApiBoardSize)
+			testCase(t, open, testApiDaily)
+			testCase(t, open, testApiTier)
+			testCase(t, open, testApiMetric)
+			testCase(t, open, testApiDuration)
+			testCase(t, open, testApiRating)
+			testCase(t, open, testApiGames)
+			testCase(t, open, testApiMonitoring)
+			testCase(t, open, testApiReplay)
+			testContextRoot(t, open, "", plausibility.DefaultLimits(), testApiQuarantine)
+			testContextRoot(t, startedYesterday(open), "", plausibility.Limits{}, testApiDailyPastMidnight)
+		})
+	}
 }
 
 func testStaticExistingFile(t *testing.T, ctxRoot string, h http.Handler) {
@@ -65,7 +94,7 @@ func testApiAuth(t *testing.T, ctxRoot string, h http.Handler) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 
 	w = httptest.NewRecorder()
-	req = httptest.NewRequest(http.MethodPut, ctxRoot+"/api/solve?moves=69", nil)
+	req = httptest.NewRequest(http.MethodPut, ctxRoot+"/api/solve", nil)
 	h.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 
@@ -96,55 +125,369 @@ func testApiInfo(t *testing.T, ctxRoot string, h http.Handler) {
 }
 
 func testApiStart(t *testing.T, ctxRoot string, h http.Handler) {
+	start := func(query string) (int, model.ApiResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, ctxRoot+"/api/start"+query, nil)
+		req.Header.Add(handler.WebAppInitDataHeader, initData)
+		h.ServeHTTP(w, req)
+		var u model.ApiResponse
+		if w.Code == http.StatusOK {
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+			if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+				t.Fatalf("decode json %s: %s", w.Body.String(), err)
+			}
+		}
+		return w.Code, u
+	}
+	code, u := start("?scramble=4x4:1")
+	assert.Equal(t, http.StatusOK, code, "api request with correct auth header should be ok")
+	assert.NotNil(t, u.Stats, "response: stats field should be set")
+	assert.Equal(t, 1, u.Stats.GamesStarted, "user started games should be exactly one")
+	if assert.NotNil(t, u.Game, "start should be responded with the game") {
+		assert.Equal(t, model.GameStarted, u.Game.Status)
+		assert.NotEqual(t, "4x4:1", u.Game.Scramble, "scramble should be chosen by the server")
+		sc, err := board.ParseScramble(u.Game.Scramble)
+		assert.NoError(t, err)
+		assert.Equal(t, board.Scramble{Size: board.Classic, Seed: sc.Seed}, sc, "expert scramble should be a random position")
+	}
+	assert.NotEmpty(t, u.Session, "start should be responded with the game session")
+
+	code, u = start("?size=3x3")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.NotNil(t, u.Game) {
+		assert.Zero(t, u.Game.Optimal, "optimal length of a random position should be searched by the verification")
+	}
+
+	code, u = start("?tier=easy")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.NotNil(t, u.Game) {
+		lo, hi := board.Easy.Range()
+		assert.GreaterOrEqual(t, u.Game.Optimal, lo, "scramble optimal length should be in the tier range")
+		assert.LessOrEqual(t, u.Game.Optimal, hi, "scramble optimal length should be in the tier range")
+	}
+
+	code, u = start("?size=3x3&daily=true")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.NotNil(t, u.Game) {
+		assert.Equal(t, board.Daily(board.Size{W: 3, H: 3}, time.Now()).String(), u.Game.Scramble, "daily game should be of the challenge of the day")
+	}
+	code, _ = start("?daily=true&tier=easy")
+	assert.Equal(t, http.StatusBadRequest, code, "daily challenge is a random position")
+	code, _ = start("?daily=yes")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func testApiSolve(t *testing.T, ctxRoot string, h http.Handler) {
+	rejected := 0
+	rejectBody := func(query string, body []byte, msg string) {
+		assert.Equal(t, http.StatusBadRequest, putSolveBody(ctxRoot, h, query, body).Code, msg)
+		rejected++
+	}
+	// a game is started for every rejected solve
+	reject := func(query string, edit func(solve *model.Solve), msg string) {
+		solve := startGame(t, ctxRoot, h, "")
+		edit(&solve)
+		rejectBody(query, marshal(t, solve), msg)
+	}
+	other := solveOf(t, board.Scramble{Size: board.Classic, Seed: 2}, board.Expert)
+
+	rejectBody("", nil, "empty solve")
+	rejectBody("", []byte(`{"recording":"1,2,3,4/5,6,7,8/9,10,11,12/13,14,0,15 U 500"}`), "illegal move")
+	rejectBody("", marshal(t, other), "solve without session")
+	reject("?size=3x3", func(*model.Solve) {}, "other board size")
+	reject("?tier=easy", func(*model.Solve) {}, "other tier")
+	reject("", func(solve *model.Solve) {
+		solve.Recording.Moves = solve.Recording.Moves[:len(solve.Recording.Moves)-1]
+		solve.Recording.Times = solve.Recording.Times[:len(solve.Recording.Times)-1]
+	}, "moves not solving the position")
+	reject("", func(solve *model.Solve) { solve.Scramble, solve.Recording = other.Scramble, other.Recording },
+		"solve of other scramble than the signed one")
+	reject("", func(solve *model.Solve) { solve.Recording = other.Recording }, "position of other scramble")
+	reject("", func(solve *model.Solve) { solve.Hints = -1 }, "negative hints")
+
+	unknown := other
+	unknown.Session = validator.SignSession(sessionKey, 1000, userId, other.Scramble, time.Now().Add(time.Hour))
+	rejectBody("", marshal(t, unknown), "session of unknown game")
+	expired := startGame(t, ctxRoot, h, "")
+	gameID, scramble, err := validator.ValidSession(sessionKey, expired.Session, userId, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, expired.Scramble, scramble, "session should be signed with the scramble of the game")
+	expired.Session = validator.SignSession(sessionKey, gameID, userId, scramble, time.Now().Add(-time.Second))
+	rejectBody("", marshal(t, expired), "expired session")
+
+	valid := startGame(t, ctxRoot, h, "")
+	valid.Optimal = 1
+	w := putSolve(t, ctxRoot, h, "", valid)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	var u model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	assert.NotNil(t, u.Stats, "response: stats field should be set")
+	assert.Equal(t, 1, u.Stats.GamesSolved, "user solved games should be exactly one")
+	rejectBody("", marshal(t, valid), "replayed solve of the solved game")
+
+	hinted := startGame(t, ctxRoot, h, "")
+	hinted.Hints = 2
+	w = putSolve(t, ctxRoot, h, "", hinted)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	assert.Equal(t, 2, u.Stats.GamesSolved, "hinted games should be counted as solved")
+
+	assert.Equal(t, rejected, monitoring(t, ctxRoot, h).GamesRejected, "rejected solves should be counted")
+}
+
+func testApiStats(t *testing.T, ctxRoot string, h http.Handler) {
 	w := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodPut, ctxRoot+"/api/start", nil)
+	req := httptest.NewRequest(http.MethodGet, ctxRoot+"/api/stats", nil)
 	req.Header.Add(handler.WebAppInitDataHeader, initData)
 	h.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code, "api request with correct auth header should be ok")
-
 	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
 	var u model.ApiResponse
 	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		fmt.Println(w.Body)
 		t.Fatalf("decode json %s: %s", w.Body.String(), err)
 	}
 	assert.NotNil(t, u.Stats, "response: stats field should be set")
-	assert.Equal(t, 1, u.Stats.GamesStarted, "user started games should be exactly one")
 }
 
-func testApiSolve(t *testing.T, ctxRoot string, h http.Handler) {
+func testApiBoardSize(t *testing.T, ctxRoot string, h http.Handler) {
 	w := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodPut, ctxRoot+"/api/solve", nil)
+	req := httptest.NewRequest(http.MethodPut, ctxRoot+"/api/start?size=9x9", nil)
 	req.Header.Add(handler.WebAppInitDataHeader, initData)
 	h.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
 	w = httptest.NewRecorder()
-	req = httptest.NewRequest(http.MethodPut, ctxRoot+"/api/solve?moves=69", nil)
+	req = httptest.NewRequest(http.MethodPut, ctxRoot+"/api/start?size=3x5", nil)
 	req.Header.Add(handler.WebAppInitDataHeader, initData)
 	h.ServeHTTP(w, req)
-	assert.Equal(t, http.StatusOK, w.Code, "api request with correct auth header should be ok")
-	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, http.StatusOK, w.Code)
 	var u model.ApiResponse
 	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
 		t.Fatalf("decode json %s: %s", w.Body.String(), err)
 	}
 	assert.NotNil(t, u.Stats, "response: stats field should be set")
-	assert.Equal(t, 1, u.Stats.GamesSolved, "user solved games should be exactly one")
+	assert.Equal(t, "3x5", u.Stats.Size)
+	assert.Equal(t, 1, u.Stats.Rank)
+	assert.Equal(t, 1, u.Stats.GamesStarted)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, ctxRoot+"/api/stats", nil)
+	req.Header.Add(handler.WebAppInitDataHeader, initData)
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	assert.Equal(t, "4x4", u.Stats.Size)
+	assert.Equal(t, 0, u.Stats.GamesStarted, "classic board games should not include other sizes")
 }
 
-func testApiStats(t *testing.T, ctxRoot string, h http.Handler) {
-	w := httptest.NewRecorder()
+func testApiDaily(t *testing.T, ctxRoot string, h http.Handler) {
+	daily := func() model.ApiResponse {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, ctxRoot+"/api/daily?size=3x3", nil)
+		req.Header.Add(handler.WebAppInitDataHeader, initData)
+		h.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var u model.ApiResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+			t.Fatalf("decode json %s: %s", w.Body.String(), err)
+		}
+		return u
+	}
+	small := board.Size{W: 3, H: 3}
+	sc := board.Daily(small, time.Now())
+
+	u := daily()
+	assert.Equal(t, &model.Daily{Date: board.DayOf(time.Now()), Scramble: sc.String()}, u.Daily)
+	assert.Equal(t, board.DayOf(time.Now()), u.Stats.Daily)
+	assert.Equal(t, 0, u.Stats.GamesSolved)
+	assert.Equal(t, -1, u.Stats.Rank)
+
+	solve := startGame(t, ctxRoot, h, "?size=3x3&daily=true")
+	assert.Equal(t, sc.String(), solve.Scramble)
+	w := putSolve(t, ctxRoot, h, "?size=3x3", solve)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	assert.NotNil(t, u.Daily, "daily challenge solve should be responded with the challenge stats")
+	assert.Equal(t, 1, u.Stats.Rank)
+
+	u = daily()
+	assert.Equal(t, 1, u.Stats.GamesSolved)
+	assert.Equal(t, 1, u.Stats.Rank)
+}
+
+// testApiDailyPastMidnight solves the daily challenge of the game started a second before midnight.
+func testApiDailyPastMidnight(t *testing.T, ctxRoot string, h http.Handler) {
+	small := board.Size{W: 3, H: 3}
+	yesterday := time.Now().UTC().Truncate(24 * time.Hour).Add(-time.Second)
+	sc := board.Daily(small, yesterday)
+	gameID, _, err := validator.ValidSession(sessionKey, startGame(t, ctxRoot, h, "?size=3x3&daily=true").Session, userId, time.Now())
+	assert.NoError(t, err)
+	// the game is of the challenge of the day before, as it is signed on the start
+	solve := solveOf(t, sc, board.Expert)
+	solve.Session = validator.SignSession(sessionKey, gameID, userId, sc.String(), time.Now().Add(time.Hour))
+	w := putSolve(t, ctxRoot, h, "?size=3x3", solve)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var u model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	assert.Equal(t, &model.Daily{Date: board.DayOf(yesterday), Scramble: sc.String()}, u.Daily, "challenge of the day of the start")
+	if assert.NotNil(t, u.Stats) {
+		assert.Equal(t, board.DayOf(yesterday), u.Stats.Daily)
+		assert.Equal(t, 1, u.Stats.GamesSolved)
+		assert.Equal(t, 1, u.Stats.Rank)
+	}
+}
+
+func testApiTier(t *testing.T, ctxRoot string, h http.Handler) {
+	medium, _, err := solver.Scramble(context.Background(), board.Classic, board.Medium)
+	assert.NoError(t, err)
+
+	solve := startGame(t, ctxRoot, h, "?tier=easy")
+	solve.Tier = board.Hard
+	assert.Equal(t, http.StatusBadRequest, putSolve(t, ctxRoot, h, "?tier=easy", solve).Code, "solve tier should match the query one")
+	solve = startGame(t, ctxRoot, h, "?tier=easy")
+	other := solveOf(t, medium, board.Easy)
+	solve.Scramble, solve.Recording = other.Scramble, other.Recording
+	assert.Equal(t, http.StatusBadRequest, putSolve(t, ctxRoot, h, "?tier=easy", solve).Code, "scramble should be the one of the tier signed")
+	assert.Equal(t, http.StatusBadRequest, putSolve(t, ctxRoot, h, "?tier=trivial", startGame(t, ctxRoot, h, "?tier=easy")).Code)
+
+	w := putSolve(t, ctxRoot, h, "?tier=easy", startGame(t, ctxRoot, h, "?tier=easy"))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var u model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	assert.Equal(t, board.Easy, u.Stats.Tier)
+	assert.Equal(t, 1, u.Stats.GamesSolved)
+
+	w = httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, ctxRoot+"/api/stats", nil)
 	req.Header.Add(handler.WebAppInitDataHeader, initData)
 	h.ServeHTTP(w, req)
-	assert.Equal(t, http.StatusOK, w.Code, "api request with correct auth header should be ok")
-	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	assert.Equal(t, board.Expert, stats.Stats.Tier)
+	assert.Equal(t, 0, stats.Stats.GamesSolved, "expert games should not include other tiers")
+}
+
+func testApiMetric(t *testing.T, ctxRoot string, h http.Handler) {
+	solve := startGame(t, ctxRoot, h, "?metric=mtm")
+	solve.Metric = board.SingleTile
+	assert.Equal(t, http.StatusBadRequest, putSolve(t, ctxRoot, h, "?metric=mtm", solve).Code,
+		"solve metric should match the query one")
+	assert.Equal(t, http.StatusBadRequest, putSolve(t, ctxRoot, h, "?metric=qtm", startGame(t, ctxRoot, h, "?metric=mtm")).Code)
+
+	w := putSolve(t, ctxRoot, h, "?metric=mtm", startGame(t, ctxRoot, h, "?metric=mtm"))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
 	var u model.ApiResponse
 	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
-		fmt.Println(w.Body)
 		t.Fatalf("decode json %s: %s", w.Body.String(), err)
 	}
-	assert.NotNil(t, u.Stats, "response: stats field should be set")
+	assert.Equal(t, board.MultiTile, u.Stats.Metric)
+	assert.Equal(t, 1, u.Stats.Rank)
+	assert.Equal(t, map[string]int{"time": 1, "moves": 1, "per_move": 1, "solves": 1}, u.Stats.Ranks)
+	assert.Equal(t, 1, u.Stats.GamesSolved)
+}
+
+func testApiDuration(t *testing.T, ctxRoot string, h http.Handler) {
+	solve := startGame(t, ctxRoot, h, "")
+	solve.Duration = model.JSONDuration(-time.Second)
+	assert.Equal(t, http.StatusBadRequest, putSolve(t, ctxRoot, h, "", solve).Code, "negative duration")
+
+	solve = startGame(t, ctxRoot, h, "")
+	solve.Duration = model.JSONDuration(solve.Recording.Duration())
+	time.Sleep(10 * time.Millisecond)
+	w := putSolve(t, ctxRoot, h, "", solve)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var u model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	assert.Positive(t, u.Stats.BestTime)
+	assert.Less(t, time.Duration(u.Stats.BestTime), time.Second, "duration should be bound by the time since the start")
+	assert.Equal(t, len(solve.Recording.Moves), u.Stats.BestMoves)
+}
+
+func testApiGames(t *testing.T, ctxRoot string, h http.Handler) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, ctxRoot+"/api/start?tier=trivial", nil)
+	req.Header.Add(handler.WebAppInitDataHeader, initData)
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// games are finished in other order than started
+	first := startGame(t, ctxRoot, h, "")
+	second := startGame(t, ctxRoot, h, "")
+	second.Hints = -1
+	assert.Equal(t, http.StatusBadRequest, putSolve(t, ctxRoot, h, "", second).Code)
+	assert.Equal(t, http.StatusOK, putSolve(t, ctxRoot, h, "", first).Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, ctxRoot+"/api/games", nil)
+	req.Header.Add(handler.WebAppInitDataHeader, initData)
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var u model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	if assert.Len(t, u.Games, 2) {
+		assert.Equal(t, model.GameSolved, u.Games[0].Status, "the game of the session should be solved")
+		assert.Equal(t, len(first.Recording.Moves), u.Games[0].Moves)
+		assert.Equal(t, model.GameRejected, u.Games[1].Status)
+	}
+}
+
+func testApiRating(t *testing.T, ctxRoot string, h http.Handler) {
+	rating := func(window string) (int, model.ApiResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, ctxRoot+"/api/rating?window="+window, nil)
+		req.Header.Add(handler.WebAppInitDataHeader, initData)
+		h.ServeHTTP(w, req)
+		var u model.ApiResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+				t.Fatalf("decode json %s: %s", w.Body.String(), err)
+			}
+		}
+		return w.Code, u
+	}
+	code, _ := rating("year")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, u := rating("week")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, model.Week, u.Stats.Window)
+	assert.Equal(t, 0, u.Stats.GamesSolved)
+	assert.Equal(t, -1, u.Stats.Rank, "a player without solves in the window should not be ranked")
+
+	assert.Equal(t, http.StatusOK, putSolve(t, ctxRoot, h, "", startGame(t, ctxRoot, h, "")).Code)
+	for _, window := range []string{"day", "week", "month"} {
+		code, u = rating(window)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, window, u.Stats.Window.String())
+		assert.Equal(t, 1, u.Stats.GamesSolved, window)
+		assert.Equal(t, 1, u.Stats.Rank, window)
+	}
+
+	code, u = rating("all")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, model.AllTime, u.Stats.Window)
+	assert.Equal(t, 1, u.Stats.Rank)
 }
 
 func testApiMonitoring(t *testing.T, ctxRoot string, h http.Handler) {
@@ -172,37 +515,233 @@ func testApiMonitoring(t *testing.T, ctxRoot string, h http.Handler) {
 	assert.NotNil(t, u.Monitoring.GamesSolved)
 }
 
-func testCase(t *testing.T, tc func(*testing.T, string, http.Handler)) {
-	testContextRoot(t, "", tc)
-	testContextRoot(t, "/", tc)
-	testContextRoot(t, "/15-puzzle/", tc)
-	testContextRoot(t, "/15-puzzle", tc)
+func testApiReplay(t *testing.T, ctxRoot string, h http.Handler) {
+	replay := func(query, code string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, ctxRoot+"/api/replay"+query, nil)
+		req.Header.Add(handler.WebAppInitDataHeader, initData)
+		if code != "" {
+			req.Header.Add(handler.WebAppExtraCodeHeader, code)
+		}
+		h.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusNotFound, replay("?rank=1", "").Code, "leaderboard of no players")
+	solve := startGame(t, ctxRoot, h, "")
+	solve.Duration = model.JSONDuration(solve.Recording.Duration()) // the best game is the one of the best time
+	assert.Equal(t, http.StatusOK, putSolve(t, ctxRoot, h, "", solve).Code)
+
+	w := replay("?rank=1", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var u model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	if assert.NotNil(t, u.Replay) {
+		assert.Equal(t, model.Replay{Rank: 1, UserID: userId, Recording: solve.Recording}, *u.Replay)
+	}
+	assert.Equal(t, http.StatusNotFound, replay("?rank=2", "").Code, "rank of no player")
+	assert.Equal(t, http.StatusNotFound, replay("?rank=1&tier=easy", "").Code, "other leaderboard")
+	assert.Equal(t, http.StatusBadRequest, replay("?rank=0", "").Code)
+	assert.Equal(t, http.StatusBadRequest, replay("", "").Code)
+	assert.Equal(t, http.StatusForbidden, replay(fmt.Sprintf("?rank=%d", handler.ReplayTopRanks+1), "").Code, "rank below the top")
+	assert.Equal(t, http.StatusNotFound, replay(fmt.Sprintf("?rank=%d", handler.ReplayTopRanks+1), "1234").Code, "any rank of the admin")
+}
+
+// testApiQuarantine solves right after the start, the pace of the solve is bound by the time passed on the server.
+func testApiQuarantine(t *testing.T, ctxRoot string, h http.Handler) {
+	solve := func() model.ApiResponse {
+		w := putSolve(t, ctxRoot, h, "", startGame(t, ctxRoot, h, ""))
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var u model.ApiResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+			t.Fatalf("decode json %s: %s", w.Body.String(), err)
+		}
+		return u
+	}
+	u := solve()
+	assert.Equal(t, 0, u.Stats.GamesSolved, "quarantined game should not be counted")
+	if assert.NotNil(t, u.Game) && assert.NotNil(t, u.Game.Suspicion) {
+		assert.Equal(t, model.GameQuarantined, u.Game.Status)
+		assert.NotEmpty(t, u.Game.Suspicion.Reasons)
+	}
+	approved := solve().Game
+	rejected := solve().Game
+	assert.Equal(t, 3, monitoring(t, ctxRoot, h).GamesQuarantined)
+
+	assert.Equal(t, http.StatusForbidden, admin(ctxRoot, h, http.MethodGet, "/quarantine", "").Code)
+	w := admin(ctxRoot, h, http.MethodGet, "/quarantine", "1234")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	if assert.Len(t, resp.Games, 3) {
+		assert.NotNil(t, resp.Games[0].Recording, "quarantined game should keep the recording for review")
+	}
+
+	review := func(gameID int, verdict string, code int) {
+		w := admin(ctxRoot, h, http.MethodPut, fmt.Sprintf("/games/%d/%s", gameID, verdict), "1234")
+		assert.Equal(t, code, w.Code, w.Body.String())
+	}
+	assert.Equal(t, http.StatusForbidden, admin(ctxRoot, h, http.MethodPut, fmt.Sprintf("/games/%d/approve", approved.ID), "").Code)
+	review(approved.ID, "accept", http.StatusBadRequest)
+	review(approved.ID, "approve", http.StatusOK)
+	review(approved.ID, "reject", http.StatusBadRequest)
+	review(rejected.ID, "reject", http.StatusOK)
+
+	m := monitoring(t, ctxRoot, h)
+	assert.Equal(t, 1, m.GamesQuarantined)
+	assert.Equal(t, 1, m.GamesSolved)
+	assert.Equal(t, 1, m.GamesRejected)
 }
 
-func testContextRoot(t *testing.T, ctxRoot string, tc func(*testing.T, string, http.Handler)) {
-	f, err := os.CreateTemp("", "puzzle15-handler-test")
+// solveOf returns a game of the scramble solved with the reduction solver, a move a second.
+func solveOf(t *testing.T, sc board.Scramble, tier board.Tier) model.Solve {
+	res, err := solver.Reduce(context.Background(), sc.Board())
 	if err != nil {
-		t.Fatalf("temporary file create: %s", err)
+		t.Fatalf("Reduce %s: %s", sc, err)
 	}
-	if err := f.Close(); err != nil {
-		t.Fatalf("temporary file close: %s", err)
+	rec := board.Recording{Start: sc.Board(), Moves: res.Moves, Times: make([]time.Duration, len(res.Moves))}
+	for i := range rec.Times {
+		rec.Times[i] = time.Duration(i+1) * time.Second
 	}
-	if err := os.Remove(f.Name()); err != nil {
-		t.Fatalf("temporary file remove before: %s", err)
+	return model.Solve{Scramble: sc.String(), Tier: tier, Recording: rec}
+}
+
+func marshal(t *testing.T, v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("encode json: %s", err)
 	}
-	defer func() {
-		if err := os.Remove(f.Name()); err != nil {
-			t.Errorf("temporary file remove after: %s", err)
-		}
-	}()
+	return b
+}
+
+// startGame starts the game on the leaderboard of the query and returns the solve of the scramble chosen by the server with the game session.
+func startGame(t *testing.T, ctxRoot string, h http.Handler, query string) model.Solve {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, ctxRoot+"/api/start"+query, nil)
+	req.Header.Add(handler.WebAppInitDataHeader, initData)
+	h.ServeHTTP(w, req)
+	var u model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil {
+		t.Fatalf("decode json %s: %s", w.Body.String(), err)
+	}
+	if u.Game == nil || u.Session == nil {
+		t.Fatalf("start%s: no game session in %s", query, w.Body.String())
+	}
+	sc, err := board.ParseScramble(u.Game.Scramble)
+	if err != nil {
+		t.Fatalf("start%s: %s", query, err)
+	}
+	solve := solveOf(t, sc, u.Game.Tier)
+	solve.Metric, solve.Session = u.Game.Metric, *u.Session
+	return solve
+}
+
+// putSolve puts the solve of the started game.
+func putSolve(t *testing.T, ctxRoot string, h http.Handler, query string, solve model.Solve) *httptest.ResponseRecorder {
+	return putSolveBody(ctxRoot, h, query, marshal(t, solve))
+}
+
+func putSolveBody(ctxRoot string, h http.Handler, query string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, ctxRoot+"/api/solve"+query, bytes.NewReader(body))
+	req.Header.Add(handler.WebAppInitDataHeader, initData)
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func admin(ctxRoot string, h http.Handler, method, path, code string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, ctxRoot+"/api/admin"+path, nil)
+	req.Header.Add(handler.WebAppInitDataHeader, initData)
+	if code != "" {
+		req.Header.Add(handler.WebAppExtraCodeHeader, code)
+	}
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func monitoring(t *testing.T, ctxRoot string, h http.Handler) model.Monitoring {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, ctxRoot+"/api/monitoring", nil)
+	req.Header.Add(handler.WebAppInitDataHeader, initData)
+	req.Header.Add(handler.WebAppExtraCodeHeader, "1234")
+	h.ServeHTTP(w, req)
+	var u model.ApiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &u); err != nil || u.Monitoring == nil {
+		t.Fatalf("decode json %s: %v", w.Body.String(), err)
+	}
+	return *u.Monitoring
+}
+
+func testCase(t *testing.T, open func(*testing.T) handler.Repository, tc func(*testing.T, string, http.Handler)) {
+	testContextRoot(t, open, "", plausibility.Limits{}, tc)
+	testContextRoot(t, open, "/", plausibility.Limits{}, tc)
+	testContextRoot(t, open, "/15-puzzle/", plausibility.Limits{}, tc)
+	testContextRoot(t, open, "/15-puzzle", plausibility.Limits{}, tc)
+}
+
+func testContextRoot(t *testing.T, open func(*testing.T) handler.Repository, ctxRoot string, limits plausibility.Limits, tc func(*testing.T, string, http.Handler)) {
+	tc(t, strings.TrimRight(ctxRoot, "/"), handler.NewHandler(open(t), botToken, "1234", ctxRoot, "testdata", "projectLink", time.UTC, limits))
+}
+
+// startedYesterday opens the repositories whose games are of the daily challenge started a second before the last midnight.
+func startedYesterday(open func(*testing.T) handler.Repository) func(*testing.T) handler.Repository {
+	return func(t *testing.T) handler.Repository { return yesterdayRepo{open(t)} }
+}
+
+type yesterdayRepo struct {
+	handler.Repository
+}
+
+func (r yesterdayRepo) Game(ctx context.Context, UserID, gameID int) (model.Game, error) {
+	g, err := r.Repository.Game(ctx, UserID, gameID)
+	if size, err := board.ParseSize(g.Size); err == nil && g.Start != nil {
+		yesterday := time.Now().UTC().Truncate(24 * time.Hour).Add(-time.Second)
+		start := model.JSONTimestamp(yesterday)
+		g.Start, g.Scramble = &start, board.Daily(size, yesterday).String()
+	}
+	return g, err
+}
 
-	r, err := repo.NewFileRepo(context.Background(), f.Name())
+func openFileRepo(t *testing.T) handler.Repository {
+	r, err := repo.NewFileRepo(context.Background(), filepath.Join(t.TempDir(), "data.json"), repo.SyncNever)
 	if err != nil {
 		t.Fatalf("NewFileRepo: %s", err)
 	}
-	err = r.AddUser(userId)
+	t.Cleanup(func() {
+		if err := r.Close(); err != nil {
+			t.Errorf("NewFileRepo close: %s", err)
+		}
+	})
+	if err := r.AddUser(context.Background(), userId); err != nil {
+		t.Fatalf("AddUser: %s", err)
+	}
+	return r
+}
+
+func openMemRepo(t *testing.T) handler.Repository {
+	r := repo.NewMemRepo()
+	if err := r.AddUser(context.Background(), userId); err != nil {
+		t.Fatalf("AddUser: %s", err)
+	}
+	return r
+}
+
+func openKVRepo(t *testing.T) handler.Repository {
+	r, err := repo.NewKVRepo(context.Background(), filepath.Join(t.TempDir(), "data.json"), repo.SyncNever)
 	if err != nil {
-		t.Fatalf("RegisterGameStart: %s", err)
+		t.Fatalf("NewKVRepo: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := r.Close(); err != nil {
+			t.Errorf("NewKVRepo close: %s", err)
+		}
+	})
+	if err := r.AddUser(context.Background(), userId); err != nil {
+		t.Fatalf("AddUser: %s", err)
 	}
-	tc(t, strings.TrimRight(ctxRoot, "/"), handler.NewHandler(r, botToken, "1234", ctxRoot, "testdata", "projectLink"))
+	return r
 }
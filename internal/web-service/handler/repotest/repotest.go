@@ -0,0 +1,380 @@
+// Package repotest is the conformance suite of the handler.Repository, every storage backend is expected to pass it.
+package repotest
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/web-service/handler"
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var classic = model.Leaderboard{Size: board.Classic}
+
+// Run runs the suite against the repositories of the backend, every test opens an empty repository.
+func Run(t *testing.T, open func(t *testing.T) handler.Repository) {
+	for _, tc := range []struct {
+		name string
+		test func(*testing.T, handler.Repository)
+	}{
+		{"UserNotFound", testUserNotFound},
+		{"Games", testGames},
+		{"FailedSolve", testFailedSolve},
+		{"Quarantine", testQuarantine},
+		{"Ranks", testRanks},
+		{"Daily", testDaily},
+		{"Window", testWindow},
+		{"Monitoring", testMonitoring},
+		{"CanceledContext", testCanceledContext},
+	} {
+		t.Run(tc.name, func(t *testing.T) { tc.test(t, open(t)) })
+	}
+}
+
+// RunImport runs the suite against the repository of the backend imported from the data written to other one,
+// write opens the empty repository the data is written to and imported opens the repository of the data once it is written.
+func RunImport(t *testing.T, write, imported func(t *testing.T) handler.Repository) {
+	ctx := context.Background()
+	r := write(t)
+	easy := model.Leaderboard{Size: board.Classic, Tier: board.Easy}
+	solveGame(t, r, 1, 20)
+	solveGame(t, r, 2, 10)
+	g := startGame(t, r, 2, easy)
+	assert.NoError(t, r.RegisterRejectedSolve(ctx, 2, easy, g.ID))
+	startGame(t, r, 3, classic)
+	g = startGame(t, r, 3, classic)
+	_, err := r.RegisterQuarantinedSolve(ctx, 3, classic, g.ID, solveOf(5), model.Suspicion{Score: 1, Reasons: []string{"too fast"}})
+	assert.NoError(t, err)
+	_, err = r.RegisterDailySolve(ctx, 1, "2024-12-31", classic, solveOf(12))
+	assert.NoError(t, err)
+	expected := snapshot(t, r)
+
+	r = imported(t)
+	assert.JSONEq(t, expected, snapshot(t, r), "imported repository should respond the same")
+	next := startGame(t, r, 4, classic)
+	assert.Equal(t, g.ID+1, next.ID, "game ids should follow the imported ones")
+	m, err := r.Monitoring(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, m.Users)
+}
+
+// snapshot encodes the responses of the repository on the players of the import, the times are encoded in seconds.
+func snapshot(t *testing.T, r handler.Repository) string {
+	ctx := context.Background()
+	since := time.Now().Add(-time.Hour)
+	s := make(map[string]any)
+	add := func(key string, v any, err error) {
+		assert.NoError(t, err, key)
+		s[key] = v
+	}
+	m, err := r.Monitoring(ctx)
+	add("monitoring", m, err)
+	games, err := r.Quarantine(ctx)
+	add("quarantine", games, err)
+	for _, lb := range []model.Leaderboard{classic, {Size: board.Classic, Tier: board.Easy}} {
+		top, err := r.Top(ctx, lb, -1)
+		add(lb.String()+"/top", top, err)
+	}
+	for UserID := 1; UserID <= 3; UserID++ {
+		key := strconv.Itoa(UserID)
+		u, err := r.Stats(ctx, UserID, classic)
+		add(key+"/stats", u, err)
+		ranks, err := r.Ranks(ctx, UserID, classic)
+		add(key+"/ranks", ranks, err)
+		games, err := r.Games(ctx, UserID)
+		add(key+"/games", games, err)
+		ranks, err = r.WindowRanks(ctx, UserID, classic, since)
+		add(key+"/window", ranks, err)
+		u, err = r.DailyStats(ctx, UserID, "2024-12-31", classic)
+		add(key+"/daily", u, err)
+		ranks, err = r.DailyRanks(ctx, UserID, "2024-12-31", classic)
+		add(key+"/daily/ranks", ranks, err)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("encode snapshot: %s", err)
+	}
+	return string(b)
+}
+
+func testUserNotFound(t *testing.T, r handler.Repository) {
+	ctx := context.Background()
+	_, err := r.Stats(ctx, 1, classic)
+	assert.ErrorIs(t, err, model.ErrUserNotFound)
+
+	startGame(t, r, 1, classic)
+	u, err := r.Stats(ctx, 1, model.Leaderboard{Size: board.Classic, Tier: board.Easy})
+	assert.NoError(t, err, "user known by other leaderboard should be found")
+	assert.Equal(t, model.User{UserID: 1}, u)
+}
+
+func testGames(t *testing.T, r handler.Repository) {
+	ctx := context.Background()
+	first := startGame(t, r, 1, classic)
+	second := startGame(t, r, 1, classic)
+	assert.Equal(t, first.ID+1, second.ID, "game ids should be in order of start")
+
+	g, err := r.Game(ctx, 1, first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.GameStarted, g.Status)
+	assert.Equal(t, "4x4:1", g.Scramble)
+	_, err = r.Game(ctx, 2, first.ID)
+	assert.ErrorIs(t, err, model.ErrGameNotFound, "game of other user")
+	_, err = r.Game(ctx, 1, second.ID+1)
+	assert.ErrorIs(t, err, model.ErrGameNotFound)
+
+	u, err := r.RegisterGameSolve(ctx, 1, classic, second.ID, solveOf(10))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, u.GamesStarted)
+	assert.Equal(t, 1, u.GamesSolved)
+	if assert.NotNil(t, u.BestMoves) {
+		assert.Equal(t, 10, *u.BestMoves)
+	}
+	assert.NoError(t, r.RegisterRejectedSolve(ctx, 1, classic, first.ID))
+
+	games, err := r.Games(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, games, 2) {
+		assert.Equal(t, model.GameRejected, games[0].Status)
+		assert.Equal(t, model.GameSolved, games[1].Status)
+		assert.Equal(t, 10, games[1].Moves)
+		assert.NotNil(t, games[1].Finish)
+	}
+	u, err = r.Stats(ctx, 1, classic)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, u.GamesRejected)
+}
+
+func testFailedSolve(t *testing.T, r handler.Repository) {
+	ctx := context.Background()
+	g := startGame(t, r, 1, classic)
+	_, err := r.RegisterGameSolve(ctx, 2, classic, g.ID, solveOf(10))
+	assert.ErrorIs(t, err, model.ErrGameNotFound, "game of other user")
+	_, err = r.RegisterGameSolve(ctx, 1, model.Leaderboard{Size: board.Classic, Tier: board.Easy}, g.ID, solveOf(10))
+	assert.ErrorIs(t, err, model.ErrOtherLeaderboard)
+	_, err = r.RegisterGameSolve(ctx, 1, classic, g.ID, solveOf(10))
+	assert.NoError(t, err)
+	_, err = r.RegisterGameSolve(ctx, 1, classic, g.ID, solveOf(5))
+	assert.ErrorIs(t, err, model.ErrGameNotStarted, "game solved twice")
+
+	u, err := r.Stats(ctx, 1, classic)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, u.GamesSolved, "failed solves should not be counted")
+	_, err = r.Stats(ctx, 2, classic)
+	assert.ErrorIs(t, err, model.ErrUserNotFound, "failed solve should not add the user")
+}
+
+func testQuarantine(t *testing.T, r handler.Repository) {
+	ctx := context.Background()
+	suspicion := model.Suspicion{Score: 1, Reasons: []string{"too fast"}}
+	var ids []int
+	for range 2 {
+		g := startGame(t, r, 1, classic)
+		u, err := r.RegisterQuarantinedSolve(ctx, 1, classic, g.ID, solveOf(10), suspicion)
+		assert.NoError(t, err)
+		assert.Zero(t, u.GamesSolved, "quarantined game should not be counted")
+		ids = append(ids, g.ID)
+	}
+	games, err := r.Quarantine(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, games, 2) {
+		assert.NotNil(t, games[0].Recording)
+		assert.Equal(t, &suspicion, games[0].Suspicion)
+	}
+
+	g, err := r.ReviewGame(ctx, ids[0], true)
+	assert.NoError(t, err)
+	assert.Equal(t, model.GameSolved, g.Status)
+	g, err = r.ReviewGame(ctx, ids[1], false)
+	assert.NoError(t, err)
+	assert.Equal(t, model.GameRejected, g.Status)
+	_, err = r.ReviewGame(ctx, ids[0], false)
+	assert.ErrorIs(t, err, model.ErrNotQuarantined, "reviewed game")
+	_, err = r.ReviewGame(ctx, ids[1]+1, true)
+	assert.ErrorIs(t, err, model.ErrGameNotFound)
+
+	games, err = r.Quarantine(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, games)
+	u, err := r.Stats(ctx, 1, classic)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, u.GamesSolved)
+	assert.Equal(t, 1, u.GamesRejected)
+	assert.NotNil(t, u.BestGame)
+}
+
+func testRanks(t *testing.T, r handler.Repository) {
+	ctx := context.Background()
+	solveGame(t, r, 1, 20)
+	solveGame(t, r, 2, 10)
+	startGame(t, r, 3, classic) // the players who have not solved a game are ranked last
+	for UserID, expected := range map[int]int{1: 2, 2: 1, 3: 3, 4: -1} {
+		ranks, err := r.Ranks(ctx, UserID, classic)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, ranks) {
+			assert.Equal(t, expected, ranks[0].Position, "user_id=%d", UserID)
+		}
+	}
+	for limit, expected := range map[int][]int{2: {2, 1}, -1: {2, 1, 3}} {
+		top, err := r.Top(ctx, classic, limit)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, top) {
+			assert.Equal(t, expected, top[0].UserIDs, "top %d", limit)
+		}
+	}
+	ranks, err := r.Ranks(ctx, 1, model.Leaderboard{Size: board.Classic, Tier: board.Easy})
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, ranks) {
+		assert.Equal(t, -1, ranks[0].Position, "player of other leaderboard")
+	}
+	top, err := r.Top(ctx, model.Leaderboard{Size: board.Classic, Tier: board.Easy}, 10)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, top) {
+		assert.Empty(t, top[0].UserIDs, "top of other leaderboard")
+	}
+}
+
+func testDaily(t *testing.T, r handler.Repository) {
+	ctx := context.Background()
+	u, err := r.DailyStats(ctx, 1, "2024-12-31", classic)
+	assert.NoError(t, err)
+	assert.Equal(t, model.User{UserID: 1}, u, "user who has not solved the challenge")
+
+	for UserID, moves := range map[int]int{1: 20, 2: 10} {
+		u, err := r.RegisterDailySolve(ctx, UserID, "2024-12-31", classic, solveOf(moves))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, u.GamesSolved)
+	}
+	u, err = r.DailyStats(ctx, 1, "2024-12-31", classic)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, u.GamesSolved)
+	u, err = r.DailyStats(ctx, 1, "2025-01-01", classic)
+	assert.NoError(t, err)
+	assert.Zero(t, u.GamesSolved, "challenge of other day")
+
+	ranks, err := r.DailyRanks(ctx, 1, "2024-12-31", classic)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, ranks) {
+		assert.Equal(t, 2, ranks[0].Position)
+	}
+	ranks, err = r.Ranks(ctx, 1, classic)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, ranks) {
+		assert.Equal(t, -1, ranks[0].Position, "daily challenge should not be ranked on the leaderboard")
+	}
+}
+
+func testWindow(t *testing.T, r handler.Repository) {
+	ctx := context.Background()
+	since := time.Now().Add(-time.Minute)
+	solveGame(t, r, 1, 20)
+	solveGame(t, r, 2, 10)
+
+	u, err := r.WindowStats(ctx, 1, classic, since)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, u.GamesStarted)
+	assert.Equal(t, 1, u.GamesSolved)
+	ranks, err := r.WindowRanks(ctx, 1, classic, since)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, ranks) {
+		assert.Equal(t, 2, ranks[0].Position)
+	}
+
+	later := time.Now().Add(time.Minute)
+	u, err = r.WindowStats(ctx, 1, classic, later)
+	assert.NoError(t, err)
+	assert.Equal(t, model.User{UserID: 1}, u, "user who has not played since the time")
+	ranks, err = r.WindowRanks(ctx, 1, classic, later)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, ranks) {
+		assert.Equal(t, -1, ranks[0].Position)
+	}
+}
+
+func testMonitoring(t *testing.T, r handler.Repository) {
+	ctx := context.Background()
+	solveGame(t, r, 1, 10)
+	easy := model.Leaderboard{Size: board.Classic, Tier: board.Easy}
+	g := startGame(t, r, 2, easy)
+	assert.NoError(t, r.RegisterRejectedSolve(ctx, 2, easy, g.ID))
+	startGame(t, r, 2, classic)
+
+	m, err := r.Monitoring(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, model.Monitoring{Users: 2, GamesStarted: 3, GamesSolved: 1, GamesRejected: 1}, m)
+}
+
+// testCanceledContext expects the calls of the done context to fail without changes.
+func testCanceledContext(t *testing.T, r handler.Repository) {
+	g := startGame(t, r, 1, classic)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := r.RegisterGameStart(ctx, 1, classic, "4x4:1")
+	assert.ErrorIs(t, err, context.Canceled, "RegisterGameStart")
+	_, err = r.RegisterGameSolve(ctx, 1, classic, g.ID, solveOf(10))
+	assert.ErrorIs(t, err, context.Canceled, "RegisterGameSolve")
+	_, err = r.RegisterQuarantinedSolve(ctx, 1, classic, g.ID, solveOf(10), model.Suspicion{})
+	assert.ErrorIs(t, err, context.Canceled, "RegisterQuarantinedSolve")
+	assert.ErrorIs(t, r.RegisterRejectedSolve(ctx, 1, classic, g.ID), context.Canceled, "RegisterRejectedSolve")
+	_, err = r.RegisterDailySolve(ctx, 1, "2024-12-31", classic, solveOf(10))
+	assert.ErrorIs(t, err, context.Canceled, "RegisterDailySolve")
+	_, err = r.ReviewGame(ctx, g.ID, true)
+	assert.ErrorIs(t, err, context.Canceled, "ReviewGame")
+	_, err = r.Game(ctx, 1, g.ID)
+	assert.ErrorIs(t, err, context.Canceled, "Game")
+	_, err = r.Games(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled, "Games")
+	_, err = r.Stats(ctx, 1, classic)
+	assert.ErrorIs(t, err, context.Canceled, "Stats")
+	_, err = r.Ranks(ctx, 1, classic)
+	assert.ErrorIs(t, err, context.Canceled, "Ranks")
+	_, err = r.Top(ctx, classic, 10)
+	assert.ErrorIs(t, err, context.Canceled, "Top")
+	_, err = r.DailyStats(ctx, 1, "2024-12-31", classic)
+	assert.ErrorIs(t, err, context.Canceled, "DailyStats")
+	_, err = r.DailyRanks(ctx, 1, "2024-12-31", classic)
+	assert.ErrorIs(t, err, context.Canceled, "DailyRanks")
+	_, err = r.WindowStats(ctx, 1, classic, time.Time{})
+	assert.ErrorIs(t, err, context.Canceled, "WindowStats")
+	_, err = r.WindowRanks(ctx, 1, classic, time.Time{})
+	assert.ErrorIs(t, err, context.Canceled, "WindowRanks")
+	_, err = r.Monitoring(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "Monitoring")
+	_, err = r.Quarantine(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "Quarantine")
+
+	games, err := r.Games(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Game{g}, games, "canceled writes should not change the game")
+}
+
+func startGame(t *testing.T, r handler.Repository, UserID int, lb model.Leaderboard) model.Game {
+	_, g, err := r.RegisterGameStart(context.Background(), UserID, lb, "4x4:1")
+	if err != nil {
+		t.Fatalf("RegisterGameStart: %s", err)
+	}
+	return g
+}
+
+// solveGame starts and solves the game of the classic board in the moves, a second a move.
+func solveGame(t *testing.T, r handler.Repository, UserID, moves int) {
+	g := startGame(t, r, UserID, classic)
+	if _, err := r.RegisterGameSolve(context.Background(), UserID, classic, g.ID, solveOf(moves)); err != nil {
+		t.Fatalf("RegisterGameSolve: %s", err)
+	}
+}
+
+func solveOf(moves int) model.Solve {
+	rec := board.Recording{Start: board.Solved(board.Classic)}
+	for i := range moves {
+		rec.Moves = append(rec.Moves, []board.Direction{board.Right, board.Left}[i%2])
+		rec.Times = append(rec.Times, time.Duration(i)*time.Second)
+	}
+	return model.Solve{Duration: model.JSONDuration(rec.Duration()), Recording: rec}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/solver"
+	"context"
+	"fmt"
+	"time"
+)
+
+// verifySolve replays the submitted game from the scramble position and fills the solve with the values computed on the server:
+// the start position of the scramble and the optimal solution length found with the solver options.
+// The moves count is the length of the replayed recording.
+func verifySolve(ctx context.Context, solve *model.Solve, lb model.Leaderboard, opts ...solver.Option) error {
+	sc, err := board.ParseScramble(solve.Scramble)
+	if err != nil {
+		return err
+	}
+	switch {
+	case sc.Size != lb.Size:
+		return fmt.Errorf("scramble %s of other board size than %s", sc, lb.Size)
+	case solve.Recording.Start != sc.Board():
+		return fmt.Errorf("recording start is not the scramble %s position", sc)
+	case solve.Tier != lb.Tier:
+		return fmt.Errorf("%s tier solve, expected %s", solve.Tier, lb.Tier)
+	case solve.Metric != lb.Metric:
+		return fmt.Errorf("%s metric solve, expected %s", solve.Metric, lb.Metric)
+	case (lb.Tier == board.Expert) != (sc.Walk == 0):
+		return fmt.Errorf("scramble %s is not of %s tier", sc, lb.Tier)
+	case len(solve.Recording.Moves) == 0 || solve.Hints < 0 || solve.Duration < 0:
+		return fmt.Errorf("%d moves, %d hints, %s duration", len(solve.Recording.Moves), solve.Hints, time.Duration(solve.Duration))
+	}
+
+	b, err := solve.Recording.Board()
+	if err != nil {
+		return fmt.Errorf("replay: %s", err)
+	}
+	if !b.IsSolved() {
+		return fmt.Errorf("replay of %d moves does not solve the scramble %s", len(solve.Recording.Moves), sc)
+	}
+
+	optimal, err := solver.Optimal(ctx, sc.Board(), opts...)
+	if err != nil {
+		return fmt.Errorf("optimal solution: %s", err)
+	}
+	if lo, hi := lb.Tier.Range(); lb.Tier != board.Expert && (optimal < lo || optimal > hi) {
+		return fmt.Errorf("scramble %s optimal length %d is out of %s tier range", sc, optimal, lb.Tier)
+	}
+	solve.Optimal = optimal
+	return nil
+}
+
+// boundDuration limits the duration measured by the client with the recording and the time passed on the server
+// since the game start was registered. The duration of a game with no start registered is unknown.
+func boundDuration(solve *model.Solve, start *model.JSONTimestamp, now time.Time) {
+	if start == nil {
+		solve.Duration = 0
+		return
+	}
+	solve.Duration = min(solve.Duration, model.JSONDuration(solve.Recording.Duration()), model.JSONDuration(now.Sub(time.Time(*start))))
+}
@@ -0,0 +1,70 @@
+// Package plausibility scores how likely a solve is played by a human, solves scoring model.QuarantineScore are quarantined.
+package plausibility
+
+import (
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/solver"
+	"fmt"
+	"time"
+)
+
+// Limits are the bounds of human play, a zero limit disables its check.
+type Limits struct {
+	MinMoveTime      time.Duration // the fastest average time per move, half as fast again is suspicious
+	NearOptimal      int           // the optimal solution length from which an optimal solve is suspicious
+	MaxSolvesPerHour int
+}
+
+// DefaultLimits allow 15 moves a second at most, are suspicious of optimal solves of 40 moves or more
+// and of more than a solve a minute during an hour.
+func DefaultLimits() Limits {
+	return Limits{MinMoveTime: 66 * time.Millisecond, NearOptimal: 40, MaxSolvesPerHour: 60}
+}
+
+// Check scores the verified solve by the moves, the pace and the games of the user finished before.
+func (l Limits) Check(solve model.Solve, games []model.Game, now time.Time) model.Suspicion {
+	var s model.Suspicion
+	add := func(score float64, format string, a ...any) {
+		s.Score += score
+		s.Reasons = append(s.Reasons, fmt.Sprintf(format, a...))
+	}
+
+	moves := len(solve.Recording.Moves)
+	if minimum := minimumMoves(solve); moves < minimum {
+		add(1, "%d moves is less than the minimum of %d", moves, minimum)
+	} else if l.NearOptimal > 0 && solve.Optimal >= l.NearOptimal && moves == solve.Optimal {
+		add(.5, "optimal solve of %d moves", moves)
+	}
+
+	// a line of tiles slides in a single move of the multi-tile metric
+	if count := solve.Metric.Count(solve.Recording.Moves); l.MinMoveTime > 0 && count > 0 {
+		pace := time.Duration(solve.Duration) / time.Duration(count)
+		switch {
+		case pace < l.MinMoveTime:
+			add(1, "%s per move is faster than %s", pace, l.MinMoveTime)
+		case pace < 2*l.MinMoveTime:
+			add(.5, "%s per move is close to %s", pace, l.MinMoveTime)
+		}
+	}
+
+	if l.MaxSolvesPerHour > 0 {
+		solves := 0
+		for _, g := range games {
+			if (g.Status == model.GameSolved || g.Status == model.GameQuarantined) && now.Sub(time.Time(*g.Finish)) < time.Hour {
+				solves++
+			}
+		}
+		if solves >= l.MaxSolvesPerHour {
+			add(.5, "%d solves during the last hour", solves+1)
+		}
+	}
+	return s
+}
+
+// minimumMoves returns the optimal solution length of the solve scramble, or its lower bound when the length is unknown.
+func minimumMoves(solve model.Solve) int {
+	if solve.Optimal > 0 {
+		return solve.Optimal
+	}
+	return solver.LinearConflict{}.Estimate(solve.Recording.Start)
+}
@@ -0,0 +1,57 @@
+package plausibility_test
+
+import (
+	"15-puzzle/internal/board"
+	"15-puzzle/internal/model"
+	"15-puzzle/internal/solver"
+	"15-puzzle/internal/web-service/plausibility"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	sc := board.Scramble{Size: board.Classic, Seed: 1}
+	res, err := solver.Reduce(context.Background(), sc.Board())
+	if err != nil {
+		t.Fatalf("Reduce %s: %s", sc, err)
+	}
+	moves := len(res.Moves)
+	solveIn := func(d time.Duration) model.Solve {
+		return model.Solve{
+			Scramble:  sc.String(),
+			Recording: board.Recording{Start: sc.Board(), Moves: res.Moves},
+			Duration:  model.JSONDuration(d),
+		}
+	}
+	now := time.Now()
+	l := plausibility.DefaultLimits()
+
+	s := l.Check(solveIn(time.Duration(moves)*time.Second), nil, now)
+	assert.False(t, s.Quarantined(), s.Reasons)
+	assert.Empty(t, s.Reasons)
+
+	s = l.Check(solveIn(time.Duration(moves)*l.MinMoveTime*3/2), nil, now)
+	assert.False(t, s.Quarantined(), "pace close to the limit alone should not quarantine")
+	assert.Len(t, s.Reasons, 1)
+
+	s = l.Check(solveIn(time.Duration(moves)*l.MinMoveTime/2), nil, now)
+	assert.True(t, s.Quarantined(), "pace faster than the limit should quarantine")
+
+	short := solveIn(time.Duration(moves) * time.Second)
+	short.Optimal = moves + 1
+	assert.True(t, l.Check(short, nil, now).Quarantined(), "less moves than optimal should quarantine")
+
+	games := make([]model.Game, l.MaxSolvesPerHour)
+	finish := model.JSONTimestamp(now.Add(-time.Minute))
+	for i := range games {
+		games[i] = model.Game{Status: model.GameSolved, Finish: &finish}
+	}
+	s = l.Check(solveIn(time.Duration(moves)*l.MinMoveTime*3/2), games, now)
+	assert.True(t, s.Quarantined(), "close pace and solve rate should quarantine together")
+	assert.Len(t, s.Reasons, 2)
+
+	assert.False(t, plausibility.Limits{}.Check(solveIn(0), games, now).Quarantined(), "zero limits should disable the checks")
+}